@@ -0,0 +1,266 @@
+package main
+
+import (
+	"archive/tar"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/entrypoint"
+)
+
+// coreDumpSignals are the signals whose default action dumps core.
+var coreDumpSignals = []syscall.Signal{
+	syscall.SIGQUIT,
+	syscall.SIGILL,
+	syscall.SIGTRAP,
+	syscall.SIGABRT,
+	syscall.SIGBUS,
+	syscall.SIGFPE,
+	syscall.SIGSEGV,
+	syscall.SIGSYS,
+	syscall.SIGXCPU,
+	syscall.SIGXFSZ,
+}
+
+// corePatternSpecifier matches the %-specifiers of core_pattern, see core(5).
+var corePatternSpecifier = regexp.MustCompile(`%.`)
+
+// collect adds the files matching rules to the bundle under
+// entrypoint.CollectedDir, along with a manifest of what was collected and
+// skipped. Paths under any of exclude are never collected.
+func collect(ctx context.Context, tw *tar.Writer, rules []entrypoint.CollectRule, failed bool, exclude []string) error {
+	var (
+		manifest []entrypoint.CollectedFile
+		seen     = make(map[string]bool)
+	)
+
+	for _, rule := range rules {
+		if rule.OnFailure && !failed {
+			continue
+		}
+
+		matches, err := filepath.Glob(rule.Pattern)
+		if err != nil {
+			clog.WarnContextf(ctx, "skipping invalid collect pattern %q: %v", rule.Pattern, err)
+			continue
+		}
+
+		budget := cmp.Or(rule.MaxBytes, entrypoint.DefaultCollectMaxBytes)
+		for _, match := range matches {
+			err := filepath.WalkDir(match, func(p string, d fs.DirEntry, walkErr error) error {
+				if slices.ContainsFunc(exclude, func(e string) bool { return p == e || strings.HasPrefix(p, e+"/") }) {
+					if d != nil && d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if walkErr != nil {
+					manifest = append(manifest, entrypoint.CollectedFile{Path: p, Skipped: walkErr.Error()})
+					return nil
+				}
+				// Only regular files are collected, symlinks aren't chased
+				if !d.Type().IsRegular() || seen[p] {
+					return nil
+				}
+				seen[p] = true
+
+				fi, err := d.Info()
+				if err != nil {
+					manifest = append(manifest, entrypoint.CollectedFile{Path: p, Skipped: err.Error()})
+					return nil
+				}
+
+				entry := entrypoint.CollectedFile{Path: p, Size: fi.Size()}
+				if fi.Size() > budget {
+					entry.Skipped = fmt.Sprintf("exceeds the remaining %d bytes allowed for %q", budget, rule.Pattern)
+					manifest = append(manifest, entry)
+					return nil
+				}
+
+				if err := addCollectedFile(tw, p, fi); err != nil {
+					return err
+				}
+				budget -= fi.Size()
+				manifest = append(manifest, entry)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(manifest) == 0 {
+		return nil
+	}
+
+	for _, f := range manifest {
+		if f.Skipped != "" {
+			clog.WarnContext(ctx, "skipped collecting file", "path", f.Path, "reason", f.Skipped)
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal collected manifest: %w", err)
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     entrypoint.CollectedManifestPath,
+		Mode:     0o644,
+		Size:     int64(len(data)),
+		ModTime:  time.Now(),
+	}); err != nil {
+		return fmt.Errorf("failed to write tar header for collected manifest: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write collected manifest: %w", err)
+	}
+	return nil
+}
+
+// addCollectedFile copies the file at p into the bundle. Files still being
+// written (e.g. logs) are copied up to the size they had when they were
+// matched, and padded if they shrank, so the bundle is always well formed.
+func addCollectedFile(tw *tar.Writer, p string, fi fs.FileInfo) error {
+	f, err := os.Open(p)
+	if err != nil {
+		// The file was removed or is unreadable, there is nothing to copy
+		return nil
+	}
+	defer f.Close()
+
+	hdr, err := tar.FileInfoHeader(fi, "")
+	if err != nil {
+		return fmt.Errorf("failed to create tar header for %s: %w", p, err)
+	}
+	hdr.Name = path.Join(entrypoint.CollectedDir, filepath.ToSlash(p))
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write tar header for %s: %w", p, err)
+	}
+
+	n, err := io.Copy(tw, io.LimitReader(f, fi.Size()))
+	if err != nil {
+		return fmt.Errorf("failed to copy %s to tar archive: %w", p, err)
+	}
+	if n < fi.Size() {
+		if _, err := tw.Write(make([]byte, fi.Size()-n)); err != nil {
+			return fmt.Errorf("failed to pad %s in tar archive: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// crashSignal reports the core dumping signal that terminated the wrapped
+// process, either directly or as reported by a shell's 128+n exit status.
+func crashSignal(err error) (syscall.Signal, bool) {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 0, false
+	}
+
+	var sig syscall.Signal
+	if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		sig = ws.Signal()
+	} else if code := exitErr.ExitCode(); code > 128 {
+		sig = syscall.Signal(code - 128)
+	}
+
+	return sig, slices.Contains(coreDumpSignals, sig)
+}
+
+// coreDumpRule returns the rule collecting the core dumps written for the
+// kernel's core_pattern, relative patterns are resolved against workDir. Core
+// dumps piped to a handler (e.g. systemd-coredump) can't be collected.
+func coreDumpRule(corePattern string, usesPID bool, workDir string) (entrypoint.CollectRule, bool) {
+	pattern := strings.TrimSpace(corePattern)
+	if pattern == "" || strings.HasPrefix(pattern, "|") {
+		return entrypoint.CollectRule{}, false
+	}
+
+	if usesPID && !strings.Contains(pattern, "%p") {
+		pattern += ".*"
+	}
+
+	pattern = corePatternSpecifier.ReplaceAllStringFunc(pattern, func(s string) string {
+		if s == "%%" {
+			return "%"
+		}
+		return "*"
+	})
+
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(workDir, pattern)
+	}
+
+	return entrypoint.CollectRule{
+		Pattern:   pattern,
+		MaxBytes:  entrypoint.DefaultCoreDumpMaxBytes,
+		OnFailure: true,
+	}, true
+}
+
+// collectCoreDumps adds a rule for the core dumps written when the wrapped
+// process crashed.
+func (o *opts) collectCoreDumps(ctx context.Context, err error) {
+	sig, ok := crashSignal(err)
+	if !ok {
+		return
+	}
+
+	corePattern, rerr := os.ReadFile("/proc/sys/kernel/core_pattern")
+	if rerr != nil {
+		clog.WarnContextf(ctx, "wrapped process crashed with %s, but core_pattern is unreadable: %v", sig, rerr)
+		return
+	}
+	usesPID, _ := os.ReadFile("/proc/sys/kernel/core_uses_pid")
+
+	wd, werr := os.Getwd()
+	if werr != nil {
+		wd = "/"
+	}
+
+	rule, ok := coreDumpRule(string(corePattern), strings.TrimSpace(string(usesPID)) == "1", wd)
+	if !ok {
+		clog.WarnContextf(ctx, "wrapped process crashed with %s, but core dumps are handled by %q and can't be collected", sig, strings.TrimSpace(string(corePattern)))
+		return
+	}
+
+	clog.InfoContextf(ctx, "wrapped process crashed with %s, collecting core dumps matching %s", sig, rule.Pattern)
+	o.CollectRules = append(o.CollectRules, rule)
+}
+
+// raiseCoreLimit raises the soft core size limit to the hard limit, so the
+// wrapped process, which inherits it, dumps core when it crashes.
+func raiseCoreLimit(ctx context.Context) {
+	var lim syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_CORE, &lim); err != nil {
+		clog.WarnContextf(ctx, "failed to read core size limit: %v", err)
+		return
+	}
+	if lim.Cur >= lim.Max {
+		return
+	}
+
+	lim.Cur = lim.Max
+	if err := syscall.Setrlimit(syscall.RLIMIT_CORE, &lim); err != nil {
+		clog.WarnContextf(ctx, "failed to raise core size limit: %v", err)
+	}
+}
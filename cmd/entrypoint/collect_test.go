@@ -0,0 +1,184 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/entrypoint"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCollect(t *testing.T) {
+	dir := t.TempDir()
+	setupTestArtifacts(t, dir, map[string]string{
+		"logs/app.log":       "app",
+		"logs/big.log":       "0123456789",
+		"logs/nested/a.log":  "nested",
+		"data/state.db":      "state",
+		"artifacts/skip.txt": "already bundled",
+		"link":               "__SYMLINK__:logs/app.log",
+	})
+
+	tests := []struct {
+		name   string
+		rules  []entrypoint.CollectRule
+		failed bool
+		want   map[string]string
+		// wantSkipped are the manifest entries that weren't collected
+		wantSkipped []string
+	}{
+		{
+			name: "globs and directories",
+			rules: []entrypoint.CollectRule{
+				{Pattern: filepath.Join(dir, "logs", "*.log"), MaxBytes: 5},
+				{Pattern: filepath.Join(dir, "logs", "nested")},
+			},
+			want: map[string]string{
+				"logs/app.log":      "app",
+				"logs/nested/a.log": "nested",
+			},
+			wantSkipped: []string{"logs/big.log"},
+		},
+		{
+			name: "on failure rules skipped on success",
+			rules: []entrypoint.CollectRule{
+				{Pattern: filepath.Join(dir, "data", "*"), OnFailure: true},
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "on failure rules collected on failure",
+			rules: []entrypoint.CollectRule{
+				{Pattern: filepath.Join(dir, "data", "*"), OnFailure: true},
+			},
+			failed: true,
+			want: map[string]string{
+				"data/state.db": "state",
+			},
+		},
+		{
+			name: "excluded paths and symlinks",
+			rules: []entrypoint.CollectRule{
+				{Pattern: filepath.Join(dir, "*")},
+			},
+			want: map[string]string{
+				"logs/app.log":      "app",
+				"logs/big.log":      "0123456789",
+				"logs/nested/a.log": "nested",
+				"data/state.db":     "state",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			if err := collect(t.Context(), tw, tt.rules, tt.failed, []string{filepath.Join(dir, "artifacts")}); err != nil {
+				t.Fatalf("collect() error = %v", err)
+			}
+			if err := tw.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			got := make(map[string]string)
+			var manifest []entrypoint.CollectedFile
+			tr := tar.NewReader(&buf)
+			for {
+				hdr, err := tr.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, err := io.ReadAll(tr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if hdr.Name == entrypoint.CollectedManifestPath {
+					if err := json.Unmarshal(data, &manifest); err != nil {
+						t.Fatalf("failed to decode manifest: %v", err)
+					}
+					continue
+				}
+				rel, ok := strings.CutPrefix(hdr.Name, entrypoint.CollectedDir+dir+"/")
+				if !ok {
+					t.Fatalf("unexpected entry %q", hdr.Name)
+				}
+				got[rel] = string(data)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("collected mismatch (-want +got):\n%s", diff)
+			}
+
+			var skipped []string
+			for _, f := range manifest {
+				if f.Skipped != "" {
+					skipped = append(skipped, strings.TrimPrefix(f.Path, dir+"/"))
+				}
+			}
+			if diff := cmp.Diff(tt.wantSkipped, skipped); diff != "" {
+				t.Errorf("skipped mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCrashSignal(t *testing.T) {
+	run := func(script string) error {
+		t.Helper()
+		return exec.Command("/bin/sh", "-c", script).Run()
+	}
+
+	if sig, ok := crashSignal(run("kill -SEGV $$")); !ok || sig != syscall.SIGSEGV {
+		t.Errorf("crashSignal(signaled) = %v, %v, want SIGSEGV", sig, ok)
+	}
+	if sig, ok := crashSignal(run("exit 134")); !ok || sig != syscall.SIGABRT {
+		t.Errorf("crashSignal(exit 134) = %v, %v, want SIGABRT", sig, ok)
+	}
+	if _, ok := crashSignal(run("kill -TERM $$")); ok {
+		t.Error("expected SIGTERM not to be treated as a crash")
+	}
+	if _, ok := crashSignal(run("exit 1")); ok {
+		t.Error("expected a plain failure not to be treated as a crash")
+	}
+	if _, ok := crashSignal(nil); ok {
+		t.Error("expected success not to be treated as a crash")
+	}
+}
+
+func TestCoreDumpRule(t *testing.T) {
+	tests := []struct {
+		pattern string
+		usesPID bool
+		want    string
+		wantOK  bool
+	}{
+		{pattern: "core\n", want: "/work/core", wantOK: true},
+		{pattern: "core\n", usesPID: true, want: "/work/core.*", wantOK: true},
+		{pattern: "/var/crash/core.%e.%p.%t\n", usesPID: true, want: "/var/crash/core.*.*.*", wantOK: true},
+		{pattern: "/tmp/100%%-%p", want: "/tmp/100%-*", wantOK: true},
+		{pattern: "|/usr/lib/systemd/systemd-coredump %P %u %g %s %t %c %h\n"},
+		{pattern: ""},
+	}
+
+	for _, tt := range tests {
+		got, ok := coreDumpRule(tt.pattern, tt.usesPID, "/work")
+		if ok != tt.wantOK || got.Pattern != tt.want {
+			t.Errorf("coreDumpRule(%q, %v) = %q, %v, want %q, %v", tt.pattern, tt.usesPID, got.Pattern, ok, tt.want, tt.wantOK)
+		}
+		if ok && (!got.OnFailure || got.MaxBytes != entrypoint.DefaultCoreDumpMaxBytes) {
+			t.Errorf("coreDumpRule(%q) = %+v, want an on failure rule capped at the core dump size", tt.pattern, got)
+		}
+	}
+}
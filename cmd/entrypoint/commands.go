@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/entrypoint"
+)
+
+// commandRunner runs a test's commands in order, recording each as a step
+// through the health socket of the entrypoint wrapping it.
+//
+// It runs as the wrapped process rather than inside the wrapping entrypoint,
+// so the driver's wrapper script has set up the sandbox before the first
+// command starts.
+type commandRunner struct {
+	socketPath  string
+	gracePeriod time.Duration
+	stdout      io.Writer
+	stderr      io.Writer
+}
+
+// run returns the exit code of the first failed command that doesn't
+// continue on error, or 0 when there is none.
+func (r *commandRunner) run(ctx context.Context, commands []entrypoint.Command) int {
+	env := slices.DeleteFunc(os.Environ(), func(kv string) bool {
+		return strings.HasPrefix(kv, entrypoint.CommandsEnvVar+"=")
+	})
+
+	for i, c := range commands {
+		if err := ctx.Err(); err != nil {
+			clog.ErrorContextf(ctx, "not running command %q: %v", c.Name, err)
+			return entrypoint.InternalErrorCode
+		}
+
+		r.send(ctx, entrypoint.Event{Type: entrypoint.EventStepStart, Step: c.Name})
+
+		_, _ = fmt.Fprintf(r.stdout, "==> [%d/%d] %s\n", i+1, len(commands), c.Name)
+		start := time.Now()
+		code, err := r.runCommand(ctx, c, env)
+
+		ev := entrypoint.Event{
+			Type:     entrypoint.EventStepEnd,
+			Step:     c.Name,
+			Status:   entrypoint.StepStatusOK,
+			ExitCode: &code,
+		}
+		if err != nil {
+			ev.Status = entrypoint.StepStatusError
+			ev.Message = err.Error()
+		}
+		r.send(ctx, ev)
+
+		clog.InfoContext(ctx, "command finished", "name", c.Name, "exit_code", code, "duration", time.Since(start))
+
+		if err == nil {
+			continue
+		}
+		if c.ContinueOnError {
+			clog.WarnContextf(ctx, "command %q failed, continuing: %v", c.Name, err)
+			continue
+		}
+
+		if skipped := commands[i+1:]; len(skipped) > 0 {
+			names := make([]string, 0, len(skipped))
+			for _, s := range skipped {
+				names = append(names, s.Name)
+			}
+			clog.WarnContextf(ctx, "command %q failed, skipping: %s", c.Name, strings.Join(names, ", "))
+		}
+		return code
+	}
+
+	return 0
+}
+
+func (r *commandRunner) runCommand(ctx context.Context, c entrypoint.Command, env []string) (int, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.Command("sh", "-c", c.Cmd)
+	cmd.Stdout = r.stdout
+	cmd.Stderr = r.stderr
+	cmd.Env = env
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
+	}
+
+	if err := cmd.Start(); err != nil {
+		return entrypoint.InternalErrorCode, fmt.Errorf("failed to start command: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), fmt.Errorf("exited with code %d", exitErr.ExitCode())
+		}
+		if err != nil {
+			return entrypoint.InternalErrorCode, err
+		}
+		return 0, nil
+
+	case <-ctx.Done():
+		// Signal the whole process group so the shell's children are
+		// terminated too
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGINT)
+		select {
+		case <-done:
+		case <-time.After(r.gracePeriod):
+			clog.InfoContextf(ctx, "command %q did not exit after SIGINT, sending SIGKILL", c.Name)
+			_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+			<-done
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return entrypoint.CommandTimeoutCode, fmt.Errorf("timed out after %s", c.Timeout)
+		}
+		return entrypoint.InternalErrorCode, fmt.Errorf("cancelled: %w", ctx.Err())
+	}
+}
+
+// send records the event, a failure to record doesn't fail the command.
+func (r *commandRunner) send(ctx context.Context, ev entrypoint.Event) {
+	if err := sendEvent(r.socketPath, ev); err != nil {
+		clog.WarnContextf(ctx, "failed to record %s for command %q: %v", ev.Type, ev.Step, err)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/entrypoint"
+)
+
+func TestCommandRunner(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []entrypoint.Command
+		wantCode int
+		// want is the status and exit code of each recorded step
+		want []string
+	}{
+		{
+			name: "all succeed",
+			commands: []entrypoint.Command{
+				{Name: "first", Cmd: "echo first"},
+				{Name: "second", Cmd: "true"},
+			},
+			want: []string{"first ok 0", "second ok 0"},
+		},
+		{
+			name: "failure stops remaining commands",
+			commands: []entrypoint.Command{
+				{Name: "first", Cmd: "exit 3"},
+				{Name: "second", Cmd: "true"},
+			},
+			wantCode: 3,
+			want:     []string{"first error 3"},
+		},
+		{
+			name: "continue on error",
+			commands: []entrypoint.Command{
+				{Name: "first", Cmd: "exit 3", ContinueOnError: true},
+				{Name: "second", Cmd: "true"},
+			},
+			want: []string{"first error 3", "second ok 0"},
+		},
+		{
+			name: "timeout",
+			commands: []entrypoint.Command{
+				{Name: "slow", Cmd: "sleep 10", Timeout: 100 * time.Millisecond},
+				{Name: "never", Cmd: "true"},
+			},
+			wantCode: entrypoint.CommandTimeoutCode,
+			want:     []string{"slow error 124"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := t.Context()
+
+			// unix socket paths are length limited, so avoid the long t.TempDir()
+			sdir, err := os.MkdirTemp("", "commands")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = os.RemoveAll(sdir) })
+			socket := filepath.Join(sdir, "health.sock")
+			artifactsDir := t.TempDir()
+
+			srv := newEventServer(artifactsDir)
+			cleanup, err := newHealthStatus().startSocket(ctx, socket, srv)
+			if err != nil {
+				t.Fatalf("startSocket() error = %v", err)
+			}
+			defer cleanup()
+
+			var out bytes.Buffer
+			r := &commandRunner{
+				socketPath:  socket,
+				gracePeriod: time.Second,
+				stdout:      &out,
+				stderr:      &out,
+			}
+
+			if code := r.run(ctx, tt.commands); code != tt.wantCode {
+				t.Errorf("run() = %d, want %d\noutput:\n%s", code, tt.wantCode, out.String())
+			}
+			srv.close(ctx)
+
+			f, err := os.Open(filepath.Join(artifactsDir, entrypoint.EventsPath))
+			if err != nil {
+				t.Fatalf("failed to open events: %v", err)
+			}
+			defer f.Close()
+
+			var events []entrypoint.Event
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				var ev entrypoint.Event
+				if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+					t.Fatalf("failed to decode event %q: %v", scanner.Text(), err)
+				}
+				events = append(events, ev)
+			}
+
+			steps, _ := entrypoint.Steps(events)
+			var got []string
+			for _, s := range steps {
+				code := -1
+				if s.ExitCode != nil {
+					code = *s.ExitCode
+				}
+				got = append(got, fmt.Sprintf("%s %s %d", s.Name, s.Status, code))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("steps = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunOnFailureTimeout(t *testing.T) {
+	old := onFailureTimeout
+	onFailureTimeout = 500 * time.Millisecond
+	t.Cleanup(func() { onFailureTimeout = old })
+
+	artifactsDir := t.TempDir()
+	o := &opts{
+		ArtifactsDir: artifactsDir,
+		GracePeriod:  100 * time.Millisecond,
+		// The background sleep keeps the hook's output open after the shell
+		// is killed, which used to block until it exited
+		OnFailureCommands: []string{"sleep 30 & wait", "echo after"},
+	}
+
+	start := time.Now()
+	o.runOnFailure(t.Context())
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("runOnFailure() took %s, want the hanging hook to time out", elapsed)
+	}
+
+	data, err := os.ReadFile(filepath.Join(artifactsDir, "logs", "on_failure.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"[exit: timed out after 500ms]", "after"} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("on_failure.log = %q, want it to contain %q", data, want)
+		}
+	}
+}
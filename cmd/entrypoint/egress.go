@@ -0,0 +1,313 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/entrypoint"
+)
+
+// hopHeaders are removed when forwarding, as they only apply to a single
+// connection.
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// newForwardProxy returns a proxyServer in forward proxy mode, which the
+// wrapped process's HTTP(S)_PROXY points at. It records every destination
+// contacted through it, and when an allowlist is set, refuses destinations
+// that aren't on it. A nil allowlist allows every destination.
+func newForwardProxy(allowlist entrypoint.EgressAllowlist) *proxyServer {
+	return &proxyServer{
+		allowlist: allowlist,
+		// Never proxy the proxy's own requests
+		transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           (&net.Dialer{Timeout: 30 * time.Second}).DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 5 * time.Minute,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		records: make(map[string]*entrypoint.EgressRecord),
+	}
+}
+
+// startForward listens on a random loopback port.
+func (p *proxyServer) startForward(ctx context.Context) (func(), error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for egress proxy: %w", err)
+	}
+	p.addr = listener.Addr().String()
+
+	p.server = &http.Server{
+		Handler:           p,
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+
+	go func() {
+		if err := p.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			clog.ErrorContextf(ctx, "egress proxy stopped: %v", err)
+		}
+	}()
+
+	clog.InfoContext(ctx, "started egress proxy", "addr", p.addr, "enforcing", p.allowlist != nil)
+
+	return func() {
+		_ = p.server.Close()
+		p.transport.CloseIdleConnections()
+	}, nil
+}
+
+// env returns the environment pointing the wrapped process at the proxy.
+// Loopback and the Kubernetes api server are excluded so in-cluster clients
+// keep working without being added to the allowlist.
+func (p *proxyServer) env(environ []string) []string {
+	noProxy := []string{"localhost", "127.0.0.1", "::1"}
+	for _, key := range []string{"NO_PROXY", "no_proxy"} {
+		if v := os.Getenv(key); v != "" {
+			noProxy = append(noProxy, strings.Split(v, ",")...)
+		}
+	}
+	for _, key := range []string{"KUBERNETES_SERVICE_HOST", entrypoint.DriverLocalRegistryHostnameEnvVar} {
+		if v := os.Getenv(key); v != "" {
+			noProxy = append(noProxy, v)
+		}
+	}
+	slices.Sort(noProxy)
+	noProxy = slices.Compact(noProxy)
+
+	proxyURL := "http://" + p.addr
+	overrides := map[string]string{
+		"HTTP_PROXY":  proxyURL,
+		"HTTPS_PROXY": proxyURL,
+		"http_proxy":  proxyURL,
+		"https_proxy": proxyURL,
+		"NO_PROXY":    strings.Join(noProxy, ","),
+		"no_proxy":    strings.Join(noProxy, ","),
+	}
+
+	env := slices.DeleteFunc(slices.Clone(environ), func(kv string) bool {
+		k, _, _ := strings.Cut(kv, "=")
+		_, ok := overrides[k]
+		return ok
+	})
+	for k, v := range overrides {
+		env = append(env, k+"="+v)
+	}
+	return env
+}
+
+func (p *proxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+
+	if r.URL.Host == "" {
+		http.Error(w, "the egress proxy only accepts proxy requests", http.StatusBadRequest)
+		return
+	}
+
+	port := r.URL.Port()
+	if port == "" {
+		port = "80"
+		if r.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	if !p.record(r.Context(), r.URL.Hostname(), port) {
+		http.Error(w, fmt.Sprintf("%s is not in the egress allowlist", r.URL.Host), http.StatusForbidden)
+		return
+	}
+
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	for _, h := range hopHeaders {
+		out.Header.Del(h)
+	}
+
+	resp, err := p.transport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, h := range hopHeaders {
+		resp.Header.Del(h)
+	}
+	for k, vs := range resp.Header {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+// tunnel handles CONNECT requests, used for HTTPS and other TLS traffic.
+func (p *proxyServer) tunnel(w http.ResponseWriter, r *http.Request) {
+	host, port, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid CONNECT destination %q", r.Host), http.StatusBadRequest)
+		return
+	}
+
+	if !p.record(r.Context(), host, port) {
+		http.Error(w, fmt.Sprintf("%s is not in the egress allowlist", r.Host), http.StatusForbidden)
+		return
+	}
+
+	upstream, err := net.DialTimeout("tcp", r.Host, 30*time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "connection cannot be hijacked", http.StatusInternalServerError)
+		return
+	}
+
+	client, buf, err := hj.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		upstream.Close()
+		client.Close()
+		return
+	}
+
+	go func() {
+		defer upstream.Close()
+		// Flush anything the client sent before the tunnel was established
+		if buf.Reader.Buffered() > 0 {
+			if _, err := io.CopyN(upstream, buf, int64(buf.Reader.Buffered())); err != nil {
+				return
+			}
+		}
+		_, _ = io.Copy(upstream, client)
+	}()
+
+	go func() {
+		defer client.Close()
+		_, _ = io.Copy(client, upstream)
+	}()
+}
+
+// record notes a request to the destination and reports whether it is
+// allowed.
+func (p *proxyServer) record(ctx context.Context, host, port string) bool {
+	allowed := p.allowlist == nil || p.allowlist.Allows(host, port)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := net.JoinHostPort(host, port)
+	rec, ok := p.records[key]
+	if !ok {
+		rec = &entrypoint.EgressRecord{
+			Host:      host,
+			Port:      port,
+			Allowed:   allowed,
+			FirstSeen: time.Now(),
+		}
+		p.records[key] = rec
+
+		if allowed {
+			clog.InfoContext(ctx, "egress", "destination", key)
+		} else {
+			clog.WarnContext(ctx, "egress denied", "destination", key)
+		}
+	}
+	rec.Requests++
+
+	return allowed
+}
+
+// denied returns the destinations refused by the allowlist.
+func (p *proxyServer) denied() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var denied []string
+	for key, rec := range p.records {
+		if !rec.Allowed {
+			denied = append(denied, key)
+		}
+	}
+	slices.Sort(denied)
+	return denied
+}
+
+// check fails a successful run when any destination was denied, and adds the
+// denied destinations to an existing failure.
+func (p *proxyServer) check(ctx context.Context, code int, err error) (int, error) {
+	denied := p.denied()
+	if len(denied) == 0 {
+		return code, err
+	}
+
+	derr := fmt.Errorf("process contacted destinations not in the egress allowlist: %s", strings.Join(denied, ", "))
+	clog.ErrorContext(ctx, derr.Error())
+
+	if err == nil {
+		return entrypoint.EgressDeniedCode, derr
+	}
+	return code, errors.Join(err, derr)
+}
+
+// write stores the recorded destinations in the artifacts dir.
+func (p *proxyServer) write(artifactsDir string) error {
+	p.mu.Lock()
+	records := make([]entrypoint.EgressRecord, 0, len(p.records))
+	for _, rec := range p.records {
+		records = append(records, *rec)
+	}
+	p.mu.Unlock()
+
+	slices.SortFunc(records, func(a, b entrypoint.EgressRecord) int {
+		return cmp.Or(cmp.Compare(a.Host, b.Host), cmp.Compare(a.Port, b.Port))
+	})
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal egress records: %w", err)
+	}
+
+	if err := os.MkdirAll(artifactsDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create artifacts directory: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(artifactsDir, entrypoint.EgressPath), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write egress records: %w", err)
+	}
+	return nil
+}
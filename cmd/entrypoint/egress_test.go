@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/entrypoint"
+)
+
+func TestEgressProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "hello")
+	}))
+	defer upstream.Close()
+
+	u, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// upstream listens on 127.0.0.1, use "localhost" as the denied name for
+	// the same server
+	p := newForwardProxy(entrypoint.EgressAllowlist{"127.0.0.1"})
+	cleanup, err := p.startForward(t.Context())
+	if err != nil {
+		t.Fatalf("startForward() error = %v", err)
+	}
+	defer cleanup()
+
+	proxyURL, err := url.Parse("http://" + p.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+
+	resp, err := client.Get(upstream.URL)
+	if err != nil {
+		t.Fatalf("allowed request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Errorf("allowed request = %d %q, want 200 \"hello\"", resp.StatusCode, body)
+	}
+
+	resp, err = client.Get("http://localhost:" + port)
+	if err != nil {
+		t.Fatalf("denied request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("denied request = %d, want 403", resp.StatusCode)
+	}
+
+	// CONNECT tunnels are checked the same way
+	conn, err := net.Dial("tcp", p.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, "CONNECT 127.0.0.1:"+port+" HTTP/1.1\r\nHost: 127.0.0.1:"+port+"\r\n\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	status := make([]byte, len("HTTP/1.1 200"))
+	if _, err := io.ReadFull(conn, status); err != nil {
+		t.Fatalf("failed to read CONNECT response: %v", err)
+	}
+	if string(status) != "HTTP/1.1 200" {
+		t.Errorf("CONNECT response = %q, want HTTP/1.1 200", status)
+	}
+
+	if got, want := p.denied(), []string{"localhost:" + port}; !slices.Equal(got, want) {
+		t.Errorf("denied() = %v, want %v", got, want)
+	}
+
+	code, err := p.check(t.Context(), 0, nil)
+	if code != entrypoint.EgressDeniedCode || err == nil || !strings.Contains(err.Error(), "localhost:"+port) {
+		t.Errorf("check() = %d, %v, want %d and the denied destination", code, err, entrypoint.EgressDeniedCode)
+	}
+
+	// an existing failure keeps its exit code
+	perr := errors.New("process failed")
+	code, err = p.check(t.Context(), 1, perr)
+	if code != 1 || !errors.Is(err, perr) {
+		t.Errorf("check() = %d, %v, want 1 wrapping the process error", code, err)
+	}
+
+	dir := t.TempDir()
+	if err := p.write(dir); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, entrypoint.EgressPath))
+	if err != nil {
+		t.Fatalf("failed to read egress records: %v", err)
+	}
+
+	var records []entrypoint.EgressRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatalf("failed to decode egress records: %v", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2: %+v", len(records), records)
+	}
+	if r := records[0]; r.Host != "127.0.0.1" || !r.Allowed || r.Requests != 2 {
+		t.Errorf("records[0] = %+v, want allowed 127.0.0.1 with 2 requests", r)
+	}
+	if r := records[1]; r.Host != "localhost" || r.Allowed || r.Requests != 1 {
+		t.Errorf("records[1] = %+v, want denied localhost with 1 request", r)
+	}
+}
+
+func TestEgressProxyRecordOnly(t *testing.T) {
+	p := newForwardProxy(nil)
+	if !p.record(t.Context(), "example.com", "443") {
+		t.Error("record() = false, want every destination allowed without an allowlist")
+	}
+	if denied := p.denied(); len(denied) != 0 {
+		t.Errorf("denied() = %v, want none", denied)
+	}
+	if code, err := p.check(t.Context(), 0, nil); code != 0 || err != nil {
+		t.Errorf("check() = %d, %v, want 0, nil", code, err)
+	}
+}
+
+func TestEgressProxyEnv(t *testing.T) {
+	t.Setenv("NO_PROXY", "internal.example.com")
+	t.Setenv("no_proxy", "")
+
+	p := &proxyServer{addr: "127.0.0.1:1234"}
+	env := p.env([]string{"PATH=/bin", "HTTP_PROXY=http://old:8080"})
+
+	got := make(map[string]string)
+	for _, kv := range env {
+		k, v, _ := strings.Cut(kv, "=")
+		if _, ok := got[k]; ok {
+			t.Errorf("%s is set more than once", k)
+		}
+		got[k] = v
+	}
+
+	if got["PATH"] != "/bin" {
+		t.Errorf("PATH = %q, want /bin", got["PATH"])
+	}
+	for _, k := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+		if got[k] != "http://127.0.0.1:1234" {
+			t.Errorf("%s = %q, want http://127.0.0.1:1234", k, got[k])
+		}
+	}
+	if noProxy := strings.Split(got["NO_PROXY"], ","); !slices.Contains(noProxy, "internal.example.com") || !slices.Contains(noProxy, "localhost") {
+		t.Errorf("NO_PROXY = %q, want existing entries and loopback", got["NO_PROXY"])
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/entrypoint"
+)
+
+// eventServer records the step and annotation events sent by the wrapped
+// process over the health socket, appending them to the artifacts dir so they
+// are bundled with the rest of the test's artifacts.
+type eventServer struct {
+	artifactsDir string
+
+	mu     sync.Mutex
+	open   []string // open steps, innermost last
+	f      *os.File
+	closed bool
+}
+
+func newEventServer(artifactsDir string) *eventServer {
+	return &eventServer{
+		artifactsDir: artifactsDir,
+	}
+}
+
+// handle records the event sent on a health socket connection, after its
+// health status was written. Health probes close the connection without
+// sending anything, which isn't an error.
+func (e *eventServer) handle(ctx context.Context, conn net.Conn) {
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	var ev entrypoint.Event
+	err := json.NewDecoder(conn).Decode(&ev)
+	if errors.Is(err, io.EOF) {
+		return
+	}
+
+	var resp entrypoint.EventResponse
+	if err != nil {
+		resp.Error = fmt.Sprintf("failed to decode event: %v", err)
+	} else if err := e.record(ev); err != nil {
+		resp.Error = err.Error()
+	}
+
+	if resp.Error != "" {
+		clog.WarnContext(ctx, "rejected event", "type", ev.Type, "step", ev.Step, "error", resp.Error)
+	}
+
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		clog.WarnContextf(ctx, "failed to respond to event: %v", err)
+	}
+}
+
+// record validates the event against the open steps and appends it to the
+// events file.
+func (e *eventServer) record(ev entrypoint.Event) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return errors.New("the process has exited, events are no longer recorded")
+	}
+
+	switch ev.Type {
+	case entrypoint.EventStepStart:
+		if ev.Step == "" {
+			return errors.New("step name is required")
+		}
+		if slices.Contains(e.open, ev.Step) {
+			return fmt.Errorf("step %q is already started", ev.Step)
+		}
+		e.open = append(e.open, ev.Step)
+
+	case entrypoint.EventStepEnd:
+		i := slices.Index(e.open, ev.Step)
+		if i < 0 {
+			return fmt.Errorf("step %q is not started", ev.Step)
+		}
+		switch ev.Status {
+		case "":
+			ev.Status = entrypoint.StepStatusOK
+		case entrypoint.StepStatusOK, entrypoint.StepStatusError:
+		default:
+			return fmt.Errorf("unknown step status %q, must be one of: %s, %s", ev.Status, entrypoint.StepStatusOK, entrypoint.StepStatusError)
+		}
+		e.open = slices.Delete(e.open, i, i+1)
+
+	case entrypoint.EventAnnotate:
+		if len(ev.Annotations) == 0 {
+			return errors.New("at least one annotation is required")
+		}
+		ev.Step = ""
+		if len(e.open) > 0 {
+			ev.Step = e.open[len(e.open)-1]
+		}
+
+	default:
+		return fmt.Errorf("unknown event type %q", ev.Type)
+	}
+
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	return e.write(ev)
+}
+
+func (e *eventServer) write(ev entrypoint.Event) error {
+	if e.f == nil {
+		path := filepath.Join(e.artifactsDir, entrypoint.EventsPath)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("failed to create events directory: %w", err)
+		}
+
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open events file: %w", err)
+		}
+		e.f = f
+	}
+
+	if err := json.NewEncoder(e.f).Encode(ev); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	return nil
+}
+
+// close ends any steps left open by the wrapped process, innermost first, and
+// closes the events file.
+func (e *eventServer) close(ctx context.Context) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i := len(e.open) - 1; i >= 0; i-- {
+		if err := e.write(entrypoint.Event{
+			Type:    entrypoint.EventStepEnd,
+			Time:    time.Now(),
+			Step:    e.open[i],
+			Status:  entrypoint.StepStatusIncomplete,
+			Message: "step was not ended before the process exited",
+		}); err != nil {
+			clog.WarnContextf(ctx, "failed to end open step %q: %v", e.open[i], err)
+		}
+	}
+	e.open = nil
+	e.closed = true
+
+	if e.f != nil {
+		if err := e.f.Close(); err != nil {
+			clog.WarnContextf(ctx, "failed to close events file: %v", err)
+		}
+		e.f = nil
+	}
+}
+
+// sendEvent sends an event to the entrypoint wrapping the caller, through its
+// health socket.
+func sendEvent(socketPath string, ev entrypoint.Event) error {
+	conn, err := net.DialTimeout("unix", socketPath, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to connect to health socket, is this running under the imagetest entrypoint? %w", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	// The health status is always written first, skip past it
+	dec := json.NewDecoder(conn)
+	var status json.RawMessage
+	if err := dec.Decode(&status); err != nil {
+		return fmt.Errorf("failed to read health status: %w", err)
+	}
+
+	if err := json.NewEncoder(conn).Encode(ev); err != nil {
+		return fmt.Errorf("failed to send event: %w", err)
+	}
+
+	var resp entrypoint.EventResponse
+	if err := dec.Decode(&resp); err != nil {
+		return fmt.Errorf("failed to read event response: %w", err)
+	}
+
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	return nil
+}
+
+// parseEventArgs parses the "step" and "annotate" subcommands:
+//
+//	step start <name>
+//	step end <name> [--status=ok|error] [--message=<message>]
+//	annotate <key>=<value>...
+func parseEventArgs(args []string) (entrypoint.Event, error) {
+	var ev entrypoint.Event
+
+	if len(args) == 0 {
+		return ev, errors.New("no event provided")
+	}
+
+	switch args[0] {
+	case "step":
+		if len(args) < 3 {
+			return ev, errors.New("usage: step start|end <name>")
+		}
+		ev.Step = args[2]
+
+		switch args[1] {
+		case "start":
+			ev.Type = entrypoint.EventStepStart
+			if len(args) > 3 {
+				return ev, fmt.Errorf("unexpected arguments: %s", strings.Join(args[3:], " "))
+			}
+
+		case "end":
+			ev.Type = entrypoint.EventStepEnd
+
+			fs := flag.NewFlagSet("step end", flag.ContinueOnError)
+			status := fs.String("status", string(entrypoint.StepStatusOK), "The result of the step, one of: ok, error")
+			fs.StringVar(&ev.Message, "message", "", "A message describing the result of the step")
+			if err := fs.Parse(args[3:]); err != nil {
+				return ev, err
+			}
+			if fs.NArg() > 0 {
+				return ev, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+			}
+			ev.Status = entrypoint.StepStatus(*status)
+
+		default:
+			return ev, fmt.Errorf("unknown step command %q, must be start or end", args[1])
+		}
+
+	case "annotate":
+		if len(args) < 2 {
+			return ev, errors.New("usage: annotate <key>=<value>...")
+		}
+		ev.Type = entrypoint.EventAnnotate
+		ev.Annotations = make(map[string]string, len(args)-1)
+		for _, kv := range args[1:] {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok || k == "" {
+				return ev, fmt.Errorf("invalid annotation %q, expected key=value", kv)
+			}
+			ev.Annotations[k] = v
+		}
+
+	default:
+		return ev, fmt.Errorf("unknown event command %q", args[0])
+	}
+
+	return ev, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/entrypoint"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestParseEventArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    entrypoint.Event
+		wantErr bool
+	}{
+		{
+			name: "step start",
+			args: []string{"step", "start", "build"},
+			want: entrypoint.Event{Type: entrypoint.EventStepStart, Step: "build"},
+		},
+		{
+			name: "step end defaults to ok",
+			args: []string{"step", "end", "build"},
+			want: entrypoint.Event{Type: entrypoint.EventStepEnd, Step: "build", Status: entrypoint.StepStatusOK},
+		},
+		{
+			name: "step end with status and message",
+			args: []string{"step", "end", "build", "--status=error", "--message", "compiler crashed"},
+			want: entrypoint.Event{Type: entrypoint.EventStepEnd, Step: "build", Status: entrypoint.StepStatusError, Message: "compiler crashed"},
+		},
+		{
+			name: "annotate",
+			args: []string{"annotate", "version=1.2.3", "url=http://localhost:8080/?a=b"},
+			want: entrypoint.Event{Type: entrypoint.EventAnnotate, Annotations: map[string]string{
+				"version": "1.2.3",
+				"url":     "http://localhost:8080/?a=b",
+			}},
+		},
+		{name: "step without name", args: []string{"step", "start"}, wantErr: true},
+		{name: "unknown step command", args: []string{"step", "pause", "build"}, wantErr: true},
+		{name: "extra start args", args: []string{"step", "start", "build", "extra"}, wantErr: true},
+		{name: "annotate without value", args: []string{"annotate", "version"}, wantErr: true},
+		{name: "annotate without annotations", args: []string{"annotate"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEventArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEventArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("parseEventArgs() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEventServer(t *testing.T) {
+	ctx := t.Context()
+
+	// unix socket paths are length limited, so avoid the long t.TempDir()
+	sdir, err := os.MkdirTemp("", "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(sdir) })
+	socket := filepath.Join(sdir, "health.sock")
+	artifactsDir := t.TempDir()
+
+	srv := newEventServer(artifactsDir)
+	cleanup, err := newHealthStatus().startSocket(ctx, socket, srv)
+	if err != nil {
+		t.Fatalf("startSocket() error = %v", err)
+	}
+	defer cleanup()
+
+	send := func(args ...string) error {
+		t.Helper()
+		ev, err := parseEventArgs(args)
+		if err != nil {
+			t.Fatalf("parseEventArgs(%v) error = %v", args, err)
+		}
+		return sendEvent(socket, ev)
+	}
+
+	for _, args := range [][]string{
+		{"annotate", "image=foo"},
+		{"step", "start", "setup"},
+		{"step", "end", "setup"},
+		{"step", "start", "verify"},
+		{"step", "start", "http"},
+		{"annotate", "port=8080"},
+		{"step", "end", "http", "--status=error", "--message=connection refused"},
+	} {
+		if err := send(args...); err != nil {
+			t.Fatalf("send(%v) error = %v", args, err)
+		}
+	}
+
+	// invalid transitions are rejected without being recorded
+	if err := send("step", "end", "never-started"); err == nil {
+		t.Error("expected ending an unstarted step to fail")
+	}
+	if err := send("step", "start", "verify"); err == nil {
+		t.Error("expected starting an open step to fail")
+	}
+	if err := send("step", "end", "verify", "--status=bogus"); err == nil {
+		t.Error("expected an unknown status to fail")
+	}
+
+	// "verify" is left open, and is ended as incomplete
+	srv.close(ctx)
+
+	if err := send("annotate", "late=true"); err == nil {
+		t.Error("expected events after close to fail")
+	}
+
+	f, err := os.Open(filepath.Join(artifactsDir, entrypoint.EventsPath))
+	if err != nil {
+		t.Fatalf("failed to open events: %v", err)
+	}
+	defer f.Close()
+
+	var events []entrypoint.Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var ev entrypoint.Event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.Fatalf("failed to decode event %q: %v", scanner.Text(), err)
+		}
+		if ev.Time.IsZero() {
+			t.Errorf("event %+v has no time", ev)
+		}
+		events = append(events, ev)
+	}
+
+	want := []entrypoint.Event{
+		{Type: entrypoint.EventAnnotate, Annotations: map[string]string{"image": "foo"}},
+		{Type: entrypoint.EventStepStart, Step: "setup"},
+		{Type: entrypoint.EventStepEnd, Step: "setup", Status: entrypoint.StepStatusOK},
+		{Type: entrypoint.EventStepStart, Step: "verify"},
+		{Type: entrypoint.EventStepStart, Step: "http"},
+		{Type: entrypoint.EventAnnotate, Step: "http", Annotations: map[string]string{"port": "8080"}},
+		{Type: entrypoint.EventStepEnd, Step: "http", Status: entrypoint.StepStatusError, Message: "connection refused"},
+		{Type: entrypoint.EventStepEnd, Step: "verify", Status: entrypoint.StepStatusIncomplete, Message: "step was not ended before the process exited"},
+	}
+	if diff := cmp.Diff(want, events, cmpopts.IgnoreFields(entrypoint.Event{}, "Time")); diff != "" {
+		t.Errorf("events mismatch (-want +got):\n%s", diff)
+	}
+}
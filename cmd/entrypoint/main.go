@@ -48,8 +48,12 @@ type opts struct {
 	ArtifactsDir      string
 	ArtifactPath      string
 	OnFailureCommands []string
+	CollectRules      []entrypoint.CollectRule
+	ProfileInterval   time.Duration
 
 	healthStatus *healthStatus
+	profiler     *profiler
+	egress       *proxyServer
 	args         []string
 }
 
@@ -64,6 +68,7 @@ func parseFlags() *opts {
 	flag.BoolVar(&opts.WaitForProbe, "wait-for-probe", true, "Wait for the entrypoint to be probed before starting the wrapped process")
 	flag.StringVar(&opts.ArtifactsDir, "artifacts-dir", entrypoint.ArtifactsDir, "Path to the directory where artifacts should be stored")
 	flag.StringVar(&opts.ArtifactPath, "artifact-path", entrypoint.ArtifactsPath, "Path to the packaged artifact tarball")
+	flag.DurationVar(&opts.ProfileInterval, "profile-interval", DefaultProfileInterval, "How often to sample the resource usage of the process, 0 disables profiling")
 
 	flag.Parse()
 
@@ -131,6 +136,37 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Resume a paused entrypoint, used when debugging
+	if len(os.Args) == 2 && os.Args[1] == "resume" {
+		if _, err := os.Stat(entrypoint.PauseFIFOPath); err != nil {
+			clog.ErrorContextf(ctx, "entrypoint is not paused: %v", err)
+			os.Exit(entrypoint.InternalErrorCode)
+		}
+
+		if err := resume(); err != nil {
+			clog.ErrorContextf(ctx, "failed to resume: %v", err)
+			os.Exit(entrypoint.InternalErrorCode)
+		}
+
+		os.Exit(0)
+	}
+
+	// Send a step or annotation event to the entrypoint wrapping the caller
+	if len(os.Args) >= 2 && (os.Args[1] == "step" || os.Args[1] == "annotate") {
+		ev, err := parseEventArgs(os.Args[1:])
+		if err != nil {
+			clog.ErrorContextf(ctx, "invalid event: %v", err)
+			os.Exit(entrypoint.InternalErrorCode)
+		}
+
+		if err := sendEvent(entrypoint.DefaultHealthCheckSocket, ev); err != nil {
+			clog.ErrorContextf(ctx, "failed to send event: %v", err)
+			os.Exit(entrypoint.InternalErrorCode)
+		}
+
+		os.Exit(0)
+	}
+
 	// Bundle the artifact dir and export it to stdout
 	if len(os.Args) == 2 && os.Args[1] == "export" {
 		f, err := os.Open(opts.ArtifactPath)
@@ -154,6 +190,24 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Run the test's commands, this is the wrapped process of an entrypoint
+	// started with the commands in its environment
+	if len(os.Args) == 1 && os.Getenv(entrypoint.CommandsEnvVar) != "" {
+		var commands []entrypoint.Command
+		if err := json.Unmarshal([]byte(os.Getenv(entrypoint.CommandsEnvVar)), &commands); err != nil {
+			clog.ErrorContextf(ctx, "failed to parse %s: %v", entrypoint.CommandsEnvVar, err)
+			os.Exit(entrypoint.InternalErrorCode)
+		}
+
+		r := &commandRunner{
+			socketPath:  entrypoint.DefaultHealthCheckSocket,
+			gracePeriod: opts.GracePeriod,
+			stdout:      os.Stdout,
+			stderr:      os.Stderr,
+		}
+		os.Exit(r.run(ctx, commands))
+	}
+
 	// Maybe start a registry proxy
 	if os.Getenv(entrypoint.DriverLocalRegistryEnvVar) != "" {
 		port, err := strconv.Atoi(os.Getenv(entrypoint.DriverLocalRegistryPortEnvVar))
@@ -189,19 +243,68 @@ func (o *opts) Run(ctx context.Context) int {
 		}
 	}
 
-	healthCleanup, err := o.healthStatus.startSocket()
+	if v := os.Getenv(entrypoint.CollectEnvVar); v != "" {
+		if err := json.Unmarshal([]byte(v), &o.CollectRules); err != nil {
+			clog.WarnContextf(ctx, "failed to parse %s: %v", entrypoint.CollectEnvVar, err)
+		}
+	}
+
+	events := newEventServer(o.ArtifactsDir)
+	healthCleanup, err := o.healthStatus.startSocket(ctx, entrypoint.DefaultHealthCheckSocket, events)
 	if err != nil {
 		clog.ErrorContextf(ctx, "failed to start health socket: %v", err)
 		return entrypoint.InternalErrorCode
 	}
 	defer healthCleanup()
 
+	if os.Getenv(entrypoint.EgressProxyEnvVar) != "" || os.Getenv(entrypoint.EgressAllowlistEnvVar) != "" {
+		var allowlist entrypoint.EgressAllowlist
+		if v := os.Getenv(entrypoint.EgressAllowlistEnvVar); v != "" {
+			if err := json.Unmarshal([]byte(v), &allowlist); err != nil {
+				clog.ErrorContextf(ctx, "failed to parse %s: %v", entrypoint.EgressAllowlistEnvVar, err)
+				return entrypoint.InternalErrorCode
+			}
+			if allowlist == nil {
+				allowlist = entrypoint.EgressAllowlist{}
+			}
+		}
+
+		o.egress = newForwardProxy(allowlist)
+		egressCleanup, err := o.egress.startForward(ctx)
+		if err != nil {
+			clog.ErrorContextf(ctx, "failed to start egress proxy: %v", err)
+			return entrypoint.InternalErrorCode
+		}
+		defer egressCleanup()
+	}
+
+	if o.ProfileInterval > 0 {
+		p, err := newProfiler(cgroupRoot, o.ProfileInterval)
+		if err != nil {
+			clog.WarnContextf(ctx, "resource profiling disabled: %v", err)
+		}
+		o.profiler = p
+	}
+
 	code, err := o.executeProcess(ctx)
+	o.collectCoreDumps(ctx, err)
+	if o.egress != nil {
+		if werr := o.egress.write(o.ArtifactsDir); werr != nil {
+			clog.WarnContextf(ctx, "failed to write egress records: %v", werr)
+		}
+		code, err = o.egress.check(ctx, code, err)
+	}
 	if err != nil {
 		clog.ErrorContextf(ctx, "wrapped process exited with exit code %d", code)
 		o.healthStatus.update(healthFailed, err.Error(), int64(code))
 	}
 
+	events.close(ctx)
+
+	if perr := o.profiler.write(o.ArtifactsDir); perr != nil {
+		clog.WarnContextf(ctx, "failed to write resource profile: %v", perr)
+	}
+
 	return o.finalize(ctx, code, err)
 }
 
@@ -233,10 +336,18 @@ func (o *opts) executeProcess(ctx context.Context) (int, error) {
 	}
 	cmdName, cmdArgs := o.args[0], o.args[1:]
 
+	self, err := os.Executable()
+	if err != nil {
+		self = entrypoint.BinaryPath
+	}
+
 	cmd := exec.Command(cmdName, cmdArgs...)
 	cmd.Stdout = stdoutw
 	cmd.Stderr = stderrw
-	cmd.Env = append(os.Environ(), "IMAGETEST=true")
+	cmd.Env = append(os.Environ(), "IMAGETEST=true", entrypoint.EntrypointEnvVar+"="+self)
+	if o.egress != nil {
+		cmd.Env = o.egress.env(cmd.Env)
+	}
 
 	// Don't wait for backgrounded child processes. This allows test scripts to fork
 	// background tasks with & without blocking test completion. When the main script
@@ -258,10 +369,13 @@ func (o *opts) executeProcess(ctx context.Context) (int, error) {
 		}
 	}
 
+	raiseCoreLimit(ctx)
+
 	clog.InfoContext(ctx, "starting wrapped process", "cmd", cmdName, "args", cmdArgs)
 	if err := cmd.Start(); err != nil {
 		return entrypoint.InternalErrorCode, fmt.Errorf("failed to start the process: %w", err)
 	}
+	o.profiler.start(ctx)
 
 	done := make(chan error, 1)
 	go func() {
@@ -280,6 +394,7 @@ func (o *opts) executeProcess(ctx context.Context) (int, error) {
 
 		<-done
 	}
+	o.profiler.stop(ctx)
 
 	// extract the exit code from the error
 	if waitErr != nil {
@@ -296,6 +411,9 @@ func (o *opts) executeProcess(ctx context.Context) (int, error) {
 	return 0, nil
 }
 
+// onFailureTimeout bounds each on_failure command.
+var onFailureTimeout = 10 * time.Second
+
 func (o *opts) runOnFailure(ctx context.Context) {
 	if len(o.OnFailureCommands) == 0 {
 		return
@@ -320,21 +438,26 @@ func (o *opts) runOnFailure(ctx context.Context) {
 
 	out := io.MultiWriter(os.Stdout, logFile)
 
+	// Run the hooks like test commands, so a hook that hangs, or leaves a
+	// child behind holding its output, is killed along with its process group
+	// once its deadline passes.
+	r := &commandRunner{
+		gracePeriod: o.GracePeriod,
+		stdout:      out,
+		stderr:      out,
+	}
+
 	clog.InfoContext(ctx, "running on_failure commands", "count", len(o.OnFailureCommands))
 	for _, command := range o.OnFailureCommands {
-		cmdCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-
 		_, _ = fmt.Fprintf(out, "$ %s\n", command)
-		cmd := exec.CommandContext(cmdCtx, "sh", "-c", command)
-		cmd.Stdout = out
-		cmd.Stderr = out
-		cmd.Env = os.Environ()
-		if err := cmd.Run(); err != nil {
+		if _, err := r.runCommand(ctx, entrypoint.Command{
+			Name:    command,
+			Cmd:     command,
+			Timeout: onFailureTimeout,
+		}, os.Environ()); err != nil {
 			_, _ = fmt.Fprintf(out, "[exit: %v]\n", err)
 		}
 		_, _ = fmt.Fprintln(out)
-
-		cancel()
 	}
 	clog.InfoContext(ctx, "on_failure commands complete")
 }
@@ -344,7 +467,7 @@ func (o *opts) finalize(ctx context.Context, code int, execErr error) int {
 		o.runOnFailure(ctx)
 	}
 
-	berr := o.bundleArtifacts(ctx)
+	berr := o.bundleArtifacts(ctx, execErr != nil)
 	if berr != nil {
 		clog.ErrorContextf(ctx, "failed to bundle artifacts: %v", berr)
 		// Let this fallthrough so we don't block the pause, but depending on the pause we may surface berr
@@ -382,8 +505,9 @@ func (o *opts) finalize(ctx context.Context, code int, execErr error) int {
 	return entrypoint.ProcessPausedCode
 }
 
-// bundleArtifacts builds the artifacts bundle suitable for exfiltration/upload.
-func (o *opts) bundleArtifacts(ctx context.Context) error {
+// bundleArtifacts builds the artifacts bundle suitable for exfiltration/upload,
+// including the files matching the collect rules.
+func (o *opts) bundleArtifacts(ctx context.Context, failed bool) error {
 	if err := os.MkdirAll(o.ArtifactsDir, 0o755); err != nil {
 		return fmt.Errorf("failed to create directory for artifact file %s: %w", o.ArtifactPath, err)
 	}
@@ -452,6 +576,10 @@ func (o *opts) bundleArtifacts(ctx context.Context) error {
 		return fmt.Errorf("failed walking artifacts directory %s: %w", o.ArtifactsDir, err)
 	}
 
+	if err := collect(ctx, tw, o.CollectRules, failed, []string{o.ArtifactsDir, o.ArtifactPath}); err != nil {
+		return fmt.Errorf("failed collecting files: %w", err)
+	}
+
 	if err := tw.Close(); err != nil {
 		return fmt.Errorf("failed to close tar writer: %w", err)
 	}
@@ -509,14 +637,14 @@ func gracefullyTerminate(ctx context.Context, cmd *exec.Cmd, gracePeriod time.Du
 
 // resume writes some bytes to the named pipe to resume the process.
 func resume() error {
-	if err := os.WriteFile("/tmp/imagetest.unpause", []byte("resume"), 0o644); err != nil {
+	if err := os.WriteFile(entrypoint.PauseFIFOPath, []byte("resume"), 0o644); err != nil {
 		return fmt.Errorf("failed to write to resume file: %w", err)
 	}
 	return nil
 }
 
 func pause(parentCtx context.Context, exitCode int) error {
-	fifoPath := "/tmp/imagetest.unpause"
+	fifoPath := entrypoint.PauseFIFOPath
 	clog.InfoContext(parentCtx, "attempting to pause for debugging", "fifo_path", fifoPath, "exit_code", exitCode)
 
 	// create a new context to avoid exiting early if the parent context is cancelled
@@ -578,7 +706,7 @@ func wait(ctx context.Context) error {
 	clog.InfoContext(ctx, "starting wait...")
 
 	health := newHealthStatus()
-	teardown, err := health.startSocket()
+	teardown, err := health.startSocket(ctx, entrypoint.DefaultHealthCheckSocket, nil)
 	if err != nil {
 		return fmt.Errorf("failed to start health socket: %w", err)
 	}
@@ -636,12 +764,15 @@ func (h *healthStatus) update(state healthState, message string, exitCode int64)
 	h.Time = time.Now()
 }
 
-func (h *healthStatus) startSocket() (func(), error) {
-	if err := os.Remove(entrypoint.DefaultHealthCheckSocket); err != nil && !errors.Is(err, os.ErrNotExist) {
+// startSocket serves the health status to every connection on socketPath.
+// When events is set, the wrapped process can then send an event on the same
+// connection.
+func (h *healthStatus) startSocket(ctx context.Context, socketPath string, events *eventServer) (func(), error) {
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
 		return nil, fmt.Errorf("failed to remove health socket: %w", err)
 	}
 
-	listener, err := net.Listen("unix", entrypoint.DefaultHealthCheckSocket)
+	listener, err := net.Listen("unix", socketPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create health socket: %w", err)
 	}
@@ -652,23 +783,29 @@ func (h *healthStatus) startSocket() (func(), error) {
 			if err != nil {
 				return
 			}
-			defer conn.Close()
 
-			// Take out a lock, since this is on the same goroutine it blocks, but we
-			// only ever expect this to be called by runtimes during health checks
-			h.mu.RLock()
-			if err := json.NewEncoder(conn).Encode(h); err != nil {
-				return
-			}
-			h.mu.RUnlock()
+			go func() {
+				defer conn.Close()
+
+				h.mu.RLock()
+				err := json.NewEncoder(conn).Encode(h)
+				h.mu.RUnlock()
+				if err != nil {
+					return
+				}
 
-			h.markProbed()
+				h.markProbed()
+
+				if events != nil {
+					events.handle(ctx, conn)
+				}
+			}()
 		}
 	}()
 
 	return func() {
 		_ = listener.Close()
-		_ = os.Remove(entrypoint.DefaultHealthCheckSocket)
+		_ = os.Remove(socketPath)
 	}, nil
 }
 
@@ -679,9 +816,23 @@ func (h *healthStatus) markProbed() {
 	})
 }
 
+// proxyServer proxies the wrapped process's traffic. By default it's a reverse
+// proxy to the driver's local registry, see Start. In forward mode, see
+// newForwardProxy, it's the process's egress proxy instead.
 type proxyServer struct {
 	port    int
 	logPath string
+
+	// The remaining fields are only used in forward mode. allowlist is nil
+	// when every destination is allowed.
+	allowlist entrypoint.EgressAllowlist
+
+	transport *http.Transport
+	server    *http.Server
+	addr      string
+
+	mu      sync.Mutex
+	records map[string]*entrypoint.EgressRecord
 }
 
 func (p *proxyServer) Start() error {
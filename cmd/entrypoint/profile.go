@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/entrypoint"
+)
+
+const (
+	// cgroupRoot is where the unified (v2) cgroup hierarchy is mounted.
+	cgroupRoot = "/sys/fs/cgroup"
+
+	DefaultProfileInterval = 1 * time.Second
+)
+
+// cgroupStats is a point in time reading of a cgroup's interface files.
+// Missing files (e.g. a controller that isn't enabled) read as zero, and the
+// peak values are -1 on kernels that don't track them.
+type cgroupStats struct {
+	time          time.Time
+	cpuUsage      time.Duration
+	memoryCurrent int64
+	memoryPeak    int64
+	pids          int64
+	pidsPeak      int64
+	ioRead        int64
+	ioWrite       int64
+}
+
+// profiler samples the resource usage of the cgroup the entrypoint, and
+// therefore the wrapped process tree, runs in. In a container this is the
+// container's own cgroup, so usage from the entrypoint itself is included but
+// is negligible.
+type profiler struct {
+	dir      string
+	interval time.Duration
+
+	mu       sync.Mutex
+	baseline cgroupStats
+	last     cgroupStats
+	samples  []entrypoint.ResourceSample
+	summary  *entrypoint.ResourceSummary
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// newProfiler returns a profiler for the calling process's cgroup, or an error
+// if cgroup v2 is unavailable.
+func newProfiler(root string, interval time.Duration) (*profiler, error) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read process cgroup: %w", err)
+	}
+	defer f.Close()
+
+	dir, err := cgroupDir(root, f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &profiler{dir: dir, interval: interval}, nil
+}
+
+// cgroupDir resolves the cgroup v2 directory from the contents of
+// /proc/<pid>/cgroup.
+func cgroupDir(root string, r io.Reader) (string, error) {
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err != nil {
+		return "", fmt.Errorf("cgroup v2 is not available at %s: %w", root, err)
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		path, ok := strings.CutPrefix(scanner.Text(), "0::")
+		if !ok {
+			continue
+		}
+
+		// With a private cgroup namespace the path is relative to the
+		// namespace root, and may point above it (e.g. /../..) when the
+		// namespace was created before the process moved cgroups. Fall back
+		// to the mount root whenever the path doesn't resolve.
+		dir := filepath.Join(root, filepath.Clean("/"+path))
+		if _, err := os.Stat(filepath.Join(dir, "cpu.stat")); err == nil {
+			return dir, nil
+		}
+		return root, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read process cgroup: %w", err)
+	}
+
+	return "", errors.New("process is not in a cgroup v2 hierarchy")
+}
+
+// start takes the baseline reading and begins sampling at the interval until
+// stop is called.
+func (p *profiler) start(ctx context.Context) {
+	if p == nil {
+		return
+	}
+
+	baseline, err := readCgroupStats(p.dir)
+	if err != nil {
+		clog.WarnContextf(ctx, "failed to read cgroup stats, resource profiling disabled: %v", err)
+		return
+	}
+	p.baseline, p.last = baseline, baseline
+
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := p.sample(); err != nil {
+					clog.WarnContextf(ctx, "failed to sample cgroup stats: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// stop takes a final sample and summarizes the run.
+func (p *profiler) stop(ctx context.Context) {
+	if p == nil || p.cancel == nil {
+		return
+	}
+
+	p.cancel()
+	<-p.done
+
+	if err := p.sample(); err != nil {
+		clog.WarnContextf(ctx, "failed to sample cgroup stats: %v", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.summary = summarize(p.baseline, p.last, p.samples)
+
+	clog.InfoContext(ctx, "finished resource profiling",
+		"duration", p.summary.Duration,
+		"cpu_usage_seconds", p.summary.CPUUsageSeconds,
+		"cpu_peak_cores", p.summary.CPUPeakCores,
+		"memory_peak_bytes", p.summary.MemoryPeakBytes,
+		"pids_peak", p.summary.PIDsPeak,
+	)
+}
+
+func (p *profiler) sample() error {
+	stats, err := readCgroupStats(p.dir)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var cores float64
+	if elapsed := stats.time.Sub(p.last.time); elapsed > 0 {
+		cores = float64(stats.cpuUsage-p.last.cpuUsage) / float64(elapsed)
+	}
+
+	p.samples = append(p.samples, entrypoint.ResourceSample{
+		Time:               stats.time,
+		CPUCores:           cores,
+		CPUUsageSeconds:    (stats.cpuUsage - p.baseline.cpuUsage).Seconds(),
+		MemoryCurrentBytes: stats.memoryCurrent,
+		PIDs:               stats.pids,
+		IOReadBytes:        stats.ioRead - p.baseline.ioRead,
+		IOWriteBytes:       stats.ioWrite - p.baseline.ioWrite,
+	})
+	p.last = stats
+	return nil
+}
+
+func summarize(baseline, last cgroupStats, samples []entrypoint.ResourceSample) *entrypoint.ResourceSummary {
+	s := &entrypoint.ResourceSummary{
+		Duration:        last.time.Sub(baseline.time),
+		Samples:         len(samples),
+		CPUUsageSeconds: (last.cpuUsage - baseline.cpuUsage).Seconds(),
+		MemoryPeakBytes: max(baseline.memoryCurrent, last.memoryPeak),
+		PIDsPeak:        max(baseline.pids, last.pidsPeak),
+		IOReadBytes:     last.ioRead - baseline.ioRead,
+		IOWriteBytes:    last.ioWrite - baseline.ioWrite,
+	}
+
+	if s.Duration > 0 {
+		s.CPUAvgCores = s.CPUUsageSeconds / s.Duration.Seconds()
+	}
+
+	for _, sample := range samples {
+		s.CPUPeakCores = max(s.CPUPeakCores, sample.CPUCores)
+		s.MemoryPeakBytes = max(s.MemoryPeakBytes, sample.MemoryCurrentBytes)
+		s.PIDsPeak = max(s.PIDsPeak, sample.PIDs)
+	}
+
+	return s
+}
+
+// write stores the time series and summary in the artifacts dir.
+func (p *profiler) write(artifactsDir string) error {
+	if p == nil || p.summary == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	samplesPath := filepath.Join(artifactsDir, entrypoint.ProfileSamplesPath)
+	if err := os.MkdirAll(filepath.Dir(samplesPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create profile directory: %w", err)
+	}
+
+	sf, err := os.Create(samplesPath)
+	if err != nil {
+		return fmt.Errorf("failed to create profile samples: %w", err)
+	}
+	defer sf.Close()
+
+	enc := json.NewEncoder(sf)
+	for _, sample := range p.samples {
+		if err := enc.Encode(sample); err != nil {
+			return fmt.Errorf("failed to write profile sample: %w", err)
+		}
+	}
+
+	summary, err := json.MarshalIndent(p.summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal profile summary: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(artifactsDir, entrypoint.ProfileSummaryPath), summary, 0o644); err != nil {
+		return fmt.Errorf("failed to write profile summary: %w", err)
+	}
+
+	return sf.Close()
+}
+
+func readCgroupStats(dir string) (cgroupStats, error) {
+	stats := cgroupStats{
+		time:       time.Now(),
+		memoryPeak: -1,
+		pidsPeak:   -1,
+	}
+
+	// cpu.stat is always present in cgroup v2, so treat it as the signal that
+	// the cgroup exists at all
+	cpu, err := readKeyedFile(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return stats, err
+	}
+	stats.cpuUsage = time.Duration(cpu["usage_usec"]) * time.Microsecond
+
+	for file, dst := range map[string]*int64{
+		"memory.current": &stats.memoryCurrent,
+		"memory.peak":    &stats.memoryPeak,
+		"pids.current":   &stats.pids,
+		"pids.peak":      &stats.pidsPeak,
+	} {
+		v, err := readSingleValue(filepath.Join(dir, file))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return stats, err
+		}
+		*dst = v
+	}
+
+	iostat, err := readIOStat(filepath.Join(dir, "io.stat"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return stats, err
+	}
+	stats.ioRead, stats.ioWrite = iostat["rbytes"], iostat["wbytes"]
+
+	return stats, nil
+}
+
+// readSingleValue reads a file holding a single integer, e.g. memory.current.
+func readSingleValue(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	s := strings.TrimSpace(string(data))
+	if s == "max" {
+		return 0, nil
+	}
+
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return v, nil
+}
+
+// readKeyedFile reads a flat keyed file of "key value" lines, e.g. cpu.stat.
+func readKeyedFile(path string) (map[string]int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]int64)
+	for line := range strings.Lines(string(data)) {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s in %s: %w", key, path, err)
+		}
+		values[key] = v
+	}
+	return values, nil
+}
+
+// readIOStat reads io.stat, summing each "key=value" field across devices.
+func readIOStat(path string) (map[string]int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]int64)
+	for line := range strings.Lines(string(data)) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		// the first field is the device's major:minor
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			v, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s in %s: %w", key, path, err)
+			}
+			values[key] += v
+		}
+	}
+	return values, nil
+}
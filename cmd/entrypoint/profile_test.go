@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/entrypoint"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCgroupDir(t *testing.T) {
+	root := t.TempDir()
+	writeCgroupFiles(t, root, map[string]string{"cgroup.controllers": "cpu memory pids io"})
+	writeCgroupFiles(t, filepath.Join(root, "system.slice", "test.scope"), map[string]string{"cpu.stat": "usage_usec 0"})
+
+	tests := []struct {
+		name    string
+		root    string
+		cgroup  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "namespaced root",
+			root:   root,
+			cgroup: "0::/\n",
+			want:   root,
+		},
+		{
+			name:   "nested cgroup",
+			root:   root,
+			cgroup: "0::/system.slice/test.scope\n",
+			want:   filepath.Join(root, "system.slice", "test.scope"),
+		},
+		{
+			name:   "path outside namespace falls back to root",
+			root:   root,
+			cgroup: "0::/../../elsewhere\n",
+			want:   root,
+		},
+		{
+			name:    "hybrid hierarchy without unified entry",
+			root:    root,
+			cgroup:  "4:memory:/docker/abc\n1:cpu:/docker/abc\n",
+			wantErr: true,
+		},
+		{
+			name:    "cgroup v1",
+			root:    t.TempDir(),
+			cgroup:  "0::/\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cgroupDir(tt.root, strings.NewReader(tt.cgroup))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("cgroupDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("cgroupDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCgroupStats(t *testing.T) {
+	dir := t.TempDir()
+	writeCgroupFiles(t, dir, map[string]string{
+		"cpu.stat":       "usage_usec 2500000\nuser_usec 2000000\nsystem_usec 500000\n",
+		"memory.current": "1048576\n",
+		"memory.peak":    "4194304\n",
+		"pids.current":   "3\n",
+		"io.stat":        "8:0 rbytes=100 wbytes=200 rios=1 wios=2 dbytes=0 dios=0\n8:16 rbytes=10 wbytes=20 rios=1 wios=1 dbytes=0 dios=0\n",
+	})
+
+	stats, err := readCgroupStats(dir)
+	if err != nil {
+		t.Fatalf("readCgroupStats() error = %v", err)
+	}
+
+	if stats.cpuUsage != 2500*time.Millisecond {
+		t.Errorf("cpuUsage = %v, want 2.5s", stats.cpuUsage)
+	}
+	if stats.memoryCurrent != 1048576 || stats.memoryPeak != 4194304 {
+		t.Errorf("memory = %d/%d, want 1048576/4194304", stats.memoryCurrent, stats.memoryPeak)
+	}
+	if stats.pids != 3 || stats.pidsPeak != -1 {
+		t.Errorf("pids = %d/%d, want 3/-1", stats.pids, stats.pidsPeak)
+	}
+	if stats.ioRead != 110 || stats.ioWrite != 220 {
+		t.Errorf("io = %d/%d, want 110/220", stats.ioRead, stats.ioWrite)
+	}
+
+	if _, err := readCgroupStats(t.TempDir()); err == nil {
+		t.Error("expected an error when cpu.stat is missing")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	start := time.Now()
+	baseline := cgroupStats{
+		time:          start,
+		cpuUsage:      1 * time.Second,
+		memoryCurrent: 100,
+		pids:          1,
+		ioRead:        10,
+		ioWrite:       20,
+	}
+	last := cgroupStats{
+		time:          start.Add(4 * time.Second),
+		cpuUsage:      5 * time.Second,
+		memoryCurrent: 150,
+		memoryPeak:    -1,
+		pids:          1,
+		pidsPeak:      -1,
+		ioRead:        110,
+		ioWrite:       220,
+	}
+	samples := []entrypoint.ResourceSample{
+		{CPUCores: 0.5, MemoryCurrentBytes: 300, PIDs: 4},
+		{CPUCores: 2, MemoryCurrentBytes: 200, PIDs: 2},
+	}
+
+	got := summarize(baseline, last, samples)
+	want := &entrypoint.ResourceSummary{
+		Duration:        4 * time.Second,
+		Samples:         2,
+		CPUUsageSeconds: 4,
+		CPUPeakCores:    2,
+		CPUAvgCores:     1,
+		MemoryPeakBytes: 300,
+		PIDsPeak:        4,
+		IOReadBytes:     100,
+		IOWriteBytes:    200,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("summarize() mismatch (-want +got):\n%s", diff)
+	}
+
+	// the kernel's own peak wins when it is higher than anything sampled
+	last.memoryPeak = 1000
+	if got := summarize(baseline, last, samples); got.MemoryPeakBytes != 1000 {
+		t.Errorf("MemoryPeakBytes = %d, want 1000", got.MemoryPeakBytes)
+	}
+}
+
+func TestProfiler(t *testing.T) {
+	dir := t.TempDir()
+	writeCgroupFiles(t, dir, map[string]string{
+		"cpu.stat":       "usage_usec 0\n",
+		"memory.current": "100\n",
+		"pids.current":   "1\n",
+	})
+
+	p := &profiler{dir: dir, interval: 10 * time.Millisecond}
+	p.start(t.Context())
+
+	time.Sleep(50 * time.Millisecond)
+	writeCgroupFiles(t, dir, map[string]string{
+		"cpu.stat":       "usage_usec 1000000\n",
+		"memory.current": "2048\n",
+		"pids.current":   "5\n",
+	})
+	time.Sleep(50 * time.Millisecond)
+
+	p.stop(t.Context())
+
+	artifactsDir := t.TempDir()
+	if err := p.write(artifactsDir); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(artifactsDir, entrypoint.ProfileSummaryPath))
+	if err != nil {
+		t.Fatalf("failed to read summary: %v", err)
+	}
+
+	var summary entrypoint.ResourceSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatalf("failed to decode summary: %v", err)
+	}
+
+	if summary.CPUUsageSeconds != 1 {
+		t.Errorf("CPUUsageSeconds = %v, want 1", summary.CPUUsageSeconds)
+	}
+	if summary.MemoryPeakBytes != 2048 {
+		t.Errorf("MemoryPeakBytes = %d, want 2048", summary.MemoryPeakBytes)
+	}
+	if summary.PIDsPeak != 5 {
+		t.Errorf("PIDsPeak = %d, want 5", summary.PIDsPeak)
+	}
+
+	f, err := os.Open(filepath.Join(artifactsDir, entrypoint.ProfileSamplesPath))
+	if err != nil {
+		t.Fatalf("failed to open samples: %v", err)
+	}
+	defer f.Close()
+
+	var lines int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var sample entrypoint.ResourceSample
+		if err := json.Unmarshal(scanner.Bytes(), &sample); err != nil {
+			t.Fatalf("failed to decode sample %q: %v", scanner.Text(), err)
+		}
+		lines++
+	}
+	if lines != summary.Samples || lines < 2 {
+		t.Errorf("got %d samples, summary reports %d", lines, summary.Samples)
+	}
+}
+
+func TestProfilerDisabled(t *testing.T) {
+	// A nil profiler is a no-op, which is how profiling is disabled
+	var p *profiler
+	p.start(t.Context())
+	p.stop(t.Context())
+	if err := p.write(t.TempDir()); err != nil {
+		t.Errorf("write() error = %v", err)
+	}
+}
+
+func writeCgroupFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("failed to create %s: %v", dir, err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
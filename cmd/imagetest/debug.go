@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/entrypoint"
+	"golang.org/x/term"
+)
+
+const debugUsage = `Usage: imagetest debug <command> [flags] [sandbox]
+
+Paused sandboxes are left behind when tests are run with
+IMAGETEST_SKIP_TEARDOWN or IMAGETEST_SKIP_TEARDOWN_ON_FAILURE set.
+
+Commands:
+  list      List paused sandboxes (the default)
+  shell     Open a shell in a sandbox
+  resume    Resume a sandbox's entrypoint so the provider can tear it down
+
+The sandbox argument is the ID shown by list, and may be omitted when there is
+only one paused sandbox.
+
+Flags:
+`
+
+// Sandbox labels set by every driver.
+const sandboxLabel = "dev.chainguard.imagetest"
+
+type sandboxState string
+
+const (
+	statePaused          sandboxState = "paused"
+	statePausedWithError sandboxState = "paused_with_error"
+	stateRunning         sandboxState = "running"
+	stateUnknown         sandboxState = "unknown"
+)
+
+// stateFromExitCode maps the exit code of the entrypoint's healthcheck to the
+// sandbox's state.
+func stateFromExitCode(code int) sandboxState {
+	switch code {
+	case 0:
+		return stateRunning
+	case entrypoint.ProcessPausedCode:
+		return statePaused
+	case entrypoint.ProcessPausedWithErrorCode:
+		return statePausedWithError
+	default:
+		return stateUnknown
+	}
+}
+
+// healthcheckMessage extracts the message logged by the entrypoint's
+// healthcheck, falling back to the raw output.
+func healthcheckMessage(output string) string {
+	output = strings.TrimSpace(output)
+
+	var line struct {
+		Msg string `json:"msg"`
+	}
+	if err := json.Unmarshal([]byte(output), &line); err == nil && line.Msg != "" {
+		return line.Msg
+	}
+	return output
+}
+
+// sandbox is a test sandbox found by one of the backends.
+type sandbox struct {
+	// ID uniquely identifies the sandbox on the command line.
+	ID string
+	// Where is a human readable description of where the sandbox runs.
+	Where   string
+	State   sandboxState
+	Message string
+
+	backend backend
+}
+
+func (s *sandbox) paused() bool {
+	return s.State == statePaused || s.State == statePausedWithError
+}
+
+// backend finds and interacts with sandboxes on one kind of runtime.
+type backend interface {
+	list(ctx context.Context) ([]*sandbox, error)
+	// shell runs cmd interactively in the sandbox.
+	shell(ctx context.Context, s *sandbox, cmd []string) error
+	// exec runs cmd non-interactively in the sandbox.
+	exec(ctx context.Context, s *sandbox, cmd []string, stdout io.Writer) error
+}
+
+type debugOpts struct {
+	all        bool
+	shell      string
+	dockerHost string
+	sshKey     string
+	kubeconfig string
+	noDocker   bool
+	noKube     bool
+}
+
+func debug(ctx context.Context, args []string) error {
+	cmd := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	var o debugOpts
+	fs := flag.NewFlagSet("debug", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), debugUsage)
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&o.all, "all", false, "Include sandboxes that are still running")
+	fs.StringVar(&o.shell, "shell", "sh", "The shell to run in the sandbox")
+	fs.StringVar(&o.dockerHost, "docker-host", os.Getenv("IMAGETEST_DOCKER_HOST"), "The docker daemon to search, e.g. ssh://ubuntu@1.2.3.4 for the ec2 driver. Defaults to the environment's docker daemon")
+	fs.StringVar(&o.sshKey, "ssh-key", "", "The private key used to connect to an ssh:// docker host")
+	fs.StringVar(&o.kubeconfig, "kubeconfig", "", "An additional kubeconfig to search, e.g. for the eks_with_eksctl and aks drivers. The current context and any k3s_in_docker clusters are always searched")
+	fs.BoolVar(&o.noDocker, "no-docker", false, "Do not search docker for sandboxes")
+	fs.BoolVar(&o.noKube, "no-kube", false, "Do not search Kubernetes clusters for sandboxes")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	sandboxes, err := o.discover(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch cmd {
+	case "list", "ls":
+		return printSandboxes(os.Stdout, sandboxes, o.all)
+
+	case "shell", "resume":
+		s, err := findSandbox(sandboxes, fs.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		if cmd == "shell" {
+			fmt.Fprintf(os.Stderr, "attaching to %s, run 'imagetest debug resume %s' when done to let teardown proceed\n", s.ID, s.ID)
+			return s.backend.shell(ctx, s, []string{o.shell})
+		}
+
+		if !s.paused() {
+			return fmt.Errorf("sandbox %s is not paused", s.ID)
+		}
+		if err := s.backend.exec(ctx, s, entrypoint.ResumeCommand, io.Discard); err != nil {
+			return fmt.Errorf("resuming %s: %w", s.ID, err)
+		}
+		fmt.Fprintf(os.Stdout, "resumed %s\n", s.ID)
+		return nil
+
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown debug command %q", cmd)
+	}
+}
+
+// discover finds sandboxes across every enabled backend. A backend that
+// cannot be reached is reported but does not prevent the others from being
+// searched.
+func (o *debugOpts) discover(ctx context.Context) ([]*sandbox, error) {
+	var (
+		sandboxes   []*sandbox
+		kubeconfigs []string
+	)
+
+	if o.kubeconfig != "" {
+		kubeconfigs = append(kubeconfigs, o.kubeconfig)
+	}
+
+	if !o.noDocker {
+		db, err := newDockerBackend(o.dockerHost, o.sshKey)
+		if err != nil {
+			return nil, err
+		}
+
+		found, err := db.list(ctx)
+		if err != nil {
+			clog.WarnContext(ctx, "failed to search docker for sandboxes", "error", err)
+		}
+		sandboxes = append(sandboxes, found...)
+
+		// k3s_in_docker records where it wrote each cluster's kubeconfig.
+		kubeconfigs = append(kubeconfigs, db.kubeconfigs(ctx)...)
+	}
+
+	if !o.noKube {
+		for _, kb := range newKubeBackends(ctx, kubeconfigs) {
+			found, err := kb.list(ctx)
+			if err != nil {
+				clog.WarnContext(ctx, "failed to search cluster for sandboxes", "cluster", kb.name, "error", err)
+			}
+			sandboxes = append(sandboxes, found...)
+		}
+	}
+
+	return sandboxes, nil
+}
+
+func printSandboxes(w io.Writer, sandboxes []*sandbox, all bool) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tWHERE\tSTATE\tMESSAGE")
+	for _, s := range sandboxes {
+		if !all && !s.paused() {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", s.ID, s.Where, s.State, s.Message)
+	}
+	return tw.Flush()
+}
+
+// findSandbox returns the paused sandbox with the given ID, or the only one
+// when id is empty.
+func findSandbox(sandboxes []*sandbox, id string) (*sandbox, error) {
+	if id == "" {
+		var paused []*sandbox
+		for _, s := range sandboxes {
+			if s.paused() {
+				paused = append(paused, s)
+			}
+		}
+
+		switch len(paused) {
+		case 0:
+			return nil, fmt.Errorf("no paused sandboxes found")
+		case 1:
+			return paused[0], nil
+		default:
+			return nil, fmt.Errorf("found %d paused sandboxes, specify one by ID", len(paused))
+		}
+	}
+
+	var matches []*sandbox
+	for _, s := range sandboxes {
+		if s.ID == id {
+			matches = append(matches, s)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("sandbox %s not found", id)
+	case 1:
+		return matches[0], nil
+	default:
+		where := make([]string, 0, len(matches))
+		for _, s := range matches {
+			where = append(where, s.Where)
+		}
+		return nil, fmt.Errorf("sandbox %s found in multiple places: %s", id, strings.Join(where, ", "))
+	}
+}
+
+// rawTerminal puts stdin into raw mode when it is a terminal, returning a
+// func to restore it.
+func rawTerminal() (func(), error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return func() {}, nil
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, fmt.Errorf("setting terminal to raw mode: %w", err)
+	}
+	return func() { _ = term.Restore(fd, state) }, nil
+}
+
+// terminalSize returns the current size of stdout, if it is a terminal.
+func terminalSize() (width, height uint, ok bool) {
+	w, h, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0, 0, false
+	}
+	return uint(w), uint(h), true
+}
+
+// watchResize calls fn with the terminal's size now and whenever it changes,
+// until ctx is done.
+func watchResize(ctx context.Context, fn func(width, height uint)) {
+	if w, h, ok := terminalSize(); ok {
+		fn(w, h)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGWINCH)
+
+	go func() {
+		defer signal.Stop(sigs)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigs:
+				if w, h, ok := terminalSize(); ok {
+					fn(w, h)
+				}
+			}
+		}
+	}()
+}
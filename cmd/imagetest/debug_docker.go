@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/entrypoint"
+	"github.com/docker/cli/cli/connhelper"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// kubeconfigPathLabel is set by the k3s_in_docker driver on its cluster
+// containers.
+const kubeconfigPathLabel = "dev.chainguard.imagetest/kubeconfig-path"
+
+// dockerBackend finds sandboxes run as containers by the docker_in_docker,
+// k3s_in_docker and ec2 drivers.
+type dockerBackend struct {
+	cli  *client.Client
+	host string
+}
+
+var _ backend = (*dockerBackend)(nil)
+
+func newDockerBackend(host, sshKey string) (*dockerBackend, error) {
+	copts := []client.Opt{
+		client.WithAPIVersionNegotiation(),
+		client.WithTLSClientConfigFromEnv(),
+	}
+
+	switch {
+	case strings.HasPrefix(host, "ssh://"):
+		sshArgs := []string{"-o", "StrictHostKeyChecking=no"}
+		if sshKey != "" {
+			sshArgs = append(sshArgs, "-i", sshKey)
+		}
+
+		helper, err := connhelper.GetConnectionHelperWithSSHOpts(host, sshArgs)
+		if err != nil {
+			return nil, fmt.Errorf("creating docker SSH connection helper: %w", err)
+		}
+		copts = append(copts,
+			client.WithHost(helper.Host),
+			client.WithDialContext(helper.Dialer),
+		)
+	case host != "":
+		copts = append(copts, client.WithHost(host))
+	default:
+		copts = append(copts, client.WithHostFromEnv())
+	}
+
+	cli, err := client.NewClientWithOpts(copts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating docker client: %w", err)
+	}
+
+	if host == "" {
+		host = cli.DaemonHost()
+	}
+
+	return &dockerBackend{cli: cli, host: host}, nil
+}
+
+// list implements backend.
+func (d *dockerBackend) list(ctx context.Context) ([]*sandbox, error) {
+	containers, err := d.cli.ContainerList(ctx, container.ListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("label", sandboxLabel+"=true"),
+			filters.Arg("status", "running"),
+		),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+
+	var sandboxes []*sandbox
+	for _, c := range containers {
+		info, err := d.cli.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			clog.WarnContext(ctx, "failed to inspect container", "id", c.ID, "error", err)
+			continue
+		}
+
+		if info.Config == nil || !isEntrypointHealthcheck(info.Config.Healthcheck) {
+			// Not a sandbox, e.g. a k3s cluster or a service container
+			continue
+		}
+
+		s := &sandbox{
+			ID:      strings.TrimPrefix(info.Name, "/"),
+			Where:   "docker " + d.host,
+			State:   stateUnknown,
+			backend: d,
+		}
+
+		if info.State != nil && info.State.Health != nil && len(info.State.Health.Log) > 0 {
+			last := info.State.Health.Log[len(info.State.Health.Log)-1]
+			s.State = stateFromExitCode(last.ExitCode)
+			s.Message = healthcheckMessage(last.Output)
+		}
+
+		sandboxes = append(sandboxes, s)
+	}
+
+	return sandboxes, nil
+}
+
+// isEntrypointHealthcheck reports whether the container is wrapped by the
+// imagetest entrypoint, which is the only kind of container that can pause.
+func isEntrypointHealthcheck(hc *container.HealthConfig) bool {
+	return hc != nil && slices.Contains(hc.Test, entrypoint.BinaryPath)
+}
+
+// kubeconfigs returns the kubeconfig paths of running k3s_in_docker clusters.
+func (d *dockerBackend) kubeconfigs(ctx context.Context) []string {
+	containers, err := d.cli.ContainerList(ctx, container.ListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("label", kubeconfigPathLabel),
+			filters.Arg("status", "running"),
+		),
+	})
+	if err != nil {
+		clog.WarnContext(ctx, "failed to list k3s clusters", "error", err)
+		return nil
+	}
+
+	var paths []string
+	for _, c := range containers {
+		path := c.Labels[kubeconfigPathLabel]
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			clog.WarnContext(ctx, "skipping k3s cluster with missing kubeconfig", "container", c.ID, "path", path)
+			continue
+		}
+		paths = append(paths, path)
+	}
+
+	return paths
+}
+
+// shell implements backend.
+func (d *dockerBackend) shell(ctx context.Context, s *sandbox, cmd []string) error {
+	exec, err := d.cli.ContainerExecCreate(ctx, s.ID, container.ExecOptions{
+		Cmd:          cmd,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		Tty:          true,
+	})
+	if err != nil {
+		return fmt.Errorf("creating exec: %w", err)
+	}
+
+	resp, err := d.cli.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{Tty: true})
+	if err != nil {
+		return fmt.Errorf("attaching to exec: %w", err)
+	}
+	defer resp.Close()
+
+	restore, err := rawTerminal()
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	watchResize(ctx, func(width, height uint) {
+		_ = d.cli.ContainerExecResize(ctx, exec.ID, container.ResizeOptions{Width: width, Height: height})
+	})
+
+	go func() {
+		_, _ = io.Copy(resp.Conn, os.Stdin)
+		_ = resp.CloseWrite()
+	}()
+
+	if _, err := io.Copy(os.Stdout, resp.Reader); err != nil {
+		return fmt.Errorf("streaming exec output: %w", err)
+	}
+
+	return d.exitCode(ctx, exec.ID)
+}
+
+// exec implements backend.
+func (d *dockerBackend) exec(ctx context.Context, s *sandbox, cmd []string, stdout io.Writer) error {
+	exec, err := d.cli.ContainerExecCreate(ctx, s.ID, container.ExecOptions{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return fmt.Errorf("creating exec: %w", err)
+	}
+
+	resp, err := d.cli.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return fmt.Errorf("attaching to exec: %w", err)
+	}
+	defer resp.Close()
+
+	var stderr strings.Builder
+	if _, err := stdcopy.StdCopy(stdout, &stderr, resp.Reader); err != nil {
+		return fmt.Errorf("streaming exec output: %w", err)
+	}
+
+	if err := d.exitCode(ctx, exec.ID); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func (d *dockerBackend) exitCode(ctx context.Context, id string) error {
+	info, err := d.cli.ContainerExecInspect(ctx, id)
+	if err != nil {
+		return fmt.Errorf("inspecting exec: %w", err)
+	}
+	if info.ExitCode != 0 {
+		return fmt.Errorf("command exited with code %d", info.ExitCode)
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/drivers/pod"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/entrypoint"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/exec"
+)
+
+// kubeTimeout bounds how long a single cluster may take to answer, so an
+// unreachable cluster doesn't block the others.
+const kubeTimeout = 15 * time.Second
+
+// kubeBackend finds sandboxes run as pods by the k3s_in_docker,
+// eks_with_eksctl and aks drivers.
+type kubeBackend struct {
+	name string
+	cfg  *rest.Config
+	cli  kubernetes.Interface
+}
+
+var _ backend = (*kubeBackend)(nil)
+
+// newKubeBackends returns a backend for the current kubeconfig context and
+// each of the given kubeconfig files, skipping any that cannot be loaded.
+func newKubeBackends(ctx context.Context, kubeconfigs []string) []*kubeBackend {
+	type source struct {
+		name  string
+		rules *clientcmd.ClientConfigLoadingRules
+	}
+
+	sources := []source{{name: "current-context", rules: clientcmd.NewDefaultClientConfigLoadingRules()}}
+	for _, path := range kubeconfigs {
+		sources = append(sources, source{name: path, rules: &clientcmd.ClientConfigLoadingRules{ExplicitPath: path}})
+	}
+
+	var backends []*kubeBackend
+	for _, src := range sources {
+		cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(src.rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+		if err != nil {
+			if !clientcmd.IsEmptyConfig(err) {
+				clog.WarnContext(ctx, "failed to load kubeconfig", "kubeconfig", src.name, "error", err)
+			}
+			continue
+		}
+		cfg.Timeout = kubeTimeout
+
+		cli, err := kubernetes.NewForConfig(cfg)
+		if err != nil {
+			clog.WarnContext(ctx, "failed to create kubernetes client", "kubeconfig", src.name, "error", err)
+			continue
+		}
+
+		backends = append(backends, &kubeBackend{name: src.name, cfg: cfg, cli: cli})
+	}
+
+	return backends
+}
+
+// list implements backend.
+func (k *kubeBackend) list(ctx context.Context) ([]*sandbox, error) {
+	pods, err := k.cli.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		LabelSelector: sandboxLabel + "=true",
+		FieldSelector: "status.phase=" + string(corev1.PodRunning),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing pods: %w", err)
+	}
+
+	var sandboxes []*sandbox
+	for _, p := range pods.Items {
+		if !hasContainer(&p, pod.SandboxContainerName) {
+			continue
+		}
+
+		s := &sandbox{
+			ID:      p.Namespace + "/" + p.Name,
+			Where:   "kubernetes " + k.cfg.Host,
+			State:   stateUnknown,
+			backend: k,
+		}
+
+		// Pods have no record of their last probe result, so run the
+		// healthcheck ourselves.
+		var out bytes.Buffer
+		err := k.exec(ctx, s, entrypoint.DefaultHealthCheckCommand, &out)
+		var exitErr exec.ExitError
+		switch {
+		case err == nil:
+			s.State = stateRunning
+		case errors.As(err, &exitErr):
+			s.State = stateFromExitCode(exitErr.ExitStatus())
+		default:
+			clog.WarnContext(ctx, "failed to check sandbox state", "sandbox", s.ID, "error", err)
+		}
+		s.Message = healthcheckMessage(out.String())
+
+		sandboxes = append(sandboxes, s)
+	}
+
+	return sandboxes, nil
+}
+
+func hasContainer(p *corev1.Pod, name string) bool {
+	for _, c := range p.Spec.Containers {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// shell implements backend.
+func (k *kubeBackend) shell(ctx context.Context, s *sandbox, cmd []string) error {
+	restore, err := rawTerminal()
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sizes := make(chan remotecommand.TerminalSize, 1)
+	watchResize(ctx, func(width, height uint) {
+		select {
+		case sizes <- remotecommand.TerminalSize{Width: uint16(width), Height: uint16(height)}:
+		default:
+		}
+	})
+
+	return k.stream(ctx, s, cmd, true, remotecommand.StreamOptions{
+		Stdin:             os.Stdin,
+		Stdout:            os.Stdout,
+		Tty:               true,
+		TerminalSizeQueue: sizeQueue{ctx: ctx, sizes: sizes},
+	})
+}
+
+// exec implements backend.
+func (k *kubeBackend) exec(ctx context.Context, s *sandbox, cmd []string, stdout io.Writer) error {
+	ctx, cancel := context.WithTimeout(ctx, kubeTimeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	if err := k.stream(ctx, s, cmd, false, remotecommand.StreamOptions{
+		Stdout: stdout,
+		Stderr: &stderr,
+	}); err != nil {
+		if stderr.Len() > 0 {
+			return fmt.Errorf("%w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+		}
+		return err
+	}
+	return nil
+}
+
+func (k *kubeBackend) stream(ctx context.Context, s *sandbox, cmd []string, tty bool, opts remotecommand.StreamOptions) error {
+	namespace, name, err := splitPodID(s.ID)
+	if err != nil {
+		return err
+	}
+
+	req := k.cli.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(name).
+		Namespace(namespace).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: pod.SandboxContainerName,
+			Command:   cmd,
+			Stdin:     opts.Stdin != nil,
+			Stdout:    opts.Stdout != nil,
+			Stderr:    opts.Stderr != nil,
+			TTY:       tty,
+		}, scheme.ParameterCodec)
+
+	spdyexec, err := remotecommand.NewSPDYExecutor(k.cfg, "POST", req.URL())
+	if err != nil {
+		return fmt.Errorf("creating exec request: %w", err)
+	}
+	wsexec, err := remotecommand.NewWebSocketExecutor(k.cfg, "GET", req.URL().String())
+	if err != nil {
+		return fmt.Errorf("creating exec request: %w", err)
+	}
+
+	executor, err := remotecommand.NewFallbackExecutor(wsexec, spdyexec, httpstream.IsUpgradeFailure)
+	if err != nil {
+		return fmt.Errorf("creating exec request: %w", err)
+	}
+
+	return executor.StreamWithContext(ctx, opts)
+}
+
+// splitPodID splits a sandbox ID of the form namespace/name.
+func splitPodID(id string) (string, string, error) {
+	namespace, name, ok := strings.Cut(id, "/")
+	if !ok || namespace == "" || name == "" {
+		return "", "", fmt.Errorf("invalid pod sandbox id %q, expected namespace/name", id)
+	}
+	return namespace, name, nil
+}
+
+// sizeQueue adapts terminal resize notifications to
+// remotecommand.TerminalSizeQueue.
+type sizeQueue struct {
+	ctx   context.Context
+	sizes chan remotecommand.TerminalSize
+}
+
+func (q sizeQueue) Next() *remotecommand.TerminalSize {
+	select {
+	case <-q.ctx.Done():
+		return nil
+	case size := <-q.sizes:
+		return &size
+	}
+}
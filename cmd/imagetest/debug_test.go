@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/entrypoint"
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestStateFromExitCode(t *testing.T) {
+	tests := []struct {
+		code int
+		want sandboxState
+	}{
+		{code: 0, want: stateRunning},
+		{code: entrypoint.ProcessPausedCode, want: statePaused},
+		{code: entrypoint.ProcessPausedWithErrorCode, want: statePausedWithError},
+		{code: 1, want: stateUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := stateFromExitCode(tt.code); got != tt.want {
+			t.Errorf("stateFromExitCode(%d) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestHealthcheckMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{
+			name:   "json",
+			output: `{"time":"2024-01-01T00:00:00Z","level":"INFO","msg":"paused with error, exit code 1"}` + "\n",
+			want:   "paused with error, exit code 1",
+		},
+		{
+			name:   "plain",
+			output: "  something went wrong\n",
+			want:   "something went wrong",
+		},
+		{
+			name:   "empty",
+			output: "",
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := healthcheckMessage(tt.output); got != tt.want {
+				t.Errorf("healthcheckMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsEntrypointHealthcheck(t *testing.T) {
+	if isEntrypointHealthcheck(nil) {
+		t.Error("expected nil healthcheck to not match")
+	}
+	if isEntrypointHealthcheck(&container.HealthConfig{Test: []string{"CMD", "true"}}) {
+		t.Error("expected unrelated healthcheck to not match")
+	}
+	if !isEntrypointHealthcheck(&container.HealthConfig{Test: append([]string{"CMD"}, entrypoint.DefaultHealthCheckCommand...)}) {
+		t.Error("expected entrypoint healthcheck to match")
+	}
+}
+
+func TestFindSandbox(t *testing.T) {
+	a := &sandbox{ID: "a", Where: "docker", State: statePaused}
+	b := &sandbox{ID: "b", Where: "docker", State: stateRunning}
+	c := &sandbox{ID: "c", Where: "docker", State: statePausedWithError}
+	c2 := &sandbox{ID: "c", Where: "kubernetes", State: statePaused}
+
+	tests := []struct {
+		name      string
+		sandboxes []*sandbox
+		id        string
+		want      *sandbox
+		wantErr   bool
+	}{
+		{name: "by id", sandboxes: []*sandbox{a, b}, id: "b", want: b},
+		{name: "only paused", sandboxes: []*sandbox{a, b}, want: a},
+		{name: "none paused", sandboxes: []*sandbox{b}, wantErr: true},
+		{name: "ambiguous paused", sandboxes: []*sandbox{a, c}, wantErr: true},
+		{name: "not found", sandboxes: []*sandbox{a}, id: "z", wantErr: true},
+		{name: "duplicate id", sandboxes: []*sandbox{c, c2}, id: "c", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findSandbox(tt.sandboxes, tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findSandbox() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("findSandbox() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintSandboxes(t *testing.T) {
+	sandboxes := []*sandbox{
+		{ID: "paused", Where: "docker", State: statePaused, Message: "waiting"},
+		{ID: "running", Where: "docker", State: stateRunning},
+	}
+
+	var buf bytes.Buffer
+	if err := printSandboxes(&buf, sandboxes, false); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("paused")) || bytes.Contains(buf.Bytes(), []byte("running")) {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := printSandboxes(&buf, sandboxes, true); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("running")) {
+		t.Errorf("expected running sandbox with all:\n%s", buf.String())
+	}
+}
+
+func TestSplitPodID(t *testing.T) {
+	ns, name, err := splitPodID("imagetest-abc/sandbox-xyz")
+	if err != nil || ns != "imagetest-abc" || name != "sandbox-xyz" {
+		t.Errorf("splitPodID() = %q, %q, %v", ns, name, err)
+	}
+
+	for _, id := range []string{"nonamespace", "/name", "ns/"} {
+		if _, _, err := splitPodID(id); err == nil {
+			t.Errorf("splitPodID(%q) expected error", id)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+# Lists the harnesses of an inventory along with their features.
+data "imagetest_inventory_status" "this" {
+  inventory = data.imagetest_inventory.this
+}
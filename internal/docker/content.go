@@ -17,6 +17,7 @@ type Content struct {
 
 	reader io.Reader
 	size   int64
+	mode   int64
 
 	pw *io.PipeWriter
 	pr *io.PipeReader
@@ -42,6 +43,12 @@ func NewContentFromFile(f *os.File, target string) (*Content, error) {
 }
 
 func NewContent(r io.Reader, target string, size int64) *Content {
+	return NewContentWithMode(r, target, size, 0o644)
+}
+
+// NewContentWithMode is like NewContent, but writes the file with the given
+// permissions, e.g. 0o755 for binaries.
+func NewContentWithMode(r io.Reader, target string, size int64, mode int64) *Content {
 	pr, pw := io.Pipe()
 
 	// Normalize the target path
@@ -52,6 +59,7 @@ func NewContent(r io.Reader, target string, size int64) *Content {
 		Dir:    path.Dir(cleanTarget),
 		reader: r,
 		size:   size,
+		mode:   mode,
 		pw:     pw,
 		pr:     pr,
 	}
@@ -89,7 +97,7 @@ func (c *Content) stream() {
 
 	hdr := &tar.Header{
 		Name:     c.Target,
-		Mode:     0o644,
+		Mode:     c.mode,
 		Size:     c.size,
 		Typeflag: tar.TypeReg,
 	}
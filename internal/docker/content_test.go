@@ -37,6 +37,25 @@ func TestContentFromFile(t *testing.T) {
 	checkContent(t, c, "/test.txt", "Hello World")
 }
 
+func TestContentWithMode(t *testing.T) {
+	c := NewContentWithMode(strings.NewReader("#!/bin/sh"), "/bin/run", 9, 0o755)
+
+	var buf bytes.Buffer
+	_, err := io.Copy(&buf, c)
+	require.NoError(t, err)
+
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		require.NoError(t, err)
+		if hdr.Typeflag == tar.TypeReg {
+			require.Equal(t, "/bin/run", hdr.Name)
+			require.Equal(t, int64(0o755), hdr.Mode)
+			break
+		}
+	}
+}
+
 func checkContent(t *testing.T, c *Content, wantTarget string, wantContent string) {
 	var buf bytes.Buffer
 	_, err := io.Copy(&buf, c)
@@ -9,10 +9,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -35,6 +37,7 @@ import (
 	"github.com/docker/go-connections/nat"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
@@ -73,6 +76,13 @@ type Request struct {
 	AutoRemove   bool
 	Logger       io.Writer
 	Init         bool
+	// Platform selects the image for a platform other than the daemon's own,
+	// which is then run under emulation.
+	Platform *ocispec.Platform
+	// WaitHealthy makes Start wait for the container to be healthy whenever it
+	// has a healthcheck, including one defined by its image. Start always
+	// waits when HealthCheck is set.
+	WaitHealthy bool
 }
 
 type ResourcesRequest struct {
@@ -298,7 +308,7 @@ func (d *Client) Start(ctx context.Context, req *Request) (*Response, error) {
 		}
 
 		// If there is a health check, block until it is healthy
-		if req.HealthCheck != nil {
+		if req.HealthCheck != nil || (req.WaitHealthy && inspect.State.Health != nil) {
 			if inspect.State.Health == nil {
 				return false, nil
 			}
@@ -338,6 +348,7 @@ func (d *Client) start(ctx context.Context, req *Request) (string, error) {
 	for _, nw := range req.Networks {
 		endpointSettings[nw.Name] = &network.EndpointSettings{
 			NetworkID: nw.ID,
+			Aliases:   nw.Aliases,
 		}
 	}
 
@@ -363,7 +374,7 @@ func (d *Client) start(ctx context.Context, req *Request) (string, error) {
 	}
 
 	// Pull the image if it doesn't already exist
-	if err := d.pull(ctx, req.Ref); err != nil {
+	if err := d.pull(ctx, req.Ref, req.Platform); err != nil {
 		return "", fmt.Errorf("pulling image: %w", err)
 	}
 
@@ -376,7 +387,7 @@ func (d *Client) start(ctx context.Context, req *Request) (string, error) {
 			Cmd:          req.Cmd,
 			AttachStdout: true,
 			AttachStderr: true,
-			Labels:       d.withDefaultLabels(req.Labels),
+			Labels:       d.withDefaultLabels(ctx, req.Labels),
 			Healthcheck:  req.HealthCheck,
 			ExposedPorts: exposedPorts,
 		},
@@ -400,7 +411,7 @@ func (d *Client) start(ctx context.Context, req *Request) (string, error) {
 		&network.NetworkingConfig{
 			EndpointsConfig: endpointSettings,
 		},
-		nil, req.Name)
+		req.Platform, req.Name)
 	if err != nil {
 		return "", fmt.Errorf("creating container: %w", err)
 	}
@@ -441,7 +452,7 @@ func (d *Client) Connect(ctx context.Context, cid string) (*Response, error) {
 }
 
 // pull the image if it doesn't exist in the daemon.
-func (d *Client) pull(ctx context.Context, ref name.Reference) error {
+func (d *Client) pull(ctx context.Context, ref name.Reference, platform *ocispec.Platform) error {
 	var buf bytes.Buffer
 	if _, err := d.inner.ImageInspect(ctx, ref.Name(), client.ImageInspectWithRawResponse(&buf)); err != nil {
 		if !cerrdefs.IsNotFound(err) {
@@ -480,9 +491,14 @@ func (d *Client) pull(ctx context.Context, ref name.Reference) error {
 		Steps:    5,
 		Cap:      1 * time.Minute,
 	}, func(ctx context.Context) (bool, error) {
-		pull, err := d.inner.ImagePull(ctx, ref.Name(), image.PullOptions{
+		opts := image.PullOptions{
 			RegistryAuth: base64.URLEncoding.EncodeToString(authdata),
-		})
+		}
+		if platform != nil {
+			opts.Platform = path.Join(platform.OS, platform.Architecture, platform.Variant)
+		}
+
+		pull, err := d.inner.ImagePull(ctx, ref.Name(), opts)
 		if err != nil {
 			clog.WarnContext(ctx, "failed to pull image, retrying", "ref", ref.Name(), "error", err)
 			lastErr = err
@@ -694,9 +710,12 @@ func (r *Response) ReadFile(ctx context.Context, path string) ([]byte, error) {
 	return data, nil
 }
 
-func (d *Client) withDefaultLabels(labels map[string]string) map[string]string {
+func (d *Client) withDefaultLabels(ctx context.Context, labels map[string]string) map[string]string {
 	l := map[string]string{
-		"dev.chainguard.imagetest": "true",
+		DefaultLabel: "true",
+	}
+	if cl, ok := ctx.Value(labelsKey{}).(map[string]string); ok {
+		maps.Copy(l, cl)
 	}
 
 	for k, v := range l {
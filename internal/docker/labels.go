@@ -0,0 +1,96 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+)
+
+const (
+	// DefaultLabel is set on every container, network and volume imagetest
+	// creates.
+	DefaultLabel = "dev.chainguard.imagetest"
+	// HarnessLabel identifies the harness a container, network or volume was
+	// created for.
+	HarnessLabel = "dev.chainguard.imagetest.harness"
+)
+
+type labelsKey struct{}
+
+// WithLabels returns a copy of ctx carrying labels, which are set on the
+// containers, networks and volumes created with the context.
+func WithLabels(ctx context.Context, labels map[string]string) context.Context {
+	existing, _ := ctx.Value(labelsKey{}).(map[string]string)
+	merged := maps.Clone(existing)
+	if merged == nil {
+		merged = make(map[string]string, len(labels))
+	}
+	maps.Copy(merged, labels)
+	return context.WithValue(ctx, labelsKey{}, merged)
+}
+
+// Removed lists the ids of the objects RemoveLabeled removed.
+type Removed struct {
+	Containers []string
+	Networks   []string
+	Volumes    []string
+}
+
+// RemoveLabeled force removes the containers, networks and volumes created by
+// imagetest that have all of the labels. Containers go first, as networks and
+// volumes can't be removed while they're in use.
+func (d *Client) RemoveLabeled(ctx context.Context, labels map[string]string) (*Removed, error) {
+	args := filters.NewArgs(filters.Arg("label", DefaultLabel+"=true"))
+	for k, v := range labels {
+		args.Add("label", k+"="+v)
+	}
+
+	var (
+		removed Removed
+		errs    []error
+	)
+
+	containers, err := d.inner.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
+	if err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+	for _, c := range containers {
+		if err := d.inner.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true, RemoveVolumes: true}); err != nil {
+			errs = append(errs, fmt.Errorf("removing container %s: %w", c.ID, err))
+			continue
+		}
+		removed.Containers = append(removed.Containers, c.ID)
+	}
+
+	networks, err := d.inner.NetworkList(ctx, network.ListOptions{Filters: args})
+	if err != nil {
+		return nil, fmt.Errorf("listing networks: %w", err)
+	}
+	for _, n := range networks {
+		if err := d.inner.NetworkRemove(ctx, n.ID); err != nil {
+			errs = append(errs, fmt.Errorf("removing network %s: %w", n.Name, err))
+			continue
+		}
+		removed.Networks = append(removed.Networks, n.ID)
+	}
+
+	volumes, err := d.inner.VolumeList(ctx, volume.ListOptions{Filters: args})
+	if err != nil {
+		return nil, fmt.Errorf("listing volumes: %w", err)
+	}
+	for _, v := range volumes.Volumes {
+		if err := d.inner.VolumeRemove(ctx, v.Name, true); err != nil {
+			errs = append(errs, fmt.Errorf("removing volume %s: %w", v.Name, err))
+			continue
+		}
+		removed.Volumes = append(removed.Volumes, v.Name)
+	}
+
+	return &removed, errors.Join(errs...)
+}
@@ -0,0 +1,26 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLabels(t *testing.T) {
+	ctx := WithLabels(t.Context(), map[string]string{HarnessLabel: "test-abcde"})
+
+	// labels added to a derived context don't leak into the parent
+	_ = WithLabels(ctx, map[string]string{HarnessLabel: "other"})
+
+	d := &Client{}
+	got := d.withDefaultLabels(ctx, map[string]string{"foo": "bar"})
+	require.Equal(t, map[string]string{
+		DefaultLabel: "true",
+		HarnessLabel: "test-abcde",
+		"foo":        "bar",
+	}, got)
+
+	// labels of the request win over the context
+	got = d.withDefaultLabels(ctx, map[string]string{HarnessLabel: "explicit"})
+	require.Equal(t, "explicit", got[HarnessLabel])
+}
@@ -25,6 +25,9 @@ type NetworkRequest struct {
 type NetworkAttachment struct {
 	Name string
 	ID   string
+	// Aliases are additional DNS names the container is reachable by on the
+	// network.
+	Aliases []string
 }
 
 func (d *Client) CreateNetwork(ctx context.Context, req *NetworkRequest) (*NetworkAttachment, error) {
@@ -49,7 +52,7 @@ func (d *Client) CreateNetwork(ctx context.Context, req *NetworkRequest) (*Netwo
 	}, func(ctx context.Context) (bool, error) {
 		resp, err := d.inner.NetworkCreate(ctx, req.Name, network.CreateOptions{
 			Driver:     "bridge",
-			Labels:     d.withDefaultLabels(req.Labels),
+			Labels:     d.withDefaultLabels(ctx, req.Labels),
 			IPAM:       req.IPAM,
 			EnableIPv6: &req.EnableIPv6,
 		})
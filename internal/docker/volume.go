@@ -19,10 +19,11 @@ func (d *Client) CreateVolume(ctx context.Context, req *VolumeRequest) (mount.Mo
 	if req.Labels == nil {
 		req.Labels = make(map[string]string)
 	}
+	labels := d.withDefaultLabels(ctx, req.Labels)
 
 	v, err := d.inner.VolumeCreate(ctx, volume.CreateOptions{
 		Name:   req.Name,
-		Labels: req.Labels,
+		Labels: labels,
 	})
 	if err != nil {
 		return mount.Mount{}, err
@@ -33,7 +34,7 @@ func (d *Client) CreateVolume(ctx context.Context, req *VolumeRequest) (mount.Mo
 		Source: v.Name,
 		Target: req.Target,
 		VolumeOptions: &mount.VolumeOptions{
-			Labels: d.withDefaultLabels(req.Labels),
+			Labels: labels,
 		},
 	}, nil
 }
@@ -85,6 +85,8 @@ type driver struct {
 	// podIdentityClientIDs maps "namespace/serviceAccountName" to the Azure
 	// client ID of the user-assigned managed identity created for it.
 	podIdentityClientIDs map[string]string
+
+	podOpts []pod.RunOpts
 }
 
 type Options struct {
@@ -139,6 +141,9 @@ type Options struct {
 	// Allows assigning Azure roles to identities used by the Kubernetes services
 	// such as Kubelet.
 	ClusterIdentityAssociations []*ClusterIdentityAssociationOptions
+
+	// Additional options applied to the test sandbox pod.
+	PodOpts []pod.RunOpts
 }
 
 // RegistryConfig holds authentication configuration for a container registry.
@@ -198,6 +203,7 @@ func NewDriver(name string, opts Options) (drivers.Tester, error) {
 		tags:                 opts.Tags,
 		dnsPrefix:            opts.DNSPrefix,
 		podIdentityClientIDs: make(map[string]string),
+		podOpts:              opts.PodOpts,
 	}
 	if k.location == "" {
 		k.location = locationDefault
@@ -1061,5 +1067,7 @@ func (k *driver) Run(ctx context.Context, ref name.Reference) (*drivers.RunResul
 		)
 	}
 
+	runOpts = append(runOpts, k.podOpts...)
+
 	return pod.Run(ctx, k.kcfg, runOpts...)
 }
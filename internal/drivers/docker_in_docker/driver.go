@@ -10,6 +10,7 @@ import (
 	"io"
 	"os"
 	"runtime"
+	"slices"
 	"strconv"
 	"time"
 
@@ -25,6 +26,7 @@ import (
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/uuid"
 	v1 "github.com/moby/docker-image-spec/specs-go/v1"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -33,6 +35,9 @@ type driver struct {
 	Envs       map[string]string // Additional environment variables to set in the sandbox
 	ExtraHosts []string          // Extra hosts (--add-hosts) to add to the sandbox
 	Mirrors    []string          // Registry mirrors to use for docker-in-docker
+	Services   []Service         // Service containers started alongside the test container
+	Platforms  []ggcrv1.Platform // Platforms the tests run on, foreign ones are emulated
+	BinfmtRef  *name.Digest      // The image registering the emulators for foreign platforms
 
 	name      string
 	stack     *harness.Stack
@@ -97,6 +102,10 @@ func (d *driver) Setup(ctx context.Context) error {
 	}
 	d.cli = cli
 
+	if err := drivers.InstallEmulators(ctx, cli, d.BinfmtRef, d.Platforms); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -112,6 +121,29 @@ func (d *driver) Run(ctx context.Context, ref name.Reference) (*drivers.RunResul
 	// Build the driver image, uses the provided dind image appended with the ref
 	span := trace.SpanFromContext(ctx)
 
+	// A test image built for a single foreign platform runs in the dind image
+	// of the same platform, under emulation. That's only possible when the
+	// platform's emulator was installed in Setup.
+	tplatform, err := drivers.ImagePlatform(ref, d.ropts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get test image platform: %w", err)
+	}
+
+	var platform *ocispec.Platform
+	if drivers.Emulated(tplatform) {
+		if !slices.ContainsFunc(d.Platforms, func(p ggcrv1.Platform) bool {
+			return p.Architecture == tplatform.Architecture
+		}) {
+			return nil, fmt.Errorf("test image is built for %s only, which needs emulation: add it to platforms and set binfmt_image", tplatform.String())
+		}
+		clog.InfoContext(ctx, "running emulated test image", "platform", tplatform.String())
+		platform = &ocispec.Platform{
+			OS:           tplatform.OS,
+			Architecture: tplatform.Architecture,
+			Variant:      tplatform.Variant,
+		}
+	}
+
 	tref, err := bundler.Mutate(ctx, d.ImageRef, ref.Context(), bundler.MutateOpts{
 		RemoteOptions: d.ropts,
 		ImageMutators: []func(ggcrv1.Image) (ggcrv1.Image, error){
@@ -170,6 +202,12 @@ func (d *driver) Run(ctx context.Context, ref name.Reference) (*drivers.RunResul
 	}
 	span.AddEvent("dind.network.created")
 
+	suffix := uuid.New().String()[:8]
+
+	if err := d.startServices(ctx, nw, suffix); err != nil {
+		return nil, err
+	}
+
 	cliCfg, err := d.cliCfg.Content()
 	if err != nil {
 		return nil, err
@@ -205,7 +243,7 @@ func (d *driver) Run(ctx context.Context, ref name.Reference) (*drivers.RunResul
 		envs = append(envs, fmt.Sprintf("%s=%s", k, v))
 	}
 
-	cname := fmt.Sprintf("%s-%s", d.name, uuid.New().String()[:8])
+	cname := fmt.Sprintf("%s-%s", d.name, suffix)
 	clog.InfoContext(ctx, "running docker-in-docker test", "image_ref", tref.String(), "container_name", cname)
 	span.AddEvent("dind.container.started")
 	cid, err := d.cli.Run(ctx, &docker.Request{
@@ -229,6 +267,7 @@ func (d *driver) Run(ctx context.Context, ref name.Reference) (*drivers.RunResul
 		ExtraHosts: extraHosts,
 		Contents:   content,
 		Logger:     mw,
+		Platform:   platform,
 	})
 
 	result := &drivers.RunResult{}
@@ -243,6 +282,10 @@ func (d *driver) Run(ctx context.Context, ref name.Reference) (*drivers.RunResul
 			clog.WarnContextf(ctx, "failed to create artifact result: %v", aerr)
 		}
 		result.Artifact = a
+
+		if rerr := result.ReadArtifactReports(); rerr != nil {
+			clog.WarnContextf(ctx, "failed to read artifact reports: %v", rerr)
+		}
 	}
 
 	if err != nil {
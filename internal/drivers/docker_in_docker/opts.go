@@ -1,12 +1,14 @@
 package dockerindocker
 
 import (
+	"fmt"
 	"maps"
 
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/docker"
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/drivers"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 )
 
@@ -117,3 +119,51 @@ func WithTimeouts(t drivers.Timeouts) DriverOpts {
 		return nil
 	}
 }
+
+func WithServices(services ...Service) DriverOpts {
+	return func(d *driver) error {
+		for _, svc := range services {
+			if svc.Image == nil {
+				return fmt.Errorf("service %q has no image", svc.Alias)
+			}
+			if svc.Alias == "" {
+				return fmt.Errorf("service %s has no alias", svc.Image)
+			}
+			for _, existing := range d.Services {
+				if existing.Alias == svc.Alias {
+					return fmt.Errorf("duplicate service alias %q", svc.Alias)
+				}
+			}
+			d.Services = append(d.Services, svc)
+		}
+		return nil
+	}
+}
+
+// WithPlatforms sets the platforms tests run on, emulators are installed for
+// those foreign to the docker host.
+func WithPlatforms(platforms ...string) DriverOpts {
+	return func(d *driver) error {
+		for _, p := range platforms {
+			platform, err := ggcrv1.ParsePlatform(p)
+			if err != nil {
+				return fmt.Errorf("invalid platform %q: %w", p, err)
+			}
+			d.Platforms = append(d.Platforms, *platform)
+		}
+		return nil
+	}
+}
+
+// WithBinfmtImage sets the digest pinned image that registers the emulators
+// for foreign platforms.
+func WithBinfmtImage(rawRef string) DriverOpts {
+	return func(d *driver) error {
+		ref, err := drivers.ParseBinfmtRef(rawRef)
+		if err != nil {
+			return err
+		}
+		d.BinfmtRef = &ref
+		return nil
+	}
+}
@@ -0,0 +1,77 @@
+package dockerindocker
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/docker"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/moby/docker-image-spec/specs-go/v1"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Service is a container started alongside the test container, on the same
+// network, before the test runs. Tests reach it by its Alias.
+type Service struct {
+	Image       name.Reference
+	Alias       string
+	Env         map[string]string
+	Command     []string
+	HealthCheck *v1.HealthcheckConfig
+	// Timeout bounds how long to wait for the service to be running, and
+	// healthy when either HealthCheck or the image defines a healthcheck.
+	Timeout time.Duration
+}
+
+// startServices starts every service on the given network, blocking until
+// each is running and, when it has a healthcheck, healthy. The services are removed when the driver is
+// torn down.
+func (d *driver) startServices(ctx context.Context, nw *docker.NetworkAttachment, suffix string) error {
+	span := trace.SpanFromContext(ctx)
+
+	for _, svc := range d.Services {
+		sname := fmt.Sprintf("%s-%s-%s", d.name, svc.Alias, suffix)
+
+		// Register the removal before starting, since a service that never
+		// becomes healthy is still left behind.
+		if err := d.stack.Add(func(ctx context.Context) error {
+			return d.cli.Remove(ctx, &docker.Response{ID: sname})
+		}); err != nil {
+			return err
+		}
+
+		envs := []string{}
+		for _, k := range slices.Sorted(maps.Keys(svc.Env)) {
+			envs = append(envs, fmt.Sprintf("%s=%s", k, svc.Env[k]))
+		}
+
+		clog.InfoContext(ctx, "starting docker-in-docker service", "image_ref", svc.Image.String(), "alias", svc.Alias, "container_name", sname)
+		if _, err := d.cli.Start(ctx, &docker.Request{
+			Name: sname,
+			Ref:  svc.Image,
+			Env:  envs,
+			Cmd:  svc.Command,
+			Labels: map[string]string{
+				"dev.chainguard.imagetest.service": svc.Alias,
+			},
+			Networks: []docker.NetworkAttachment{{
+				Name:    nw.Name,
+				ID:      nw.ID,
+				Aliases: []string{svc.Alias},
+			}},
+			HealthCheck: svc.HealthCheck,
+			WaitHealthy: true,
+			Timeout:     svc.Timeout,
+		}); err != nil {
+			return fmt.Errorf("starting service %s: %w", svc.Alias, err)
+		}
+
+		span.AddEvent("dind.service.started")
+	}
+
+	return nil
+}
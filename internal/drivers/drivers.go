@@ -1,16 +1,25 @@
 package drivers
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/url"
 	"os"
+	"path"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/entrypoint"
 	"github.com/google/go-containerregistry/pkg/name"
 )
 
@@ -24,6 +33,14 @@ type Tester interface {
 	Run(context.Context, name.Reference) (*RunResult, error)
 }
 
+// KubernetesVersioner is implemented by drivers that provision their own
+// Kubernetes cluster.
+type KubernetesVersioner interface {
+	// KubernetesVersion returns the version reported by the cluster's api
+	// server, it is only available after Setup()
+	KubernetesVersion() string
+}
+
 // Timeouts holds parsed driver lifecycle timeouts. Zero means no
 // driver-level deadline for that phase.
 type Timeouts struct {
@@ -75,11 +92,20 @@ func (t Timeouts) TeardownContext(ctx context.Context) (context.Context, context
 
 type RunResult struct {
 	Artifact *RunArtifactResult
+	// Profile is the resource usage recorded by the entrypoint, if any.
+	Profile *entrypoint.ResourceSummary
+	// Events are the step and annotation events sent by the test, in the
+	// order they were recorded.
+	Events []entrypoint.Event
+	// Egress are the destinations the test contacted through the
+	// entrypoint's egress proxy, when it was enabled.
+	Egress []entrypoint.EgressRecord
 }
 
 type RunArtifactResult struct {
 	URI      string
 	Checksum string
+	Size     int64 // The size of the bundle in bytes
 }
 
 func NewRunArtifactResult(ctx context.Context, rc io.ReadCloser) (*RunArtifactResult, error) {
@@ -92,7 +118,8 @@ func NewRunArtifactResult(ctx context.Context, rc io.ReadCloser) (*RunArtifactRe
 	h := sha256.New()
 	mw := io.MultiWriter(af, h)
 
-	if _, err := io.Copy(mw, rc); err != nil {
+	n, err := io.Copy(mw, rc)
+	if err != nil {
 		return nil, err
 	}
 
@@ -110,5 +137,185 @@ func NewRunArtifactResult(ctx context.Context, rc io.ReadCloser) (*RunArtifactRe
 	return &RunArtifactResult{
 		URI:      u.String(),
 		Checksum: checksum,
+		Size:     n,
 	}, nil
 }
+
+// AppendRunArtifactFiles returns a new artifact containing every entry of
+// artifact plus files, keyed by their slash separated path within the
+// bundle. The existing artifact may be nil or empty, in which case a bundle
+// is created from files alone. The existing artifact's file is removed once
+// it's been replaced.
+func AppendRunArtifactFiles(ctx context.Context, artifact *RunArtifactResult, files map[string][]byte) (*RunArtifactResult, error) {
+	pr, pw := io.Pipe()
+
+	go func() {
+		pw.CloseWithError(writeArtifactBundle(pw, artifact, files))
+	}()
+
+	result, err := NewRunArtifactResult(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+
+	if artifact != nil && artifact.URI != "" {
+		if u, err := url.Parse(artifact.URI); err == nil && u.Scheme == "file" {
+			if err := os.Remove(u.Path); err != nil && !os.IsNotExist(err) {
+				clog.WarnContext(ctx, "failed to remove replaced artifact", "uri", artifact.URI, "error", err)
+			}
+		}
+	}
+	return result, nil
+}
+
+func writeArtifactBundle(w io.Writer, artifact *RunArtifactResult, files map[string][]byte) error {
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+
+	if artifact != nil && artifact.URI != "" {
+		if err := copyArtifactEntries(tw, artifact.URI); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		data := files[name]
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0o644,
+			Size:     int64(len(data)),
+			ModTime:  time.Now(),
+		}); err != nil {
+			return fmt.Errorf("writing tar header for %s: %w", name, err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return fmt.Errorf("writing %s to artifact: %w", name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("closing tar writer: %w", err)
+	}
+	return gzw.Close()
+}
+
+func copyArtifactEntries(tw *tar.Writer, uri string) error {
+	return walkArtifact(uri, func(hdr *tar.Header, r io.Reader) error {
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("writing tar header for %s: %w", hdr.Name, err)
+		}
+		if _, err := io.Copy(tw, r); err != nil {
+			return fmt.Errorf("copying %s to artifact: %w", hdr.Name, err)
+		}
+		return nil
+	})
+}
+
+// walkArtifact calls fn for every entry of the artifact bundle at uri.
+func walkArtifact(uri string, fn func(hdr *tar.Header, r io.Reader) error) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return fmt.Errorf("parsing artifact uri %q: %w", uri, err)
+	}
+	if u.Scheme != "file" {
+		return fmt.Errorf("unsupported artifact uri scheme %q", u.Scheme)
+	}
+
+	f, err := os.Open(u.Path)
+	if err != nil {
+		return fmt.Errorf("opening artifact: %w", err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat artifact: %w", err)
+	}
+	// A failed export leaves behind an empty file, treat it as an empty bundle
+	if fi.Size() == 0 {
+		return nil
+	}
+
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("reading artifact: %w", err)
+	}
+	defer gzr.Close()
+
+	return walkTar(gzr, fn)
+}
+
+// walkTar calls fn for every entry of the tar stream rd.
+func walkTar(rd io.Reader, fn func(hdr *tar.Header, r io.Reader) error) error {
+	tr := tar.NewReader(rd)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading artifact entry: %w", err)
+		}
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// ReadArtifactReports fills the result's Profile, Events and Egress from the reports
+// the entrypoint wrote to its artifact bundle. Reports missing from the bundle
+// are left empty.
+func (r *RunResult) ReadArtifactReports() error {
+	if r.Artifact == nil || r.Artifact.URI == "" {
+		return nil
+	}
+
+	return walkArtifact(r.Artifact.URI, r.readReport(""))
+}
+
+// ReadReports is ReadArtifactReports for a tar stream of the entrypoint's
+// artifacts directory, such as one copied out of a container, whose entries
+// are all under dir.
+func (r *RunResult) ReadReports(rd io.Reader, dir string) error {
+	return walkTar(rd, r.readReport(dir))
+}
+
+// readReport returns a walk func decoding the reports of the entries under
+// dir, or of every entry when dir is empty.
+func (r *RunResult) readReport(dir string) func(hdr *tar.Header, rd io.Reader) error {
+	return func(hdr *tar.Header, rd io.Reader) error {
+		name := path.Clean(hdr.Name)
+		if dir != "" {
+			rel, ok := strings.CutPrefix(name, path.Clean(dir)+"/")
+			if !ok {
+				return nil
+			}
+			name = rel
+		}
+
+		switch name {
+		case entrypoint.ProfileSummaryPath:
+			r.Profile = &entrypoint.ResourceSummary{}
+			if err := json.NewDecoder(rd).Decode(r.Profile); err != nil {
+				return fmt.Errorf("decoding resource profile: %w", err)
+			}
+
+		case entrypoint.EventsPath:
+			dec := json.NewDecoder(rd)
+			for dec.More() {
+				var ev entrypoint.Event
+				if err := dec.Decode(&ev); err != nil {
+					return fmt.Errorf("decoding events: %w", err)
+				}
+				r.Events = append(r.Events, ev)
+			}
+
+		case entrypoint.EgressPath:
+			if err := json.NewDecoder(rd).Decode(&r.Egress); err != nil {
+				return fmt.Errorf("decoding egress records: %w", err)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,208 @@
+package drivers
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/entrypoint"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAppendRunArtifactFiles(t *testing.T) {
+	ctx := t.Context()
+
+	base, err := AppendRunArtifactFiles(ctx, nil, map[string][]byte{
+		"logs/process.log": []byte("hello"),
+	})
+	if err != nil {
+		t.Fatalf("creating base artifact: %v", err)
+	}
+
+	got, err := AppendRunArtifactFiles(ctx, base, map[string][]byte{
+		"diagnostics/app/events.txt": []byte("events"),
+	})
+	if err != nil {
+		t.Fatalf("appending to artifact: %v", err)
+	}
+
+	if got.Checksum == base.Checksum {
+		t.Errorf("expected checksum to change after appending")
+	}
+
+	want := map[string]string{
+		"logs/process.log":           "hello",
+		"diagnostics/app/events.txt": "events",
+	}
+	if diff := cmp.Diff(want, readArtifact(t, got.URI)); diff != "" {
+		t.Errorf("artifact contents mismatch (-want +got):\n%s", diff)
+	}
+
+	u, err := url.Parse(base.URI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(u.Path); !os.IsNotExist(err) {
+		t.Errorf("expected the replaced artifact to be removed, got: %v", err)
+	}
+}
+
+func TestReadArtifactReports(t *testing.T) {
+	ctx := t.Context()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	withReports, err := AppendRunArtifactFiles(ctx, nil, map[string][]byte{
+		"logs/process.log":            []byte("hello"),
+		entrypoint.ProfileSummaryPath: []byte(`{"duration":2000000000,"samples":2,"cpu_peak_cores":1.5,"memory_peak_bytes":4096}`),
+		entrypoint.EventsPath: []byte(`{"type":"step_start","time":"2024-01-01T00:00:00Z","step":"build"}
+{"type":"step_end","time":"2024-01-01T00:00:01Z","step":"build","status":"ok"}
+`),
+		entrypoint.EgressPath: []byte(`[{"host":"example.com","port":"443","allowed":false,"requests":3,"first_seen":"2024-01-01T00:00:00Z"}]`),
+	})
+	if err != nil {
+		t.Fatalf("creating artifact: %v", err)
+	}
+
+	got := &RunResult{Artifact: withReports}
+	if err := got.ReadArtifactReports(); err != nil {
+		t.Fatalf("reading reports: %v", err)
+	}
+	want := &RunResult{
+		Artifact: withReports,
+		Profile: &entrypoint.ResourceSummary{
+			Duration:        2 * time.Second,
+			Samples:         2,
+			CPUPeakCores:    1.5,
+			MemoryPeakBytes: 4096,
+		},
+		Events: []entrypoint.Event{
+			{Type: entrypoint.EventStepStart, Time: start, Step: "build"},
+			{Type: entrypoint.EventStepEnd, Time: start.Add(time.Second), Step: "build", Status: entrypoint.StepStatusOK},
+		},
+		Egress: []entrypoint.EgressRecord{
+			{Host: "example.com", Port: "443", Requests: 3, FirstSeen: start},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("reports mismatch (-want +got):\n%s", diff)
+	}
+
+	withoutReports, err := AppendRunArtifactFiles(ctx, nil, map[string][]byte{
+		"logs/process.log": []byte("hello"),
+	})
+	if err != nil {
+		t.Fatalf("creating artifact: %v", err)
+	}
+
+	got = &RunResult{Artifact: withoutReports}
+	if err := got.ReadArtifactReports(); err != nil {
+		t.Fatalf("reading reports: %v", err)
+	}
+	if got.Profile != nil || got.Events != nil || got.Egress != nil {
+		t.Errorf("expected no reports, got %+v", got)
+	}
+
+	if err := (&RunResult{}).ReadArtifactReports(); err != nil {
+		t.Errorf("expected a result without an artifact to have no reports, got %v", err)
+	}
+}
+
+func TestReadReports(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Laid out like a copy of the artifacts directory out of a container
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range []struct {
+		name string
+		data string
+	}{
+		{"artifacts/", ""},
+		{"artifacts/logs/process.log", "hello"},
+		{"artifacts/" + entrypoint.ProfileSummaryPath, `{"duration":2000000000,"samples":2}`},
+		{"artifacts/" + entrypoint.EventsPath, `{"type":"step_start","time":"2024-01-01T00:00:00Z","step":"build"}
+{"type":"step_end","time":"2024-01-01T00:00:01Z","step":"build","status":"ok"}
+`},
+		{"artifacts/" + entrypoint.EgressPath, `[{"host":"example.com","port":"443","allowed":false,"requests":3,"first_seen":"2024-01-01T00:00:00Z"}]`},
+		{entrypoint.EventsPath, `{"type":"step_start","time":"2024-01-01T00:00:00Z","step":"elsewhere"}`},
+	} {
+		hdr := &tar.Header{Typeflag: tar.TypeReg, Name: f.name, Mode: 0o644, Size: int64(len(f.data))}
+		if f.data == "" {
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0o755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := &RunResult{}
+	if err := got.ReadReports(&buf, "artifacts"); err != nil {
+		t.Fatalf("reading reports: %v", err)
+	}
+	want := &RunResult{
+		Profile: &entrypoint.ResourceSummary{
+			Duration: 2 * time.Second,
+			Samples:  2,
+		},
+		Events: []entrypoint.Event{
+			{Type: entrypoint.EventStepStart, Time: start, Step: "build"},
+			{Type: entrypoint.EventStepEnd, Time: start.Add(time.Second), Step: "build", Status: entrypoint.StepStatusOK},
+		},
+		Egress: []entrypoint.EgressRecord{
+			{Host: "example.com", Port: "443", Requests: 3, FirstSeen: start},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("reports mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func readArtifact(t *testing.T, uri string) map[string]string {
+	t.Helper()
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Remove(u.Path) })
+
+	data, err := os.ReadFile(u.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gzr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]string)
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[hdr.Name] = string(b)
+	}
+	return files
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -21,6 +22,7 @@ import (
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/moby/docker-image-spec/specs-go/v1"
@@ -130,6 +132,9 @@ func (d *driver) runContainer(ctx context.Context, cli *client.Client, ref name.
 			Env:          env,
 			AttachStdout: true,
 			AttachStderr: true,
+			Labels: map[string]string{
+				"dev.chainguard.imagetest": "true",
+			},
 			Healthcheck: &v1.HealthcheckConfig{
 				Test:        append([]string{"CMD"}, entrypoint.DefaultHealthCheckCommand...),
 				Interval:    1 * time.Second,
@@ -224,7 +229,9 @@ func (d *driver) runContainer(ctx context.Context, cli *client.Client, ref name.
 		}
 	}()
 
-	var result *drivers.RunResult
+	// The result is returned even when the test fails, so its reports
+	// explain the failure
+	result := &drivers.RunResult{}
 	var runErr error
 
 	select {
@@ -233,17 +240,13 @@ func (d *driver) runContainer(ctx context.Context, cli *client.Client, ref name.
 			runErr = fmt.Errorf("waiting for container: %w", err)
 		}
 	case status := <-statusCh:
-		switch status.StatusCode {
-		case 0, int64(entrypoint.ProcessPausedCode):
-			result = &drivers.RunResult{}
-		default:
+		if status.StatusCode != 0 && status.StatusCode != int64(entrypoint.ProcessPausedCode) {
 			runErr = fmt.Errorf("container exited with code %d", status.StatusCode)
 		}
 	case exitCode := <-healthCh:
 		// Health check detected a terminal state
 		if exitCode == int64(entrypoint.ProcessPausedCode) {
 			log.Info("container paused after success (IMAGETEST_SKIP_TEARDOWN)")
-			result = &drivers.RunResult{}
 		} else {
 			runErr = fmt.Errorf("container health check failed with exit code %d", exitCode)
 		}
@@ -255,6 +258,10 @@ func (d *driver) runContainer(ctx context.Context, cli *client.Client, ref name.
 	cancelRun()
 	<-logDone
 
+	if err := readReports(ctx, cli, resp.ID, result); err != nil {
+		log.Warn("failed to read artifact reports", "error", err)
+	}
+
 	if runErr != nil && logBuf.Len() > 0 {
 		runErr = fmt.Errorf("%w\n\nContainer %s logs:\n%s", runErr, containerName, logBuf.String())
 	}
@@ -262,6 +269,24 @@ func (d *driver) runContainer(ctx context.Context, cli *client.Client, ref name.
 	return result, runErr
 }
 
+// readReports fills the result's resource profile, step events and egress
+// records from the entrypoint's artifacts directory. Unlike the other drivers there's no artifact bundle to read them
+// from, so the directory is copied out of the stopped (or paused) container
+// instead.
+func readReports(ctx context.Context, cli *client.Client, id string, result *drivers.RunResult) error {
+	rc, _, err := cli.CopyFromContainer(ctx, id, entrypoint.ArtifactsDir)
+	if errdefs.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("copying artifacts: %w", err)
+	}
+	defer rc.Close()
+
+	// The copy's entries are rooted at the directory's base name
+	return result.ReadReports(rc, path.Base(entrypoint.ArtifactsDir))
+}
+
 func (d *driver) mounts(ctx context.Context) []mount.Mount {
 	if len(d.cfg.VolumeMounts) == 0 {
 		return nil
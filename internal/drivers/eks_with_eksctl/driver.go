@@ -58,6 +58,7 @@ type driver struct {
 
 	podIdentityAssociations []*podIdentityAssociation
 	registries              map[string]*RegistryConfig
+	podOpts                 []pod.RunOpts
 }
 
 type Options struct {
@@ -72,6 +73,8 @@ type Options struct {
 	Tags                    map[string]string
 	Timeouts                drivers.Timeouts
 	Registries              map[string]*RegistryConfig
+	// PodOpts are additional options applied to the test sandbox pod.
+	PodOpts []pod.RunOpts
 }
 
 // RegistryConfig holds authentication configuration for a container registry.
@@ -122,6 +125,7 @@ func NewDriver(name string, opts Options) (drivers.Tester, error) {
 		awsProfile: opts.AWSProfile,
 		tags:       opts.Tags,
 		timeouts:   opts.Timeouts,
+		podOpts:    opts.PodOpts,
 	}
 	if k.region == "" {
 		k.region = regionDefault
@@ -603,13 +607,16 @@ func (k *driver) Run(ctx context.Context, ref name.Reference) (*drivers.RunResul
 		}
 	}
 
-	return pod.Run(ctx, k.kcfg,
+	runOpts := []pod.RunOpts{
 		pod.WithImageRef(ref),
 		pod.WithExtraEnvs(map[string]string{
 			"IMAGETEST_DRIVER": "eks_with_eksctl",
 		}),
 		pod.WithRegistryStaticAuth(dcfg),
-	)
+	}
+	runOpts = append(runOpts, k.podOpts...)
+
+	return pod.Run(ctx, k.kcfg, runOpts...)
 }
 
 func (k *driver) amiFamily() string {
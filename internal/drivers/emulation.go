@@ -0,0 +1,94 @@
+package drivers
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/docker"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// ParseBinfmtRef parses the reference of the image used to register the QEMU
+// emulators for foreign platforms with the kernel of the docker host, e.g.
+// tonistiigi/binfmt@sha256:.... The image runs privileged, so it must be
+// pinned by digest.
+func ParseBinfmtRef(raw string) (name.Digest, error) {
+	ref, err := name.NewDigest(raw)
+	if err != nil {
+		return name.Digest{}, fmt.Errorf("binfmt image must be pinned by digest: %w", err)
+	}
+	return ref, nil
+}
+
+// Emulated reports whether images for the platform must be run under
+// emulation on this host.
+func Emulated(p *v1.Platform) bool {
+	return p != nil && p.Architecture != "" && p.Architecture != runtime.GOARCH
+}
+
+// ImagePlatform returns the platform of the image at ref, or nil when ref is
+// an index and the platform is only chosen when it is pulled.
+func ImagePlatform(ref name.Reference, opts ...remote.Option) (*v1.Platform, error) {
+	desc, err := remote.Get(ref, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image: %w", err)
+	}
+	if !desc.MediaType.IsImage() {
+		return nil, nil
+	}
+
+	img, err := desc.Image()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load image: %w", err)
+	}
+
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config file: %w", err)
+	}
+	return cfg.Platform(), nil
+}
+
+// InstallEmulators registers the emulators for the foreign platforms by
+// running the binfmt image, which is nil when none is configured. binfmt
+// handlers are global to the kernel, so they apply to every container on the
+// docker host, including nested ones.
+func InstallEmulators(ctx context.Context, cli *docker.Client, binfmt *name.Digest, platforms []v1.Platform) error {
+	var archs []string
+	for _, p := range platforms {
+		if Emulated(&p) && !slices.Contains(archs, p.Architecture) {
+			archs = append(archs, p.Architecture)
+		}
+	}
+	if len(archs) == 0 {
+		return nil
+	}
+
+	if binfmt == nil {
+		return fmt.Errorf("emulating %s requires a binfmt image pinned by digest", strings.Join(archs, ", "))
+	}
+
+	clog.InfoContext(ctx, "installing emulators", "architectures", archs, "image_ref", binfmt.String())
+	cid, err := cli.Run(ctx, &docker.Request{
+		Ref:        *binfmt,
+		Privileged: true, // Required to register binfmt handlers
+		Cmd:        []string{"--install", strings.Join(archs, ",")},
+	})
+	if cid != "" {
+		defer func() {
+			if rerr := cli.Remove(ctx, &docker.Response{ID: cid}); rerr != nil {
+				clog.WarnContextf(ctx, "failed to remove binfmt container: %v", rerr)
+			}
+		}()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to install emulators for %s: %w", strings.Join(archs, ", "), err)
+	}
+	return nil
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strconv"
 	"text/template"
 	"time"
@@ -18,6 +20,10 @@ import (
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/go-connections/nat"
 	"github.com/google/go-containerregistry/pkg/name"
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 	v1 "github.com/moby/docker-image-spec/specs-go/v1"
 	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -41,6 +47,14 @@ type driver struct {
 	Namespace     string            // The namespace to use for the test pods
 	Hooks         *K3sHooks         // Run commands at various lifecycle events
 	SandboxEnvs   map[string]string // Additional environment variables to set in the sandbox
+	PodOpts       []pod.RunOpts     // Additional options applied to the sandbox pod
+	Platforms     []ggcrv1.Platform // Platforms the tests run on, foreign ones are emulated
+	BinfmtRef     *name.Digest      // The image registering the emulators for foreign platforms
+
+	Version      string          // The Kubernetes version to resolve a k3s image for, e.g. 1.30
+	ServerArgs   []string        // Additional flags passed to the k3s server
+	KubeletArgs  []string        // Additional flags passed to the kubelet
+	FeatureGates map[string]bool // Feature gates enabled or disabled across all components
 
 	kubeconfigWritePath string // When set, the generated kubeconfig will be written to this path on the host
 
@@ -49,6 +63,11 @@ type driver struct {
 	kcli     kubernetes.Interface
 	kcfg     *rest.Config
 	timeouts drivers.Timeouts
+	ropts    []remote.Option
+
+	// serverVersion is the version reported by the api server once the
+	// cluster is up.
+	serverVersion string
 }
 
 type K3sRegistryConfig struct {
@@ -98,6 +117,14 @@ func (k *driver) Setup(ctx context.Context) error {
 		return fmt.Errorf("creating docker client: %w", err)
 	}
 
+	if err := k.resolveImage(ctx); err != nil {
+		return fmt.Errorf("resolving kubernetes version: %w", err)
+	}
+
+	if err := drivers.InstallEmulators(ctx, cli, k.BinfmtRef, k.Platforms); err != nil {
+		return err
+	}
+
 	contents := []*docker.Content{}
 
 	ktpl := fmt.Sprintf(`
@@ -179,7 +206,7 @@ configs:
 	resp, err := cli.Start(ctx, &docker.Request{
 		Name:       k.name,
 		Ref:        k.ImageRef,
-		Cmd:        []string{"server"},
+		Cmd:        k.serverArgs(),
 		Privileged: true, // This doesn't work without privilege, so don't make it configurable
 		Networks: []docker.NetworkAttachment{{
 			ID:   nw.ID,
@@ -294,6 +321,13 @@ configs:
 	}
 	trace.SpanFromContext(ctx).AddEvent("k3s.cluster.ready")
 
+	sv, err := k.kcli.Discovery().ServerVersion()
+	if err != nil {
+		return fmt.Errorf("getting server version: %w", err)
+	}
+	k.serverVersion = sv.GitVersion
+	clog.InfoContext(ctx, "k3s cluster ready", "kubernetes_version", k.serverVersion)
+
 	// Ensure some common mount propagation fixes are applied to make this feel
 	// more like a "real" cluster
 	defaultMountCommands := []string{
@@ -326,6 +360,12 @@ configs:
 	return nil
 }
 
+// KubernetesVersion returns the version of the running cluster. It is only
+// available once Setup has completed.
+func (k *driver) KubernetesVersion() string {
+	return k.serverVersion
+}
+
 func (k *driver) Teardown(ctx context.Context) error {
 	ctx, cancel := k.timeouts.TeardownContext(ctx)
 	defer cancel()
@@ -348,14 +388,74 @@ func (k *driver) Run(ctx context.Context, ref name.Reference) (*drivers.RunResul
 		}
 	}
 
-	return pod.Run(ctx, k.kcfg,
+	ref, err := k.nativeRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	runOpts := []pod.RunOpts{
 		pod.WithImageRef(ref),
 		pod.WithExtraEnvs(map[string]string{
 			"IMAGETEST_DRIVER": "k3s_in_docker",
 		}),
 		pod.WithExtraEnvs(k.SandboxEnvs),
 		pod.WithRegistryStaticAuth(dcfg),
-	)
+	}
+	runOpts = append(runOpts, k.PodOpts...)
+
+	return pod.Run(ctx, k.kcfg, runOpts...)
+}
+
+// nativeRef returns a reference containerd accepts for a test image built for
+// a single foreign platform, when that platform is one of the driver's
+// platforms and so has an emulator installed. containerd refuses to run an
+// image whose platform doesn't match the node's, so the image is wrapped in
+// an index that deliberately mislabels it as the node's platform, and is then
+// run under emulation. The index is pushed to the test image's repository by
+// digest only, it is never tagged, but anything pulling it by that digest gets
+// the foreign image for the node's platform.
+func (k *driver) nativeRef(ctx context.Context, ref name.Reference) (name.Reference, error) {
+	if len(k.Platforms) == 0 {
+		return ref, nil
+	}
+
+	platform, err := drivers.ImagePlatform(ref, k.ropts...)
+	if err != nil {
+		return nil, fmt.Errorf("getting test image platform: %w", err)
+	}
+	if !drivers.Emulated(platform) || !slices.ContainsFunc(k.Platforms, func(p ggcrv1.Platform) bool {
+		return p.Architecture == platform.Architecture
+	}) {
+		return ref, nil
+	}
+
+	img, err := remote.Image(ref, k.ropts...)
+	if err != nil {
+		return nil, fmt.Errorf("loading test image: %w", err)
+	}
+
+	idx := mutate.AppendManifests(empty.Index, mutate.IndexAddendum{
+		Add: img,
+		Descriptor: ggcrv1.Descriptor{
+			Platform: &ggcrv1.Platform{
+				OS:           platform.OS,
+				Architecture: runtime.GOARCH,
+			},
+		},
+	})
+
+	dig, err := idx.Digest()
+	if err != nil {
+		return nil, fmt.Errorf("getting index digest: %w", err)
+	}
+
+	iref := ref.Context().Digest(dig.String())
+	if err := remote.WriteIndex(iref, idx, append(k.ropts, remote.WithContext(ctx))...); err != nil {
+		return nil, fmt.Errorf("pushing test image index: %w", err)
+	}
+
+	clog.InfoContext(ctx, "running emulated test image", "platform", platform.String(), "image_ref", iref.String())
+	return iref, nil
 }
 
 // waitReady blocks until the k3s cluster is "ready". there are many
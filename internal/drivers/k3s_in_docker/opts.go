@@ -5,8 +5,11 @@ import (
 	"maps"
 
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/drivers"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/drivers/pod"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 )
 
 type DriverOpts func(*driver) error
@@ -154,3 +157,81 @@ func WithTimeouts(t drivers.Timeouts) DriverOpts {
 		return nil
 	}
 }
+
+func WithPodOpts(opts ...pod.RunOpts) DriverOpts {
+	return func(k *driver) error {
+		k.PodOpts = append(k.PodOpts, opts...)
+		return nil
+	}
+}
+
+func WithRemoteOptions(opts ...remote.Option) DriverOpts {
+	return func(k *driver) error {
+		k.ropts = append(k.ropts, opts...)
+		return nil
+	}
+}
+
+// WithKubernetesVersion resolves the k3s image to the newest tag in the image's
+// repository matching the given minor (1.30) or patch (1.30.4) version.
+func WithKubernetesVersion(version string) DriverOpts {
+	return func(k *driver) error {
+		if !requestedVersionRe.MatchString(version) {
+			return fmt.Errorf("invalid kubernetes version %q, must be of the form 1.30 or 1.30.4", version)
+		}
+		k.Version = version
+		return nil
+	}
+}
+
+func WithServerArgs(args ...string) DriverOpts {
+	return func(k *driver) error {
+		k.ServerArgs = append(k.ServerArgs, args...)
+		return nil
+	}
+}
+
+func WithKubeletArgs(args ...string) DriverOpts {
+	return func(k *driver) error {
+		k.KubeletArgs = append(k.KubeletArgs, args...)
+		return nil
+	}
+}
+
+func WithFeatureGates(gates map[string]bool) DriverOpts {
+	return func(k *driver) error {
+		if k.FeatureGates == nil {
+			k.FeatureGates = make(map[string]bool)
+		}
+		maps.Copy(k.FeatureGates, gates)
+		return nil
+	}
+}
+
+// WithPlatforms sets the platforms tests run on, emulators are installed for
+// those foreign to the docker host.
+func WithPlatforms(platforms ...string) DriverOpts {
+	return func(k *driver) error {
+		for _, p := range platforms {
+			platform, err := ggcrv1.ParsePlatform(p)
+			if err != nil {
+				return fmt.Errorf("invalid platform %q: %w", p, err)
+			}
+			k.Platforms = append(k.Platforms, *platform)
+		}
+		return nil
+	}
+}
+
+// WithBinfmtImage sets the digest pinned image that registers the emulators
+// for foreign platforms.
+func WithBinfmtImage(rawRef string) DriverOpts {
+	return func(k *driver) error {
+		ref, err := drivers.ParseBinfmtRef(rawRef)
+		if err != nil {
+			return err
+		}
+		k.BinfmtRef = &ref
+		return nil
+	}
+}
@@ -0,0 +1,155 @@
+package k3sindocker
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// requestedVersionRe matches the Kubernetes versions a user can ask for, either
+// a minor (1.30) or a patch (1.30.4) release.
+var requestedVersionRe = regexp.MustCompile(`^v?(\d+)\.(\d+)(?:\.(\d+))?$`)
+
+// tagVersionRe matches the version tags published for k3s images, covering
+// both the upstream (v1.30.4-k3s1) and Chainguard (1.30.4, 1.30.4-r1, 1.30-dev)
+// conventions.
+var tagVersionRe = regexp.MustCompile(`^v?(\d+)\.(\d+)(?:\.(\d+))?(?:[-+](?:k3s|r)(\d+))?(-dev)?$`)
+
+type k3sVersion struct {
+	major, minor int
+	// patch and revision are -1 when the tag does not pin them.
+	patch, revision int
+	dev             bool
+}
+
+func parseTagVersion(tag string) (k3sVersion, bool) {
+	m := tagVersionRe.FindStringSubmatch(tag)
+	if m == nil {
+		return k3sVersion{}, false
+	}
+
+	v := k3sVersion{patch: -1, revision: -1, dev: m[5] != ""}
+	v.major, _ = strconv.Atoi(m[1])
+	v.minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.patch, _ = strconv.Atoi(m[3])
+	}
+	if m[4] != "" {
+		v.revision, _ = strconv.Atoi(m[4])
+	}
+	return v, true
+}
+
+// less orders versions so that, among tags for the same release, the ones
+// pinning a patch and revision sort above floating tags.
+func (v k3sVersion) less(o k3sVersion) bool {
+	if v.major != o.major {
+		return v.major < o.major
+	}
+	if v.minor != o.minor {
+		return v.minor < o.minor
+	}
+	if v.patch != o.patch {
+		return v.patch < o.patch
+	}
+	return v.revision < o.revision
+}
+
+// resolveVersionTag returns the tag from tags that best matches the requested
+// Kubernetes version: the newest release of the requested minor or patch
+// version, of the same variant (-dev or not) as the default image.
+func resolveVersionTag(tags []string, version string, dev bool) (string, error) {
+	m := requestedVersionRe.FindStringSubmatch(version)
+	if m == nil {
+		return "", fmt.Errorf("invalid kubernetes version %q, must be of the form 1.30 or 1.30.4", version)
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	patch := -1
+	if m[3] != "" {
+		patch, _ = strconv.Atoi(m[3])
+	}
+
+	var (
+		best    string
+		bestVer k3sVersion
+	)
+	for _, tag := range tags {
+		v, ok := parseTagVersion(tag)
+		if !ok || v.dev != dev || v.major != major || v.minor != minor {
+			continue
+		}
+		if patch >= 0 && v.patch != patch {
+			continue
+		}
+		if best == "" || bestVer.less(v) {
+			best, bestVer = tag, v
+		}
+	}
+
+	if best == "" {
+		return "", fmt.Errorf("no k3s image found for kubernetes version %s", version)
+	}
+	return best, nil
+}
+
+// resolveImage points ImageRef at the tag in its repository matching the
+// requested Version.
+func (k *driver) resolveImage(ctx context.Context) error {
+	if k.Version == "" {
+		return nil
+	}
+
+	dev := false
+	if tag, ok := k.ImageRef.(name.Tag); ok {
+		dev = strings.HasSuffix(tag.TagStr(), "-dev")
+	}
+
+	repo := k.ImageRef.Context()
+	tags, err := remote.List(repo, append(k.ropts, remote.WithContext(ctx))...)
+	if err != nil {
+		return fmt.Errorf("listing tags for %s: %w", repo, err)
+	}
+
+	tag, err := resolveVersionTag(tags, k.Version, dev)
+	if err != nil {
+		return fmt.Errorf("%s: %w", repo, err)
+	}
+
+	k.ImageRef = repo.Tag(tag)
+	clog.InfoContext(ctx, "resolved kubernetes version", "kubernetes_version", k.Version, "image_ref", k.ImageRef.String())
+	return nil
+}
+
+// serverArgs returns the k3s server flags for the user supplied server and
+// kubelet arguments and feature gates.
+func (k *driver) serverArgs() []string {
+	args := []string{"server"}
+	args = append(args, k.ServerArgs...)
+	for _, arg := range k.KubeletArgs {
+		args = append(args, "--kubelet-arg="+arg)
+	}
+
+	if len(k.FeatureGates) > 0 {
+		gates := make([]string, 0, len(k.FeatureGates))
+		for _, g := range slices.Sorted(maps.Keys(k.FeatureGates)) {
+			gates = append(gates, fmt.Sprintf("%s=%t", g, k.FeatureGates[g]))
+		}
+		fg := "feature-gates=" + strings.Join(gates, ",")
+
+		// Feature gates must agree across components, so set them everywhere.
+		for _, component := range []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler", "kubelet", "kube-proxy"} {
+			args = append(args, fmt.Sprintf("--%s-arg=%s", component, fg))
+		}
+	}
+
+	return args
+}
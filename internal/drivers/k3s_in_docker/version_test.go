@@ -0,0 +1,87 @@
+package k3sindocker
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestResolveVersionTag(t *testing.T) {
+	tags := []string{
+		"latest",
+		"latest-dev",
+		"1.29",
+		"1.29-dev",
+		"1.29.8",
+		"1.29.8-dev",
+		"1.30",
+		"1.30-dev",
+		"1.30.3",
+		"1.30.4",
+		"1.30.4-r1",
+		"1.30.4-dev",
+		"1.30.4-r1-dev",
+		"v1.31.0-k3s1",
+		"v1.31.0-k3s2",
+		"sha256-deadbeef.sig",
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		dev     bool
+		want    string
+		wantErr bool
+	}{
+		{name: "minor", version: "1.30", want: "1.30.4-r1"},
+		{name: "minor dev", version: "1.30", dev: true, want: "1.30.4-r1-dev"},
+		{name: "v prefix", version: "v1.29", want: "1.29.8"},
+		{name: "patch", version: "1.30.3", want: "1.30.3"},
+		{name: "patch with no dev variant", version: "1.30.3", dev: true, wantErr: true},
+		{name: "upstream k3s revisions", version: "1.31", want: "v1.31.0-k3s2"},
+		{name: "unknown minor", version: "1.28", wantErr: true},
+		{name: "invalid", version: "latest", wantErr: true},
+		{name: "build metadata", version: "1.30.4+k3s1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveVersionTag(tags, tt.version, tt.dev)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveVersionTag() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveVersionTag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerArgs(t *testing.T) {
+	k := &driver{
+		ServerArgs:  []string{"--kube-apiserver-arg=v=4"},
+		KubeletArgs: []string{"max-pods=50"},
+		FeatureGates: map[string]bool{
+			"SidecarContainers":         true,
+			"InPlacePodVerticalScaling": false,
+		},
+	}
+
+	want := []string{
+		"server",
+		"--kube-apiserver-arg=v=4",
+		"--kubelet-arg=max-pods=50",
+		"--kube-apiserver-arg=feature-gates=InPlacePodVerticalScaling=false,SidecarContainers=true",
+		"--kube-controller-manager-arg=feature-gates=InPlacePodVerticalScaling=false,SidecarContainers=true",
+		"--kube-scheduler-arg=feature-gates=InPlacePodVerticalScaling=false,SidecarContainers=true",
+		"--kubelet-arg=feature-gates=InPlacePodVerticalScaling=false,SidecarContainers=true",
+		"--kube-proxy-arg=feature-gates=InPlacePodVerticalScaling=false,SidecarContainers=true",
+	}
+
+	if got := k.serverArgs(); !slices.Equal(got, want) {
+		t.Errorf("serverArgs() = %v, want %v", got, want)
+	}
+
+	if got := (&driver{}).serverArgs(); !slices.Equal(got, []string{"server"}) {
+		t.Errorf("serverArgs() = %v, want [server]", got)
+	}
+}
@@ -2,10 +2,12 @@ package lambda
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
@@ -19,18 +21,30 @@ type driver struct {
 	region        string
 	executionRole string
 	functionName  string
+	invocations   []Invocation
 
-	client *lambda.Client
+	client  *lambda.Client
+	results []InvocationResult
 }
 
 // NewDriver creates a new driver for AWS Lambda.
 //
 // This isn't used by the typical imagetest_tests resource, but is instead used by
 // the imagetest_tests_lambda resource. It satisfies the same interface anyway.
-func NewDriver(region, executionRole string) (drivers.Tester, error) {
+//
+// Once the function is active, each of the invocations is run against it. When
+// there are none, the function is invoked once with no payload.
+func NewDriver(region, executionRole string, invocations []Invocation) (drivers.Tester, error) {
+	for _, inv := range invocations {
+		if err := inv.Validate(); err != nil {
+			return nil, fmt.Errorf("invocation %s: %w", inv.Name, err)
+		}
+	}
+
 	return &driver{
 		region:        region,
 		executionRole: executionRole,
+		invocations:   invocations,
 	}, nil
 }
 
@@ -104,18 +118,48 @@ L:
 	clog.FromContext(ctx).Info("Lambda function is active", "name", k.functionName)
 	span.AddEvent("lambda.function.active")
 
-	// Invoke the function to ensure it is ready.
-	if out, err := k.client.Invoke(ctx, &lambda.InvokeInput{FunctionName: &k.functionName}); err != nil {
+	var err error
+	k.results, err = runInvocations(ctx, k.invoke, k.invocations)
+	if err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// InvocationResults implements InvocationReporter.
+func (k *driver) InvocationResults() []InvocationResult {
+	return k.results
+}
+
+// invoke calls the deployed function with the Lambda Invoke API.
+func (k *driver) invoke(ctx context.Context, inv Invocation) (*response, error) {
+	in := &lambda.InvokeInput{
+		FunctionName: &k.functionName,
+		Payload:      inv.Payload,
+		LogType:      types.LogTypeTail,
+	}
+	if len(inv.ClientContext) > 0 {
+		in.ClientContext = aws.String(base64.StdEncoding.EncodeToString(inv.ClientContext))
+	}
+
+	start := time.Now()
+	out, err := k.client.Invoke(ctx, in)
+	if err != nil {
 		return nil, fmt.Errorf("failed to invoke Lambda function: %w", err)
-	} else if out.StatusCode != 200 {
-		return nil, fmt.Errorf("function returned %d: %s", out.StatusCode, string(out.Payload))
-	} else if out.FunctionError != nil {
-		return nil, fmt.Errorf("function returned error: %q: %s", *out.FunctionError, string(out.Payload))
-	} else {
-		if out == nil {
-			return nil, fmt.Errorf("function returned nil output")
+	}
+
+	resp := &response{
+		StatusCode: int(out.StatusCode),
+		Payload:    out.Payload,
+		Duration:   time.Since(start),
+	}
+	if out.FunctionError != nil {
+		resp.FunctionError = *out.FunctionError
+	}
+	if out.LogResult != nil {
+		if logs, err := base64.StdEncoding.DecodeString(*out.LogResult); err == nil {
+			resp.Logs = string(logs)
 		}
-		clog.FromContext(ctx).Info("function invoked successfully", "out", out)
 	}
-	return nil, nil
+	return resp, nil
 }
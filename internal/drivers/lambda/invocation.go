@@ -0,0 +1,264 @@
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// Invocation is a single call of the function under test, and what its
+// response is expected to look like.
+type Invocation struct {
+	Name string
+	// Payload is the JSON event the function is invoked with.
+	Payload []byte
+	// ClientContext is JSON passed to the function as the client context.
+	ClientContext []byte
+	// ExpectStatus is the expected status code of the invocation, 200 if
+	// unset.
+	ExpectStatus int
+	// ExpectFunctionError inverts the default expectation that the function
+	// does not return an error.
+	ExpectFunctionError bool
+	Assertions          []Assertion
+	// MaxDuration fails the invocation if it takes longer, when set.
+	MaxDuration time.Duration
+}
+
+// Assertion checks a value in the response payload. The value is either the
+// whole payload, or the result of JSONPath applied to it.
+type Assertion struct {
+	JSONPath string
+	// Equals is compared to the value exactly, when set.
+	Equals *string
+	// Matches is a regular expression the value must match, when set.
+	Matches string
+}
+
+// Validate checks the invocation is well formed before anything is deployed.
+func (i Invocation) Validate() error {
+	if len(i.Payload) > 0 && !json.Valid(i.Payload) {
+		return fmt.Errorf("payload is not valid JSON")
+	}
+	if len(i.ClientContext) > 0 && !json.Valid(i.ClientContext) {
+		return fmt.Errorf("client context is not valid JSON")
+	}
+	for j, a := range i.Assertions {
+		if a.Equals == nil && a.Matches == "" {
+			return fmt.Errorf("assertion %d: one of equals or matches is required", j)
+		}
+		if a.Matches != "" {
+			if _, err := regexp.Compile(a.Matches); err != nil {
+				return fmt.Errorf("assertion %d: invalid regular expression: %w", j, err)
+			}
+		}
+		if a.JSONPath != "" {
+			if _, err := parseJSONPath(a.JSONPath); err != nil {
+				return fmt.Errorf("assertion %d: %w", j, err)
+			}
+		}
+	}
+	return nil
+}
+
+// response is what the function returned for an invocation.
+type response struct {
+	StatusCode    int
+	FunctionError string
+	Payload       []byte
+	// Logs is the tail of the function's logs, when available.
+	Logs     string
+	Duration time.Duration
+}
+
+// invoker calls the function under test.
+type invoker func(ctx context.Context, inv Invocation) (*response, error)
+
+// InvocationError is a failed invocation.
+type InvocationError struct {
+	Name string
+	Err  error
+	Logs string
+}
+
+func (e *InvocationError) Error() string {
+	msg := fmt.Sprintf("invocation %s: %v", e.Name, e.Err)
+	if e.Logs != "" {
+		msg += "\n\nfunction logs:\n" + e.Logs
+	}
+	return msg
+}
+
+func (e *InvocationError) Unwrap() error { return e.Err }
+
+// InvocationResult is the outcome of an invocation.
+type InvocationResult struct {
+	Name string
+	// StatusCode and Duration are zero when the function couldn't be invoked.
+	StatusCode int
+	Duration   time.Duration
+	// Err is set when the invocation failed.
+	Err *InvocationError
+}
+
+// InvocationReporter is implemented by the Lambda drivers, reporting the
+// result of each invocation once the driver has run.
+type InvocationReporter interface {
+	InvocationResults() []InvocationResult
+}
+
+// InvocationErrors is every failed invocation of a run.
+type InvocationErrors []*InvocationError
+
+func (e InvocationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// defaultInvocations is used when none are configured, and only checks the
+// function can be invoked successfully.
+var defaultInvocations = []Invocation{{Name: "default"}}
+
+// runInvocations invokes the function once per invocation, in order, and
+// returns the result of each, along with an InvocationErrors for those that
+// did not meet their expectations. Every invocation runs even if an earlier
+// one fails.
+func runInvocations(ctx context.Context, invoke invoker, invocations []Invocation) ([]InvocationResult, error) {
+	if len(invocations) == 0 {
+		invocations = defaultInvocations
+	}
+
+	span := trace.SpanFromContext(ctx)
+
+	results := make([]InvocationResult, 0, len(invocations))
+	var errs InvocationErrors
+	for _, inv := range invocations {
+		resp, err := invoke(ctx, inv)
+		if err != nil {
+			ierr := &InvocationError{Name: inv.Name, Err: err}
+			errs = append(errs, ierr)
+			results = append(results, InvocationResult{Name: inv.Name, Err: ierr})
+			continue
+		}
+		result := InvocationResult{Name: inv.Name, StatusCode: resp.StatusCode, Duration: resp.Duration}
+
+		span.AddEvent("lambda.invocation", trace.WithAttributes(
+			attribute.String("invocation.name", inv.Name),
+			attribute.Int("invocation.status", resp.StatusCode),
+			attribute.Int64("invocation.duration_ms", resp.Duration.Milliseconds()),
+		))
+
+		if err := inv.check(resp); err != nil {
+			result.Err = &InvocationError{Name: inv.Name, Err: err, Logs: resp.Logs}
+			errs = append(errs, result.Err)
+			results = append(results, result)
+			continue
+		}
+
+		clog.InfoContext(ctx, "invocation passed", "invocation", inv.Name, "duration", resp.Duration, "payload", string(resp.Payload))
+		results = append(results, result)
+	}
+
+	if len(errs) > 0 {
+		return results, errs
+	}
+	return results, nil
+}
+
+// check evaluates the invocation's expectations against the response.
+func (i Invocation) check(resp *response) error {
+	want := i.ExpectStatus
+	if want == 0 {
+		want = 200
+	}
+	if resp.StatusCode != want {
+		return fmt.Errorf("expected status %d, got %d: %s", want, resp.StatusCode, string(resp.Payload))
+	}
+
+	switch {
+	case i.ExpectFunctionError && resp.FunctionError == "":
+		return fmt.Errorf("expected a function error, got none: %s", string(resp.Payload))
+	case !i.ExpectFunctionError && resp.FunctionError != "":
+		return fmt.Errorf("function returned error: %q: %s", resp.FunctionError, string(resp.Payload))
+	}
+
+	if i.MaxDuration > 0 && resp.Duration > i.MaxDuration {
+		return fmt.Errorf("took %s, longer than the maximum of %s", resp.Duration, i.MaxDuration)
+	}
+
+	var errs []error
+	for _, a := range i.Assertions {
+		if err := a.check(resp.Payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (a Assertion) check(payload []byte) error {
+	value := string(payload)
+	subject := "payload"
+
+	if a.JSONPath != "" {
+		subject = a.JSONPath
+
+		jp, err := parseJSONPath(a.JSONPath)
+		if err != nil {
+			return err
+		}
+
+		var data any
+		if err := json.Unmarshal(payload, &data); err != nil {
+			return fmt.Errorf("%s: payload is not JSON: %w", subject, err)
+		}
+
+		var buf bytes.Buffer
+		if err := jp.Execute(&buf, data); err != nil {
+			return fmt.Errorf("%s: %w", subject, err)
+		}
+		value = buf.String()
+	}
+
+	if a.Equals != nil && value != *a.Equals {
+		return fmt.Errorf("%s: expected %q, got %q", subject, *a.Equals, value)
+	}
+
+	if a.Matches != "" {
+		re, err := regexp.Compile(a.Matches)
+		if err != nil {
+			return fmt.Errorf("%s: invalid regular expression: %w", subject, err)
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("%s: %q does not match %q", subject, value, a.Matches)
+		}
+	}
+
+	return nil
+}
+
+// parseJSONPath accepts both the bare ($.a.b) and kubectl ({.a.b}) forms of
+// JSONPath.
+func parseJSONPath(path string) (*jsonpath.JSONPath, error) {
+	if !strings.HasPrefix(path, "{") {
+		path = "{" + path + "}"
+	}
+
+	jp := jsonpath.New("assertion")
+	if err := jp.Parse(path); err != nil {
+		return nil, fmt.Errorf("invalid json path %q: %w", path, err)
+	}
+	return jp, nil
+}
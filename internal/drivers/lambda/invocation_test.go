@@ -0,0 +1,183 @@
+package lambda
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/utils/ptr"
+)
+
+func TestInvocationCheck(t *testing.T) {
+	payload := []byte(`{"statusCode":200,"body":"hello world","headers":{"content-type":"text/plain"},"items":[1,2]}`)
+
+	tests := []struct {
+		name    string
+		inv     Invocation
+		resp    response
+		wantErr bool
+	}{{
+		name: "defaults",
+		resp: response{StatusCode: 200, Payload: payload},
+	}, {
+		name:    "unexpected status",
+		resp:    response{StatusCode: 500, Payload: payload},
+		wantErr: true,
+	}, {
+		name: "expected status",
+		inv:  Invocation{ExpectStatus: 202},
+		resp: response{StatusCode: 202},
+	}, {
+		name:    "function error",
+		resp:    response{StatusCode: 200, FunctionError: "Unhandled"},
+		wantErr: true,
+	}, {
+		name: "expected function error",
+		inv:  Invocation{ExpectFunctionError: true},
+		resp: response{StatusCode: 200, FunctionError: "Unhandled"},
+	}, {
+		name:    "missing expected function error",
+		inv:     Invocation{ExpectFunctionError: true},
+		resp:    response{StatusCode: 200},
+		wantErr: true,
+	}, {
+		name:    "too slow",
+		inv:     Invocation{MaxDuration: time.Second},
+		resp:    response{StatusCode: 200, Duration: 2 * time.Second},
+		wantErr: true,
+	}, {
+		name: "assertions pass",
+		inv: Invocation{Assertions: []Assertion{
+			{JSONPath: "$.statusCode", Equals: ptr.To("200")},
+			{JSONPath: ".body", Matches: "^hello"},
+			{JSONPath: "{.headers.content-type}", Equals: ptr.To("text/plain")},
+			{JSONPath: "$.items[1]", Equals: ptr.To("2")},
+			{Matches: `"body":"hello world"`},
+		}},
+		resp: response{StatusCode: 200, Payload: payload},
+	}, {
+		name: "equals fails",
+		inv: Invocation{Assertions: []Assertion{
+			{JSONPath: "$.statusCode", Equals: ptr.To("201")},
+		}},
+		resp:    response{StatusCode: 200, Payload: payload},
+		wantErr: true,
+	}, {
+		name: "matches fails",
+		inv: Invocation{Assertions: []Assertion{
+			{JSONPath: "$.body", Matches: "^goodbye"},
+		}},
+		resp:    response{StatusCode: 200, Payload: payload},
+		wantErr: true,
+	}, {
+		name: "missing path",
+		inv: Invocation{Assertions: []Assertion{
+			{JSONPath: "$.nope", Equals: ptr.To("")},
+		}},
+		resp:    response{StatusCode: 200, Payload: payload},
+		wantErr: true,
+	}, {
+		name: "json path on non-json payload",
+		inv: Invocation{Assertions: []Assertion{
+			{JSONPath: "$.body", Matches: "."},
+		}},
+		resp:    response{StatusCode: 200, Payload: []byte("plain text")},
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.inv.check(&tt.resp); (err != nil) != tt.wantErr {
+				t.Errorf("check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInvocationValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		inv     Invocation
+		wantErr bool
+	}{
+		{name: "empty", inv: Invocation{}},
+		{name: "valid", inv: Invocation{Payload: []byte(`{"a":1}`), ClientContext: []byte(`{}`), Assertions: []Assertion{{JSONPath: "$.a", Matches: `\d`}}}},
+		{name: "invalid payload", inv: Invocation{Payload: []byte(`{`)}, wantErr: true},
+		{name: "invalid client context", inv: Invocation{ClientContext: []byte(`nope`)}, wantErr: true},
+		{name: "empty assertion", inv: Invocation{Assertions: []Assertion{{JSONPath: "$.a"}}}, wantErr: true},
+		{name: "invalid regex", inv: Invocation{Assertions: []Assertion{{Matches: "("}}}, wantErr: true},
+		{name: "invalid json path", inv: Invocation{Assertions: []Assertion{{JSONPath: "$.a[", Matches: "."}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.inv.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunInvocations(t *testing.T) {
+	var called []string
+	invoke := func(_ context.Context, inv Invocation) (*response, error) {
+		called = append(called, inv.Name)
+		switch inv.Name {
+		case "broken":
+			return nil, errors.New("connection refused")
+		case "erroring":
+			return &response{StatusCode: 200, FunctionError: "Unhandled", Logs: "panic!"}, nil
+		default:
+			return &response{StatusCode: 200}, nil
+		}
+	}
+
+	results, err := runInvocations(context.Background(), invoke, []Invocation{
+		{Name: "ok"},
+		{Name: "broken"},
+		{Name: "erroring"},
+		{Name: "ok-again"},
+	})
+
+	if want := []string{"ok", "broken", "erroring", "ok-again"}; len(called) != len(want) {
+		t.Fatalf("invoked %v, want %v", called, want)
+	}
+
+	var errs InvocationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("runInvocations() error = %v, want InvocationErrors", err)
+	}
+	if len(errs) != 2 || errs[0].Name != "broken" || errs[1].Name != "erroring" {
+		t.Fatalf("runInvocations() errors = %v", errs)
+	}
+	if errs[1].Logs != "panic!" {
+		t.Errorf("runInvocations() logs = %q, want %q", errs[1].Logs, "panic!")
+	}
+
+	if len(results) != 4 {
+		t.Fatalf("runInvocations() results = %v, want 4", results)
+	}
+	for i, want := range []struct {
+		name   string
+		status int
+		failed bool
+	}{
+		{"ok", 200, false},
+		{"broken", 0, true},
+		{"erroring", 200, true},
+		{"ok-again", 200, false},
+	} {
+		if got := results[i]; got.Name != want.name || got.StatusCode != want.status || (got.Err != nil) != want.failed {
+			t.Errorf("runInvocations() results[%d] = %+v, want %+v", i, got, want)
+		}
+	}
+
+	called = nil
+	if _, err := runInvocations(context.Background(), invoke, nil); err != nil {
+		t.Errorf("runInvocations() default error = %v", err)
+	}
+	if len(called) != 1 || called[0] != "default" {
+		t.Errorf("runInvocations() default invoked %v", called)
+	}
+}
@@ -0,0 +1,366 @@
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/docker"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/drivers"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/harness"
+	"github.com/docker/go-connections/nat"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// emulatorReleaseURL is where the Runtime Interface Emulator binaries of a
+	// release are published.
+	emulatorReleaseURL = "https://github.com/aws/aws-lambda-runtime-interface-emulator/releases/download/%s/%s"
+
+	// DefaultEmulatorVersion is the Runtime Interface Emulator release that's
+	// downloaded when no other is asked for.
+	DefaultEmulatorVersion = "v1.20"
+
+	// emulatorPath is where the emulator is copied to in the function's
+	// container.
+	emulatorPath = "/aws-lambda-rie"
+
+	// emulatorPort is the port the emulator serves the invoke API on.
+	emulatorPort = "8080/tcp"
+
+	// invokePath is the emulator's equivalent of the Lambda Invoke API.
+	invokePath = "/2015-03-31/functions/function/invocations"
+
+	// localReadyTimeout bounds how long we wait for the emulator to accept
+	// invocations.
+	localReadyTimeout = 30 * time.Second
+)
+
+// Emulator locates the Runtime Interface Emulator binary to run the function
+// under.
+type Emulator struct {
+	// Path is an aws-lambda-rie binary on the host. When empty, the binary is
+	// downloaded from the Version release and cached.
+	Path string
+	// Version is the release to download, defaulting to DefaultEmulatorVersion.
+	Version string
+	// SHA256 is the hex encoded checksum the downloaded binary must have. It's
+	// required when Path is empty.
+	SHA256 string
+}
+
+type localDriver struct {
+	emulator    Emulator
+	invocations []Invocation
+	ropts       []remote.Option
+
+	cli     *docker.Client
+	stack   *harness.Stack
+	results []InvocationResult
+}
+
+// NewLocalDriver creates a driver that runs the function's image in a local
+// container under the AWS Lambda Runtime Interface Emulator, rather than
+// deploying it to AWS. No AWS credentials are required.
+//
+// The emulator binary is either read from the host or downloaded from a pinned
+// release and checked against its expected checksum.
+func NewLocalDriver(emulator Emulator, invocations []Invocation, ropts ...remote.Option) (drivers.Tester, error) {
+	for _, inv := range invocations {
+		if err := inv.Validate(); err != nil {
+			return nil, fmt.Errorf("invocation %s: %w", inv.Name, err)
+		}
+	}
+
+	if emulator.Path == "" && emulator.SHA256 == "" {
+		return nil, fmt.Errorf("downloading the runtime interface emulator requires its sha256 checksum")
+	}
+	if emulator.Version == "" {
+		emulator.Version = DefaultEmulatorVersion
+	}
+
+	return &localDriver{
+		emulator:    emulator,
+		invocations: invocations,
+		ropts: append([]remote.Option{
+			remote.WithAuthFromKeychain(authn.DefaultKeychain),
+			remote.WithPlatform(ggcrv1.Platform{
+				OS:           "linux",
+				Architecture: runtime.GOARCH,
+			}),
+		}, ropts...),
+		stack: harness.NewStack(),
+	}, nil
+}
+
+func (k *localDriver) Setup(ctx context.Context) error {
+	cli, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	k.cli = cli
+	return nil
+}
+
+func (k *localDriver) Teardown(ctx context.Context) error {
+	if v := os.Getenv("IMAGETEST_LAMBDA_SKIP_TEARDOWN"); v == "true" {
+		clog.FromContext(ctx).Info("Skipping Lambda teardown due to IMAGETEST_LAMBDA_SKIP_TEARDOWN=true")
+		return nil
+	}
+	return k.stack.Teardown(ctx)
+}
+
+func (k *localDriver) Run(ctx context.Context, ref name.Reference) (*drivers.RunResult, error) {
+	dig, ok := ref.(name.Digest)
+	if !ok {
+		return nil, fmt.Errorf("expected digest reference, got %T %q", ref, ref)
+	}
+
+	img, err := remote.Image(ref, append(k.ropts, remote.WithContext(ctx))...)
+	if err != nil {
+		return nil, fmt.Errorf("fetching image: %w", err)
+	}
+
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("getting image config: %w", err)
+	}
+
+	rie, err := k.emulatorBinary(ctx, cfg.Architecture)
+	if err != nil {
+		return nil, fmt.Errorf("getting runtime interface emulator: %w", err)
+	}
+
+	// The emulator wraps the image's own entrypoint, which is either the
+	// function's bootstrap or, for the AWS base images, a script that execs it.
+	cmd := slices.Concat(cfg.Config.Entrypoint, cfg.Config.Cmd)
+	if len(cmd) == 0 {
+		return nil, fmt.Errorf("image has no entrypoint or cmd to run as the function")
+	}
+
+	cname := fmt.Sprintf("imagetest-lambda-%s-%s", dig.DigestStr()[7:15], uuid.New().String()[:8])
+	if err := k.stack.Add(func(ctx context.Context) error {
+		return k.cli.Remove(ctx, &docker.Response{ID: cname})
+	}); err != nil {
+		return nil, err
+	}
+
+	clog.InfoContext(ctx, "starting Lambda function under the runtime interface emulator", "image_ref", ref.String(), "container_name", cname)
+	resp, err := k.cli.Start(ctx, &docker.Request{
+		Name:       cname,
+		Ref:        ref,
+		Entrypoint: []string{emulatorPath},
+		Cmd:        cmd,
+		Contents: []*docker.Content{
+			docker.NewContentWithMode(bytes.NewReader(rie), emulatorPath, int64(len(rie)), 0o755),
+		},
+		PortBindings: nat.PortMap{
+			emulatorPort: []nat.PortBinding{{
+				HostIP:   "127.0.0.1",
+				HostPort: "", // Lets the docker daemon pick a random port
+			}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("starting function container: %w", err)
+	}
+	span := trace.SpanFromContext(ctx)
+	span.AddEvent("lambda.local.started")
+
+	binding, cleanup, err := resp.PortBinding(emulatorPort)
+	if err != nil {
+		return nil, fmt.Errorf("getting emulator port: %w", err)
+	}
+	if err := k.stack.Add(func(ctx context.Context) error {
+		cleanup()
+		return nil
+	}); err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("http://%s:%s%s", binding.HostIP, binding.HostPort, invokePath)
+
+	k.results, err = runInvocations(ctx, func(ctx context.Context, inv Invocation) (*response, error) {
+		return invokeLocal(ctx, endpoint, inv)
+	}, k.invocations)
+	if err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// InvocationResults implements InvocationReporter.
+func (k *localDriver) InvocationResults() []InvocationResult {
+	return k.results
+}
+
+// invokeLocal invokes the function through the emulator at endpoint. The
+// emulator only starts the function on the first invocation, and may not be
+// listening yet, so requests that never got a response are retried.
+func invokeLocal(ctx context.Context, endpoint string, inv Invocation) (*response, error) {
+	payload := inv.Payload
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+
+	var (
+		resp    *response
+		lastErr error
+	)
+	if err := wait.PollUntilContextTimeout(ctx, 500*time.Millisecond, localReadyTimeout, true, func(ctx context.Context) (bool, error) {
+		resp, lastErr = postInvocation(ctx, endpoint, payload, inv.ClientContext)
+		if lastErr != nil {
+			clog.DebugContext(ctx, "waiting for runtime interface emulator", "error", lastErr)
+			return false, nil
+		}
+		return true, nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to invoke Lambda function: %w: last error: %w", err, lastErr)
+	}
+	return resp, nil
+}
+
+func postInvocation(ctx context.Context, endpoint string, payload, clientContext []byte) (*response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(clientContext) > 0 {
+		req.Header.Set("X-Amz-Client-Context", base64.StdEncoding.EncodeToString(clientContext))
+	}
+
+	start := time.Now()
+	hresp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer hresp.Body.Close()
+
+	body, err := io.ReadAll(hresp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	resp := &response{
+		StatusCode:    hresp.StatusCode,
+		FunctionError: hresp.Header.Get("X-Amz-Function-Error"),
+		Payload:       body,
+		Duration:      time.Since(start),
+	}
+
+	// Unlike the Lambda API, the emulator does not always set the function
+	// error header, so also look for the error shape in the payload.
+	if resp.FunctionError == "" {
+		var ferr struct {
+			ErrorType string `json:"errorType"`
+		}
+		if json.Unmarshal(body, &ferr) == nil && ferr.ErrorType != "" {
+			resp.FunctionError = "Unhandled"
+		}
+	}
+
+	return resp, nil
+}
+
+// emulatorBinary returns the Runtime Interface Emulator binary for the given
+// architecture, downloading it if one wasn't provided.
+func (k *localDriver) emulatorBinary(ctx context.Context, arch string) ([]byte, error) {
+	if k.emulator.Path != "" {
+		return os.ReadFile(k.emulator.Path)
+	}
+
+	var asset string
+	switch arch {
+	case "amd64", "":
+		asset = "aws-lambda-rie"
+	case "arm64":
+		asset = "aws-lambda-rie-arm64"
+	default:
+		return nil, fmt.Errorf("no runtime interface emulator available for architecture %q", arch)
+	}
+
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		cache = os.TempDir()
+	}
+	cached := filepath.Join(cache, "imagetest", "aws-lambda-rie", k.emulator.Version, asset)
+	if data, err := os.ReadFile(cached); err == nil {
+		if verifyChecksum(data, k.emulator.SHA256) == nil {
+			return data, nil
+		}
+		clog.WarnContext(ctx, "ignoring cached runtime interface emulator with a mismatched checksum", "path", cached)
+	}
+
+	clog.InfoContext(ctx, "downloading runtime interface emulator", "asset", asset, "version", k.emulator.Version)
+	data, err := downloadEmulator(ctx, fmt.Sprintf(emulatorReleaseURL, k.emulator.Version, asset), k.emulator.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("downloading %s %s: %w", asset, k.emulator.Version, err)
+	}
+
+	// Caching is best effort, a failure only costs us a download next time.
+	if err := os.MkdirAll(filepath.Dir(cached), 0o755); err == nil {
+		if err := os.WriteFile(cached, data, 0o755); err != nil {
+			clog.WarnContext(ctx, "failed to cache runtime interface emulator", "error", err)
+		}
+	}
+
+	return data, nil
+}
+
+// downloadEmulator downloads the binary at url, failing unless it has the
+// expected sha256 checksum.
+func downloadEmulator(ctx context.Context, url string, checksum string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyChecksum(data, checksum); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func verifyChecksum(data []byte, checksum string) error {
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, checksum) {
+		return fmt.Errorf("checksum mismatch: got sha256 %s, want %s", got, checksum)
+	}
+	return nil
+}
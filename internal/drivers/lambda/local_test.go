@@ -0,0 +1,104 @@
+package lambda
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInvokeLocal(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		inv       Invocation
+		want      string
+		wantError string
+	}{{
+		name: "success",
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			_, _ = w.Write(body)
+		},
+		inv:  Invocation{Payload: []byte(`{"hello":"world"}`)},
+		want: `{"hello":"world"}`,
+	}, {
+		name: "default payload",
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			_, _ = w.Write(body)
+		},
+		want: `{}`,
+	}, {
+		name: "client context",
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			cc, _ := base64.StdEncoding.DecodeString(r.Header.Get("X-Amz-Client-Context"))
+			_, _ = w.Write(cc)
+		},
+		inv:  Invocation{ClientContext: []byte(`{"custom":{"a":"b"}}`)},
+		want: `{"custom":{"a":"b"}}`,
+	}, {
+		name: "function error header",
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Amz-Function-Error", "Handled")
+			_, _ = w.Write([]byte(`"boom"`))
+		},
+		want:      `"boom"`,
+		wantError: "Handled",
+	}, {
+		name: "function error payload",
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"errorType":"Runtime.ExitError","errorMessage":"exit status 1"}`))
+		},
+		want:      `{"errorType":"Runtime.ExitError","errorMessage":"exit status 1"}`,
+		wantError: "Unhandled",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+
+			got, err := invokeLocal(context.Background(), srv.URL+invokePath, tt.inv)
+			if err != nil {
+				t.Fatalf("invokeLocal() error = %v", err)
+			}
+			if got.StatusCode != http.StatusOK {
+				t.Errorf("invokeLocal() status = %d, want %d", got.StatusCode, http.StatusOK)
+			}
+			if string(got.Payload) != tt.want {
+				t.Errorf("invokeLocal() payload = %q, want %q", got.Payload, tt.want)
+			}
+			if got.FunctionError != tt.wantError {
+				t.Errorf("invokeLocal() function error = %q, want %q", got.FunctionError, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestDownloadEmulator(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("rie"))
+	}))
+	defer srv.Close()
+
+	sum := sha256.Sum256([]byte("rie"))
+	want := hex.EncodeToString(sum[:])
+
+	data, err := downloadEmulator(t.Context(), srv.URL, strings.ToUpper(want))
+	if err != nil {
+		t.Fatalf("downloadEmulator() = %v", err)
+	}
+	if string(data) != "rie" {
+		t.Errorf("downloadEmulator() = %q, want rie", data)
+	}
+
+	if _, err := downloadEmulator(t.Context(), srv.URL, strings.Repeat("0", 64)); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("downloadEmulator() with the wrong checksum = %v, want a checksum mismatch", err)
+	}
+}
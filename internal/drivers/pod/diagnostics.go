@@ -0,0 +1,277 @@
+package pod
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// DiagnosticsDir is the directory within the artifact bundle that cluster
+	// diagnostics are written to.
+	DiagnosticsDir = "diagnostics"
+
+	// diagnosticsTimeout bounds how long we spend snapshotting the cluster.
+	diagnosticsTimeout = 2 * time.Minute
+
+	// diagnosticsLogLimit caps the logs collected per container.
+	diagnosticsLogLimit = 256 * 1024
+)
+
+// systemNamespaces are never considered test namespaces.
+var systemNamespaces = []string{
+	"kube-system",
+	"kube-public",
+	"kube-node-lease",
+}
+
+// collectDiagnostics snapshots the state of the sandbox's namespace and the
+// namespaces the test created, roughly what a user would gather by hand with
+// kubectl get events, get -o yaml and logs. Namespaces that existed before the
+// sandbox pod are left alone, since on shared clusters they belong to
+// unrelated workloads. Collection is best effort: individual failures are
+// recorded in the snapshot rather than aborting it. The returned map is keyed
+// by the file's path within the artifact bundle.
+func collectDiagnostics(ctx context.Context, cli kubernetes.Interface, sandbox *corev1.Pod) map[string][]byte {
+	// The caller's context has likely been cancelled if the test timed out, so
+	// detach from it to give ourselves a fighting chance at collecting anything.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), diagnosticsTimeout)
+	defer cancel()
+
+	files := make(map[string][]byte)
+
+	for _, ns := range testNamespaces(ctx, cli, sandbox, files) {
+		d := namespaceDiagnostics{
+			cli:   cli,
+			ns:    ns,
+			files: files,
+		}
+		d.collect(ctx)
+	}
+
+	clog.InfoContext(ctx, "collected cluster diagnostics", "files", len(files))
+	return files
+}
+
+// testNamespaces returns the sandbox's namespace followed by the non-system
+// namespaces created since the sandbox pod was. The sandbox may not be allowed
+// to list namespaces, in which case only its own is returned.
+func testNamespaces(ctx context.Context, cli kubernetes.Interface, sandbox *corev1.Pod, files map[string][]byte) []string {
+	namespaces := []string{sandbox.Namespace}
+
+	nsl, err := cli.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		files[path.Join(DiagnosticsDir, "errors.txt")] = fmt.Appendf(nil, "failed to list namespaces: %v\n", err)
+		return namespaces
+	}
+
+	for _, ns := range nsl.Items {
+		if ns.Name == sandbox.Namespace || slices.Contains(systemNamespaces, ns.Name) {
+			continue
+		}
+		if ns.CreationTimestamp.Before(&sandbox.CreationTimestamp) {
+			continue
+		}
+		namespaces = append(namespaces, ns.Name)
+	}
+	return namespaces
+}
+
+type namespaceDiagnostics struct {
+	cli   kubernetes.Interface
+	ns    string
+	files map[string][]byte
+}
+
+func (d *namespaceDiagnostics) put(name string, data []byte) {
+	d.files[path.Join(DiagnosticsDir, d.ns, name)] = data
+}
+
+func (d *namespaceDiagnostics) collect(ctx context.Context) {
+	d.events(ctx)
+
+	pods, err := d.cli.CoreV1().Pods(d.ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		d.put("pods.txt", fmt.Appendf(nil, "failed to list pods: %v\n", err))
+		return
+	}
+	d.put("pods.txt", podStatuses(pods.Items))
+
+	for _, p := range pods.Items {
+		d.object("pod", &p)
+		d.logs(ctx, p)
+	}
+
+	// Workloads are the usual reason a test fails to become ready, so capture
+	// their full spec and status as well.
+	if deps, err := d.cli.AppsV1().Deployments(d.ns).List(ctx, metav1.ListOptions{}); err == nil {
+		for _, dep := range deps.Items {
+			d.object("deployment", &dep)
+		}
+	}
+
+	if sts, err := d.cli.AppsV1().StatefulSets(d.ns).List(ctx, metav1.ListOptions{}); err == nil {
+		for _, s := range sts.Items {
+			d.object("statefulset", &s)
+		}
+	}
+
+	if dss, err := d.cli.AppsV1().DaemonSets(d.ns).List(ctx, metav1.ListOptions{}); err == nil {
+		for _, ds := range dss.Items {
+			d.object("daemonset", &ds)
+		}
+	}
+
+	if svcs, err := d.cli.CoreV1().Services(d.ns).List(ctx, metav1.ListOptions{}); err == nil {
+		for _, svc := range svcs.Items {
+			d.object("service", &svc)
+		}
+	}
+}
+
+func (d *namespaceDiagnostics) events(ctx context.Context) {
+	el, err := d.cli.CoreV1().Events(d.ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		d.put("events.txt", fmt.Appendf(nil, "failed to list events: %v\n", err))
+		return
+	}
+
+	events := el.Items
+	slices.SortStableFunc(events, func(a, b corev1.Event) int {
+		return eventTime(a).Compare(eventTime(b))
+	})
+
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "LAST SEEN\tTYPE\tREASON\tOBJECT\tCOUNT\tMESSAGE")
+	for _, e := range events {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s/%s\t%d\t%s\n",
+			eventTime(e).Format(time.RFC3339),
+			e.Type,
+			e.Reason,
+			strings.ToLower(e.InvolvedObject.Kind),
+			e.InvolvedObject.Name,
+			e.Count,
+			strings.TrimSpace(e.Message),
+		)
+	}
+	_ = tw.Flush()
+
+	d.put("events.txt", buf.Bytes())
+}
+
+// object writes the full object, including its status, much like kubectl get
+// -o yaml. Managed fields are dropped since they only add noise.
+func (d *namespaceDiagnostics) object(kind string, obj metav1.Object) {
+	obj.SetManagedFields(nil)
+
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		data = fmt.Appendf(nil, "failed to marshal %s: %v\n", kind, err)
+	}
+	d.put(path.Join("objects", kind, obj.GetName()+".yaml"), data)
+}
+
+func (d *namespaceDiagnostics) logs(ctx context.Context, p corev1.Pod) {
+	statuses := slices.Concat(p.Status.InitContainerStatuses, p.Status.ContainerStatuses)
+	for _, cs := range statuses {
+		d.put(path.Join("logs", p.Name, cs.Name+".log"), d.containerLogs(ctx, p, cs.Name, false))
+
+		// Crash looping containers rarely have anything useful in their current
+		// instance, so grab the previous one as well.
+		if cs.RestartCount > 0 {
+			d.put(path.Join("logs", p.Name, cs.Name+".previous.log"), d.containerLogs(ctx, p, cs.Name, true))
+		}
+	}
+}
+
+func (d *namespaceDiagnostics) containerLogs(ctx context.Context, p corev1.Pod, container string, previous bool) []byte {
+	rc, err := d.cli.CoreV1().Pods(p.Namespace).GetLogs(p.Name, &corev1.PodLogOptions{
+		Container:  container,
+		Previous:   previous,
+		LimitBytes: ptr.To(int64(diagnosticsLogLimit)),
+	}).Stream(ctx)
+	if err != nil {
+		return fmt.Appendf(nil, "failed to get logs: %v\n", err)
+	}
+	defer rc.Close()
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, rc); err != nil {
+		fmt.Fprintf(&buf, "\nfailed to copy logs: %v\n", err)
+	}
+	return buf.Bytes()
+}
+
+// podStatuses renders a kubectl get pods -o wide style summary, with a line per
+// container describing its current state.
+func podStatuses(pods []corev1.Pod) []byte {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tPHASE\tNODE\tCONTAINER\tREADY\tRESTARTS\tSTATE")
+	for _, p := range pods {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t\t\t\t%s\n", p.Name, p.Status.Phase, p.Spec.NodeName, podConditions(p))
+
+		statuses := slices.Concat(p.Status.InitContainerStatuses, p.Status.ContainerStatuses)
+		for _, cs := range statuses {
+			fmt.Fprintf(tw, "\t\t\t%s\t%t\t%d\t%s\n", cs.Name, cs.Ready, cs.RestartCount, containerState(cs.State))
+		}
+	}
+	_ = tw.Flush()
+	return buf.Bytes()
+}
+
+func podConditions(p corev1.Pod) string {
+	var failing []string
+	for _, c := range p.Status.Conditions {
+		if c.Status == corev1.ConditionTrue {
+			continue
+		}
+		msg := string(c.Type)
+		if c.Reason != "" {
+			msg += ": " + c.Reason
+		}
+		if c.Message != "" {
+			msg += " (" + c.Message + ")"
+		}
+		failing = append(failing, msg)
+	}
+	return strings.Join(failing, "; ")
+}
+
+func containerState(s corev1.ContainerState) string {
+	switch {
+	case s.Waiting != nil:
+		return strings.TrimSpace(fmt.Sprintf("waiting: %s %s", s.Waiting.Reason, s.Waiting.Message))
+	case s.Running != nil:
+		return fmt.Sprintf("running since %s", s.Running.StartedAt.Format(time.RFC3339))
+	case s.Terminated != nil:
+		return strings.TrimSpace(fmt.Sprintf("terminated: %s exit_code=%d %s", s.Terminated.Reason, s.Terminated.ExitCode, s.Terminated.Message))
+	default:
+		return "unknown"
+	}
+}
+
+func eventTime(e corev1.Event) time.Time {
+	switch {
+	case !e.LastTimestamp.IsZero():
+		return e.LastTimestamp.Time
+	case !e.EventTime.IsZero():
+		return e.EventTime.Time
+	default:
+		return e.CreationTimestamp.Time
+	}
+}
@@ -0,0 +1,87 @@
+package pod
+
+import (
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCollectDiagnostics(t *testing.T) {
+	started := metav1.NewTime(time.Now().Add(-time.Minute))
+	sandbox := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "sandbox", Namespace: "imagetest", CreationTimestamp: started},
+	}
+
+	client := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", CreationTimestamp: metav1.NewTime(started.Add(time.Second))}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "imagetest", CreationTimestamp: metav1.NewTime(started.Add(-time.Hour))}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "app", CreationTimestamp: metav1.NewTime(started.Add(time.Second))}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", CreationTimestamp: metav1.NewTime(started.Add(-time.Hour))}},
+		sandbox,
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: "kube-system"},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "unrelated"},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "app"},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodPending,
+				ContainerStatuses: []corev1.ContainerStatus{
+					{
+						Name:         "nginx",
+						RestartCount: 2,
+						State: corev1.ContainerState{
+							Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+						},
+					},
+				},
+			},
+		},
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "web.1", Namespace: "app"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "web", Namespace: "app"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "BackOff",
+			Message:        "Back-off restarting failed container",
+		},
+	)
+
+	files := collectDiagnostics(t.Context(), client, sandbox)
+
+	for _, want := range []string{
+		"app/events.txt",
+		"app/pods.txt",
+		"app/objects/pod/web.yaml",
+		"app/logs/web/nginx.log",
+		"app/logs/web/nginx.previous.log",
+		"imagetest/objects/pod/sandbox.yaml",
+	} {
+		if _, ok := files[path.Join(DiagnosticsDir, want)]; !ok {
+			t.Errorf("expected diagnostics to contain %s", want)
+		}
+	}
+
+	for name := range files {
+		if strings.HasPrefix(name, path.Join(DiagnosticsDir, "kube-system")) {
+			t.Errorf("expected system namespaces to be skipped, found %s", name)
+		}
+		if strings.HasPrefix(name, path.Join(DiagnosticsDir, "unrelated")) {
+			t.Errorf("expected namespaces predating the sandbox to be skipped, found %s", name)
+		}
+	}
+
+	if got := string(files[path.Join(DiagnosticsDir, "app", "events.txt")]); !strings.Contains(got, "Back-off restarting failed container") {
+		t.Errorf("expected events to contain the pod event, got:\n%s", got)
+	}
+
+	if got := string(files[path.Join(DiagnosticsDir, "app", "pods.txt")]); !strings.Contains(got, "waiting: CrashLoopBackOff") {
+		t.Errorf("expected pod statuses to contain the container state, got:\n%s", got)
+	}
+}
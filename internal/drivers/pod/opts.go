@@ -5,6 +5,7 @@ import (
 
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/docker"
 	"github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
 )
 
 func WithImageRef(ref name.Reference) RunOpts {
@@ -44,3 +45,58 @@ func WithRegistryStaticAuth(cfg *docker.DockerConfig) RunOpts {
 		return nil
 	}
 }
+
+func WithRBAC(rbac RBAC) RunOpts {
+	return func(o *opts) error {
+		if err := rbac.Validate(); err != nil {
+			return err
+		}
+		if rbac.Mode == "" {
+			rbac.Mode = RBACModeClusterAdmin
+		}
+		o.RBAC = rbac
+		return nil
+	}
+}
+
+func WithResources(resources corev1.ResourceRequirements) RunOpts {
+	return func(o *opts) error {
+		o.Resources = resources
+		return nil
+	}
+}
+
+func WithNodeSelector(selector map[string]string) RunOpts {
+	return func(o *opts) error {
+		maps.Copy(o.NodeSelector, selector)
+		return nil
+	}
+}
+
+func WithTolerations(tolerations ...corev1.Toleration) RunOpts {
+	return func(o *opts) error {
+		o.Tolerations = append(o.Tolerations, tolerations...)
+		return nil
+	}
+}
+
+func WithAffinity(affinity *corev1.Affinity) RunOpts {
+	return func(o *opts) error {
+		o.Affinity = affinity
+		return nil
+	}
+}
+
+func WithRuntimeClassName(name string) RunOpts {
+	return func(o *opts) error {
+		o.RuntimeClassName = name
+		return nil
+	}
+}
+
+func WithSecurityContext(sc *corev1.SecurityContext) RunOpts {
+	return func(o *opts) error {
+		o.SecurityContext = sc
+		return nil
+	}
+}
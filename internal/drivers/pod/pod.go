@@ -21,12 +21,10 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	authv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
-	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/watch"
 	corev1apply "k8s.io/client-go/applyconfigurations/core/v1"
-	rbacv1apply "k8s.io/client-go/applyconfigurations/rbac/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/remotecommand"
@@ -55,6 +53,19 @@ type opts struct {
 	// pod. Despite its name, its not for docker, but for clients that can
 	// leverage creds in the known ~/.docker/config.json location.
 	DockerConfig *docker.DockerConfig
+	// RBAC is the access granted to the pod's service account.
+	RBAC RBAC
+
+	// Scheduling and resource controls for the sandbox container.
+	Resources        corev1.ResourceRequirements
+	NodeSelector     map[string]string
+	Tolerations      []corev1.Toleration
+	Affinity         *corev1.Affinity
+	RuntimeClassName string
+	// SecurityContext is merged over the sandbox container's default
+	// privileged, root security context. Only the fields that are set are
+	// overridden.
+	SecurityContext *corev1.SecurityContext
 
 	client kubernetes.Interface
 	cfg    *rest.Config
@@ -73,9 +84,11 @@ func Run(ctx context.Context, kcfg *rest.Config, options ...RunOpts) (*drivers.R
 		},
 		ExtraAnnotations:          map[string]string{},
 		ServiceAccountAnnotations: map[string]string{},
+		NodeSelector:              map[string]string{},
 		ExtraEnvs: map[string]string{
 			"IMAGETEST": "true",
 		},
+		RBAC: RBAC{Mode: RBACModeClusterAdmin},
 
 		cfg: kcfg,
 	}
@@ -129,11 +142,32 @@ func Run(ctx context.Context, kcfg *rest.Config, options ...RunOpts) (*drivers.R
 
 	monitorErr := monitor(ctx, o.client, pobj)
 
+	// Snapshot the cluster before exporting the artifact, since the export
+	// resumes the entrypoint and lets the sandbox tear itself down.
+	var diagnostics map[string][]byte
+	if monitorErr != nil {
+		diagnostics = collectDiagnostics(ctx, o.client, pobj)
+	}
+
 	result := &drivers.RunResult{Artifact: &drivers.RunArtifactResult{}}
 	if err := o.getArtifact(ctx, pobj, result); err != nil {
 		clog.ErrorContext(ctx, "failed to get artifact", "error", err)
 	} else {
 		span.AddEvent("pod.artifact.retrieved")
+
+		if err := result.ReadArtifactReports(); err != nil {
+			clog.WarnContext(ctx, "failed to read artifact reports", "error", err)
+		}
+	}
+
+	if len(diagnostics) > 0 {
+		artifact, err := drivers.AppendRunArtifactFiles(ctx, result.Artifact, diagnostics)
+		if err != nil {
+			clog.ErrorContext(ctx, "failed to add diagnostics to artifact", "error", err)
+		} else {
+			result.Artifact = artifact
+			span.AddEvent("pod.diagnostics.collected")
+		}
 	}
 
 	return result, monitorErr
@@ -159,6 +193,10 @@ func (o *opts) preflight(ctx context.Context) error {
 		return fmt.Errorf("user does not have permission to create pods in the %s namespace", o.Namespace)
 	}
 
+	if err := o.validateRBAC(ctx); err != nil {
+		return err
+	}
+
 	nsa := corev1apply.Namespace(o.Namespace).WithName(o.Namespace)
 	if _, err := o.client.CoreV1().Namespaces().Apply(ctx, nsa, metav1.ApplyOptions{
 		FieldManager: "imagetest",
@@ -177,24 +215,8 @@ func (o *opts) preflight(ctx context.Context) error {
 		return fmt.Errorf("failed to apply service account: %w", err)
 	}
 
-	// Create the role binding
-	crba := rbacv1apply.ClusterRoleBinding(o.Name).
-		WithName(o.Name).
-		WithSubjects(&rbacv1apply.SubjectApplyConfiguration{
-			Kind:      ptr.To(rbacv1.ServiceAccountKind),
-			Name:      ptr.To(o.Name),
-			Namespace: ptr.To(o.Namespace),
-		}).
-		WithRoleRef(&rbacv1apply.RoleRefApplyConfiguration{
-			APIGroup: ptr.To(rbacv1.GroupName),
-			Kind:     ptr.To("ClusterRole"),
-			Name:     ptr.To("cluster-admin"),
-		})
-	if _, err := o.client.RbacV1().ClusterRoleBindings().Apply(ctx, crba, metav1.ApplyOptions{
-		FieldManager: "imagetest",
-		Force:        true,
-	}); err != nil {
-		return fmt.Errorf("failed to apply cluster role binding: %w", err)
+	if err := o.applyRBAC(ctx); err != nil {
+		return err
 	}
 
 	if o.DockerConfig != nil {
@@ -614,6 +636,19 @@ func (o *opts) pod() *corev1.Pod {
 		},
 	}
 
+	sandbox := &pod.Spec.Containers[0]
+	sandbox.Resources = o.Resources
+	mergeSecurityContext(sandbox.SecurityContext, o.SecurityContext)
+
+	if len(o.NodeSelector) > 0 {
+		pod.Spec.NodeSelector = maps.Clone(o.NodeSelector)
+	}
+	pod.Spec.Tolerations = o.Tolerations
+	pod.Spec.Affinity = o.Affinity
+	if o.RuntimeClassName != "" {
+		pod.Spec.RuntimeClassName = ptr.To(o.RuntimeClassName)
+	}
+
 	maps.Copy(pod.Labels, o.ExtraLabels)
 
 	maps.Copy(pod.Annotations, o.ExtraAnnotations)
@@ -654,6 +689,37 @@ func (o *opts) pod() *corev1.Pod {
 	return pod
 }
 
+// mergeSecurityContext overrides the fields of dst that are set in src.
+func mergeSecurityContext(dst, src *corev1.SecurityContext) {
+	if src == nil {
+		return
+	}
+	if src.Privileged != nil {
+		dst.Privileged = src.Privileged
+	}
+	if src.RunAsUser != nil {
+		dst.RunAsUser = src.RunAsUser
+	}
+	if src.RunAsGroup != nil {
+		dst.RunAsGroup = src.RunAsGroup
+	}
+	if src.RunAsNonRoot != nil {
+		dst.RunAsNonRoot = src.RunAsNonRoot
+	}
+	if src.ReadOnlyRootFilesystem != nil {
+		dst.ReadOnlyRootFilesystem = src.ReadOnlyRootFilesystem
+	}
+	if src.AllowPrivilegeEscalation != nil {
+		dst.AllowPrivilegeEscalation = src.AllowPrivilegeEscalation
+	}
+	if src.Capabilities != nil {
+		dst.Capabilities = src.Capabilities
+	}
+	if src.SeccompProfile != nil {
+		dst.SeccompProfile = src.SeccompProfile
+	}
+}
+
 func (o *opts) getArtifact(ctx context.Context, pod *corev1.Pod, result *drivers.RunResult) error {
 	req := o.client.CoreV1().RESTClient().Post().
 		Resource("pods").
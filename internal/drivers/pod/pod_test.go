@@ -11,11 +11,14 @@ import (
 
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/entrypoint"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes/fake"
 	ktesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
 )
 
 func TestMonitor(t *testing.T) {
@@ -595,3 +598,66 @@ func TestPodMonitorErrorLogs(t *testing.T) {
 		}
 	}
 }
+
+func TestPodSchedulingOptions(t *testing.T) {
+	o := &opts{
+		Name:         "imagetest",
+		Namespace:    "imagetest",
+		ImageRef:     name.MustParseReference("cgr.dev/chainguard/kubectl:latest-dev"),
+		ExtraLabels:  map[string]string{},
+		NodeSelector: map[string]string{},
+	}
+
+	for _, opt := range []RunOpts{
+		WithNodeSelector(map[string]string{"kubernetes.io/arch": "arm64"}),
+		WithTolerations(corev1.Toleration{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}),
+		WithRuntimeClassName("nvidia"),
+		WithResources(corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{"nvidia.com/gpu": resource.MustParse("1")},
+		}),
+		WithSecurityContext(&corev1.SecurityContext{
+			Privileged: ptr.To(false),
+			RunAsUser:  ptr.To(int64(65532)),
+		}),
+	} {
+		if err := opt(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := o.pod()
+
+	if diff := cmp.Diff(map[string]string{"kubernetes.io/arch": "arm64"}, p.Spec.NodeSelector); diff != "" {
+		t.Errorf("node selector mismatch (-want +got):\n%s", diff)
+	}
+
+	if len(p.Spec.Tolerations) != 1 || p.Spec.Tolerations[0].Key != "nvidia.com/gpu" {
+		t.Errorf("expected gpu toleration, got %v", p.Spec.Tolerations)
+	}
+
+	if got := ptr.Deref(p.Spec.RuntimeClassName, ""); got != "nvidia" {
+		t.Errorf("expected runtime class nvidia, got %q", got)
+	}
+
+	sandbox := p.Spec.Containers[0]
+	if q := sandbox.Resources.Limits["nvidia.com/gpu"]; q.Cmp(resource.MustParse("1")) != 0 {
+		t.Errorf("expected gpu limit of 1, got %s", q.String())
+	}
+
+	// Overridden fields are replaced, the rest keep their defaults
+	sc := sandbox.SecurityContext
+	if ptr.Deref(sc.Privileged, true) {
+		t.Error("expected sandbox to not be privileged")
+	}
+	if got := ptr.Deref(sc.RunAsUser, 0); got != 65532 {
+		t.Errorf("expected run as user 65532, got %d", got)
+	}
+	if got := ptr.Deref(sc.RunAsGroup, -1); got != 0 {
+		t.Errorf("expected default run as group 0, got %d", got)
+	}
+
+	// The artifacts sidecar is left untouched
+	if p.Spec.Containers[1].Resources.Limits.Cpu().String() != "50m" {
+		t.Errorf("expected artifact container resources to be unchanged, got %v", p.Spec.Containers[1].Resources)
+	}
+}
@@ -0,0 +1,241 @@
+package pod
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	rbacv1apply "k8s.io/client-go/applyconfigurations/rbac/v1"
+	"k8s.io/utils/ptr"
+)
+
+// RBACMode controls what the sandbox's service account is allowed to do.
+type RBACMode string
+
+const (
+	// RBACModeClusterAdmin binds the sandbox to cluster-admin. This is the
+	// default, and is only appropriate for ephemeral clusters.
+	RBACModeClusterAdmin RBACMode = "cluster-admin"
+	// RBACModeNamespace grants the sandbox the user supplied rules through a
+	// Role and RoleBinding scoped to the sandbox namespace.
+	RBACModeNamespace RBACMode = "namespace"
+	// RBACModeClusterRole binds the sandbox to an existing ClusterRole.
+	RBACModeClusterRole RBACMode = "cluster-role"
+)
+
+// RBAC is the access granted to the sandbox's service account.
+type RBAC struct {
+	Mode RBACMode
+	// Rules are granted in the sandbox namespace when Mode is
+	// RBACModeNamespace.
+	Rules []rbacv1.PolicyRule
+	// ClusterRole is the name of the existing ClusterRole bound when Mode is
+	// RBACModeClusterRole.
+	ClusterRole string
+}
+
+// Validate checks the RBAC configuration is internally consistent.
+func (r RBAC) Validate() error {
+	switch r.Mode {
+	case "", RBACModeClusterAdmin:
+		if len(r.Rules) > 0 || r.ClusterRole != "" {
+			return fmt.Errorf("rbac mode %q does not accept rules or a cluster role", RBACModeClusterAdmin)
+		}
+	case RBACModeNamespace:
+		if len(r.Rules) == 0 {
+			return fmt.Errorf("rbac mode %q requires at least one rule", r.Mode)
+		}
+		if r.ClusterRole != "" {
+			return fmt.Errorf("rbac mode %q does not accept a cluster role", r.Mode)
+		}
+		for i, rule := range r.Rules {
+			if len(rule.NonResourceURLs) > 0 {
+				return fmt.Errorf("rule %d: non-resource urls cannot be granted in a namespace", i)
+			}
+			if len(rule.Verbs) == 0 || len(rule.Resources) == 0 {
+				return fmt.Errorf("rule %d: verbs and resources are required", i)
+			}
+		}
+	case RBACModeClusterRole:
+		if r.ClusterRole == "" {
+			return fmt.Errorf("rbac mode %q requires a cluster role", r.Mode)
+		}
+		if len(r.Rules) > 0 {
+			return fmt.Errorf("rbac mode %q does not accept rules", r.Mode)
+		}
+	default:
+		return fmt.Errorf("unknown rbac mode %q, must be one of: %s, %s, %s", r.Mode, RBACModeClusterAdmin, RBACModeNamespace, RBACModeClusterRole)
+	}
+	return nil
+}
+
+// applyRBAC grants the sandbox's service account its configured access.
+func (o *opts) applyRBAC(ctx context.Context) error {
+	subject := &rbacv1apply.SubjectApplyConfiguration{
+		Kind:      ptr.To(rbacv1.ServiceAccountKind),
+		Name:      ptr.To(o.Name),
+		Namespace: ptr.To(o.Namespace),
+	}
+
+	applyOpts := metav1.ApplyOptions{
+		FieldManager: "imagetest",
+		Force:        true,
+	}
+
+	switch o.RBAC.Mode {
+	case RBACModeNamespace:
+		rules := make([]*rbacv1apply.PolicyRuleApplyConfiguration, 0, len(o.RBAC.Rules))
+		for _, rule := range o.RBAC.Rules {
+			rules = append(rules, rbacv1apply.PolicyRule().
+				WithAPIGroups(rule.APIGroups...).
+				WithResources(rule.Resources...).
+				WithVerbs(rule.Verbs...).
+				WithResourceNames(rule.ResourceNames...))
+		}
+
+		ra := rbacv1apply.Role(o.Name, o.Namespace).
+			WithName(o.Name).
+			WithRules(rules...)
+		if _, err := o.client.RbacV1().Roles(o.Namespace).Apply(ctx, ra, applyOpts); err != nil {
+			return fmt.Errorf("failed to apply role: %w", err)
+		}
+
+		rba := rbacv1apply.RoleBinding(o.Name, o.Namespace).
+			WithName(o.Name).
+			WithSubjects(subject).
+			WithRoleRef(&rbacv1apply.RoleRefApplyConfiguration{
+				APIGroup: ptr.To(rbacv1.GroupName),
+				Kind:     ptr.To("Role"),
+				Name:     ptr.To(o.Name),
+			})
+		if _, err := o.client.RbacV1().RoleBindings(o.Namespace).Apply(ctx, rba, applyOpts); err != nil {
+			return fmt.Errorf("failed to apply role binding: %w", err)
+		}
+
+	default:
+		role := "cluster-admin"
+		if o.RBAC.Mode == RBACModeClusterRole {
+			role = o.RBAC.ClusterRole
+		}
+
+		crba := rbacv1apply.ClusterRoleBinding(o.Name).
+			WithName(o.Name).
+			WithSubjects(subject).
+			WithRoleRef(&rbacv1apply.RoleRefApplyConfiguration{
+				APIGroup: ptr.To(rbacv1.GroupName),
+				Kind:     ptr.To("ClusterRole"),
+				Name:     ptr.To(role),
+			})
+		if _, err := o.client.RbacV1().ClusterRoleBindings().Apply(ctx, crba, applyOpts); err != nil {
+			return fmt.Errorf("failed to apply cluster role binding: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// validateRBAC ensures the caller holds every permission it is about to grant
+// the sandbox. The API server refuses to create roles that escalate beyond the
+// caller's own access, but its error is opaque and only surfaces mid-setup, so
+// check up front and report exactly what is missing.
+func (o *opts) validateRBAC(ctx context.Context) error {
+	var (
+		namespace string
+		rules     []rbacv1.PolicyRule
+	)
+
+	switch o.RBAC.Mode {
+	case RBACModeNamespace:
+		namespace = o.Namespace
+		rules = o.RBAC.Rules
+	case RBACModeClusterRole:
+		cr, err := o.client.RbacV1().ClusterRoles().Get(ctx, o.RBAC.ClusterRole, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get cluster role %s: %w", o.RBAC.ClusterRole, err)
+		}
+		rules = cr.Rules
+	default:
+		// cluster-admin is validated by the api server itself
+		return nil
+	}
+
+	var denied []string
+	for _, rule := range rules {
+		for _, attrs := range ruleAttributes(namespace, rule) {
+			resp, err := o.client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authv1.SelfSubjectAccessReview{
+				Spec: authv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: attrs,
+				},
+			}, metav1.CreateOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to create authorization review: %w", err)
+			}
+
+			if !resp.Status.Allowed {
+				denied = append(denied, describeAttributes(attrs))
+			}
+		}
+	}
+
+	if len(denied) > 0 {
+		return fmt.Errorf("user cannot grant the sandbox permissions it does not hold itself, missing: %s", strings.Join(denied, ", "))
+	}
+
+	return nil
+}
+
+// ruleAttributes expands a policy rule into the individual access checks it
+// grants.
+func ruleAttributes(namespace string, rule rbacv1.PolicyRule) []*authv1.ResourceAttributes {
+	groups := rule.APIGroups
+	if len(groups) == 0 {
+		groups = []string{""}
+	}
+
+	names := rule.ResourceNames
+	if len(names) == 0 {
+		names = []string{""}
+	}
+
+	var attrs []*authv1.ResourceAttributes
+	for _, group := range groups {
+		for _, res := range rule.Resources {
+			resource, subresource, _ := strings.Cut(res, "/")
+			for _, verb := range rule.Verbs {
+				for _, name := range names {
+					attrs = append(attrs, &authv1.ResourceAttributes{
+						Namespace:   namespace,
+						Verb:        verb,
+						Group:       group,
+						Resource:    resource,
+						Subresource: subresource,
+						Name:        name,
+					})
+				}
+			}
+		}
+	}
+	return attrs
+}
+
+func describeAttributes(a *authv1.ResourceAttributes) string {
+	resource := a.Resource
+	if a.Group != "" {
+		resource += "." + a.Group
+	}
+	if a.Subresource != "" {
+		resource += "/" + a.Subresource
+	}
+	if a.Name != "" {
+		resource += "/" + a.Name
+	}
+
+	s := fmt.Sprintf("%s %s", a.Verb, resource)
+	if a.Namespace != "" {
+		s += fmt.Sprintf(" in %s", a.Namespace)
+	}
+	return s
+}
@@ -0,0 +1,93 @@
+package pod
+
+import (
+	"strings"
+	"testing"
+
+	authv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+)
+
+func TestRBACValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rbac    RBAC
+		wantErr bool
+	}{
+		{name: "default", rbac: RBAC{}},
+		{name: "cluster_admin", rbac: RBAC{Mode: RBACModeClusterAdmin}},
+		{name: "cluster_admin_with_rules", rbac: RBAC{Mode: RBACModeClusterAdmin, Rules: []rbacv1.PolicyRule{{}}}, wantErr: true},
+		{
+			name: "namespace",
+			rbac: RBAC{Mode: RBACModeNamespace, Rules: []rbacv1.PolicyRule{
+				{Verbs: []string{"get"}, Resources: []string{"pods"}},
+			}},
+		},
+		{name: "namespace_without_rules", rbac: RBAC{Mode: RBACModeNamespace}, wantErr: true},
+		{
+			name: "namespace_with_non_resource_urls",
+			rbac: RBAC{Mode: RBACModeNamespace, Rules: []rbacv1.PolicyRule{
+				{Verbs: []string{"get"}, NonResourceURLs: []string{"/healthz"}},
+			}},
+			wantErr: true,
+		},
+		{name: "cluster_role", rbac: RBAC{Mode: RBACModeClusterRole, ClusterRole: "view"}},
+		{name: "cluster_role_without_name", rbac: RBAC{Mode: RBACModeClusterRole}, wantErr: true},
+		{name: "unknown", rbac: RBAC{Mode: "nope"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rbac.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRBAC(t *testing.T) {
+	client := fake.NewClientset()
+
+	// Only allow reading pods
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action ktesting.Action) (bool, runtime.Object, error) {
+		review := action.(ktesting.CreateAction).GetObject().(*authv1.SelfSubjectAccessReview)
+		attrs := review.Spec.ResourceAttributes
+		review.Status.Allowed = attrs.Resource == "pods" && attrs.Verb == "get"
+		return true, review, nil
+	})
+
+	o := &opts{
+		Name:      "imagetest",
+		Namespace: "imagetest",
+		RBAC: RBAC{
+			Mode: RBACModeNamespace,
+			Rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}},
+				{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"get", "delete"}},
+			},
+		},
+		client: client,
+	}
+
+	err := o.validateRBAC(t.Context())
+	if err == nil {
+		t.Fatal("expected error but got nil")
+	}
+
+	for _, want := range []string{
+		"get deployments.apps in imagetest",
+		"delete deployments.apps in imagetest",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to contain %q, got %q", want, err.Error())
+		}
+	}
+
+	if strings.Contains(err.Error(), "get pods") {
+		t.Errorf("expected allowed permissions to be omitted, got %q", err.Error())
+	}
+}
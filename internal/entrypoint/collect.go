@@ -0,0 +1,62 @@
+package entrypoint
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+const (
+	// CollectEnvVar is a JSON list of CollectRules the entrypoint gathers into
+	// the artifact bundle after the wrapped process exits.
+	CollectEnvVar = "IMAGETEST_COLLECT"
+
+	// CollectedDir is where collected files are placed in the artifact
+	// bundle, under their absolute path in the sandbox.
+	CollectedDir = "collected"
+	// CollectedManifestPath lists every collected and skipped file, relative
+	// to the artifact bundle.
+	CollectedManifestPath = CollectedDir + "/manifest.json"
+
+	// DefaultCollectMaxBytes caps the total size collected by a rule that
+	// doesn't set its own cap.
+	DefaultCollectMaxBytes = 10 << 20
+	// DefaultCoreDumpMaxBytes caps the total size of the core dumps collected
+	// when the wrapped process crashes.
+	DefaultCoreDumpMaxBytes = 512 << 20
+)
+
+// CollectRule selects files in the sandbox to add to the artifact bundle.
+type CollectRule struct {
+	// Pattern is an absolute filepath.Match glob. Matched directories are
+	// collected recursively.
+	Pattern string `json:"pattern"`
+	// MaxBytes caps the total size of the files collected by this rule,
+	// files that would exceed it are skipped. Zero uses
+	// DefaultCollectMaxBytes.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+	// OnFailure only collects the files when the wrapped process failed.
+	OnFailure bool `json:"on_failure,omitempty"`
+}
+
+// Validate checks the pattern is an absolute, well formed glob.
+func (r CollectRule) Validate() error {
+	if !filepath.IsAbs(r.Pattern) {
+		return fmt.Errorf("invalid collect pattern %q: must be an absolute path", r.Pattern)
+	}
+	if _, err := filepath.Match(r.Pattern, ""); errors.Is(err, filepath.ErrBadPattern) {
+		return fmt.Errorf("invalid collect pattern %q: %w", r.Pattern, err)
+	}
+	if r.MaxBytes < 0 {
+		return fmt.Errorf("invalid collect max size for %q: must not be negative", r.Pattern)
+	}
+	return nil
+}
+
+// CollectedFile is an entry of the collected manifest.
+type CollectedFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	// Skipped is the reason the file wasn't collected, if it wasn't.
+	Skipped string `json:"skipped,omitempty"`
+}
@@ -0,0 +1,27 @@
+package entrypoint
+
+import "time"
+
+const (
+	// CommandsEnvVar is a JSON list of Commands. When set, the entrypoint runs
+	// them in order in place of the test's single command, recording each as
+	// a step.
+	CommandsEnvVar = "IMAGETEST_COMMANDS"
+
+	// CommandTimeoutCode is the exit code recorded for a command that was
+	// terminated after exceeding its timeout, matching timeout(1).
+	CommandTimeoutCode = 124
+)
+
+// Command is a single named step of a test's commands.
+type Command struct {
+	Name string `json:"name"`
+	// Cmd is run with "sh -c".
+	Cmd string `json:"cmd"`
+	// Timeout is the maximum time the command may run, zero means it is only
+	// bound by the test's timeout.
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// ContinueOnError runs the remaining commands when this one fails, and
+	// doesn't fail the test because of it.
+	ContinueOnError bool `json:"continue_on_error,omitempty"`
+}
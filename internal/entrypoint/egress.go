@@ -0,0 +1,100 @@
+package entrypoint
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// EgressProxyEnvVar enables the entrypoint's egress recording proxy.
+	EgressProxyEnvVar = "IMAGETEST_EGRESS_PROXY"
+	// EgressAllowlistEnvVar is a JSON list of the destinations the wrapped
+	// process may contact through the egress proxy. When unset every
+	// destination is allowed and only recorded.
+	EgressAllowlistEnvVar = "IMAGETEST_EGRESS_ALLOWLIST"
+
+	// EgressPath is where the recorded destinations are written, relative to
+	// the artifacts dir.
+	EgressPath = "egress.json"
+
+	// EgressDeniedCode is the exit code used when the wrapped process
+	// succeeded, but contacted a destination that isn't allowed.
+	EgressDeniedCode = 76
+)
+
+// EgressRecord is a destination contacted through the egress proxy.
+type EgressRecord struct {
+	Host      string    `json:"host"`
+	Port      string    `json:"port"`
+	Allowed   bool      `json:"allowed"`
+	Requests  int       `json:"requests"`
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+func (r EgressRecord) String() string {
+	return net.JoinHostPort(r.Host, r.Port)
+}
+
+// EgressAllowlist is a list of destination patterns. Each pattern is a host,
+// optionally with a leading "*." to match any subdomain, and optionally
+// followed by ":port" to only match that port.
+type EgressAllowlist []string
+
+// Validate checks every pattern is well formed.
+func (a EgressAllowlist) Validate() error {
+	for _, pattern := range a {
+		host, port := splitEgressPattern(pattern)
+		if host == "" || host == "*." {
+			return fmt.Errorf("invalid egress allowlist entry %q: a host is required", pattern)
+		}
+		if strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+			return fmt.Errorf("invalid egress allowlist entry %q: wildcards are only supported as a leading *.", pattern)
+		}
+		if strings.Contains(host, "/") {
+			return fmt.Errorf("invalid egress allowlist entry %q: expected a host, not a url", pattern)
+		}
+		if port != "" {
+			if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+				return fmt.Errorf("invalid egress allowlist entry %q: port must be a number between 1 and 65535", pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// Allows reports whether the destination matches any pattern.
+func (a EgressAllowlist) Allows(host, port string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	for _, pattern := range a {
+		phost, pport := splitEgressPattern(pattern)
+		if pport != "" && pport != port {
+			continue
+		}
+
+		phost = strings.ToLower(phost)
+		if suffix, ok := strings.CutPrefix(phost, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+
+		if host == phost {
+			return true
+		}
+	}
+	return false
+}
+
+// splitEgressPattern splits the optional port from a pattern, accounting for
+// bracketed IPv6 addresses.
+func splitEgressPattern(pattern string) (host, port string) {
+	if h, p, err := net.SplitHostPort(pattern); err == nil {
+		return h, p
+	}
+	return strings.Trim(pattern, "[]"), ""
+}
@@ -0,0 +1,54 @@
+package entrypoint
+
+import "testing"
+
+func TestEgressAllowlist(t *testing.T) {
+	allowlist := EgressAllowlist{
+		"example.com",
+		"*.googleapis.com",
+		"registry.local:5000",
+		"[::1]:8080",
+	}
+	if err := allowlist.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	tests := []struct {
+		host, port string
+		want       bool
+	}{
+		{"example.com", "443", true},
+		{"EXAMPLE.com.", "80", true},
+		{"www.example.com", "443", false},
+		{"storage.googleapis.com", "443", true},
+		{"googleapis.com", "443", false},
+		{"registry.local", "5000", true},
+		{"registry.local", "443", false},
+		{"::1", "8080", true},
+		{"telemetry.example.net", "443", false},
+	}
+
+	for _, tt := range tests {
+		if got := allowlist.Allows(tt.host, tt.port); got != tt.want {
+			t.Errorf("Allows(%q, %q) = %v, want %v", tt.host, tt.port, got, tt.want)
+		}
+	}
+
+	if (EgressAllowlist{}).Allows("example.com", "443") {
+		t.Error("expected an empty allowlist to deny everything")
+	}
+}
+
+func TestEgressAllowlistValidate(t *testing.T) {
+	for _, invalid := range []string{
+		"",
+		"*.",
+		"foo.*.com",
+		"https://example.com",
+		"example.com:notaport",
+	} {
+		if err := (EgressAllowlist{invalid}).Validate(); err == nil {
+			t.Errorf("Validate(%q) expected an error", invalid)
+		}
+	}
+}
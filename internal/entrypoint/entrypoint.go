@@ -11,8 +11,18 @@ const (
 	// DefaultProcessLogPath contains both stdout and stderr.
 	DefaultProcessLogPath = ArtifactsDir + "/logs/process.log"
 
+	// DefaultHealthCheckSocket is where the entrypoint serves its health
+	// status, and accepts step and annotation events from the wrapped process.
 	DefaultHealthCheckSocket = "/tmp/imagetest.health.sock"
 
+	// EntrypointEnvVar holds the path of the entrypoint binary in the wrapped
+	// process's environment, so scripts can call its subcommands.
+	EntrypointEnvVar = "IMAGETEST_ENTRYPOINT"
+
+	// PauseFIFOPath is the named pipe a paused entrypoint blocks on, writing
+	// to it resumes the entrypoint.
+	PauseFIFOPath = "/tmp/imagetest.unpause"
+
 	// Return code if entrypoint fails.
 	InternalErrorCode = 1000
 
@@ -35,6 +45,14 @@ const (
 	ArtifactsMountPath = "/mnt/imagetest"
 	ArtifactsDir       = ArtifactsMountPath + "/artifacts"
 	ArtifactsPath      = ArtifactsMountPath + "/artifacts.tar.gz"
+
+	// ProfileSamplesPath and ProfileSummaryPath are where the resource
+	// profile is written, relative to the artifacts dir.
+	ProfileSamplesPath = "profile/samples.jsonl"
+	ProfileSummaryPath = "profile/summary.json"
+
+	// EventsPath is where events are recorded, relative to the artifacts dir.
+	EventsPath = "events.jsonl"
 )
 
 // PauseMode are the states of pause the entrypoint can be in.
@@ -58,3 +76,9 @@ var DefaultHealthCheckCommand = []string{
 	BinaryPath,
 	"healthcheck",
 }
+
+// ResumeCommand resumes a paused entrypoint.
+var ResumeCommand = []string{
+	BinaryPath,
+	"resume",
+}
@@ -0,0 +1,97 @@
+package entrypoint
+
+import (
+	"maps"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventStepStart EventType = "step_start"
+	EventStepEnd   EventType = "step_end"
+	EventAnnotate  EventType = "annotate"
+)
+
+type StepStatus string
+
+const (
+	StepStatusOK    StepStatus = "ok"
+	StepStatusError StepStatus = "error"
+	// StepStatusIncomplete is used for steps that were still open when the
+	// wrapped process exited.
+	StepStatusIncomplete StepStatus = "incomplete"
+)
+
+// Event is sent by the wrapped process to the entrypoint's health socket.
+type Event struct {
+	Type EventType `json:"type"`
+	Time time.Time `json:"time"`
+	// Step is the step being started or ended. For annotations it is the
+	// innermost open step, or empty when the annotation applies to the test.
+	Step        string            `json:"step,omitempty"`
+	Status      StepStatus        `json:"status,omitempty"`
+	Message     string            `json:"message,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// ExitCode is set when the step is one of the test's commands.
+	ExitCode *int `json:"exit_code,omitempty"`
+}
+
+// EventResponse is the entrypoint's reply to an Event.
+type EventResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// Step is a named section of a test, assembled from its events.
+type Step struct {
+	Name        string
+	Status      StepStatus
+	Message     string
+	Start       time.Time
+	End         time.Time
+	Annotations map[string]string
+	ExitCode    *int
+}
+
+// Steps assembles the recorded events into steps, in the order they started,
+// and the annotations made outside of any step.
+func Steps(events []Event) ([]*Step, map[string]string) {
+	var (
+		steps       []*Step
+		open        = make(map[string]*Step)
+		annotations = make(map[string]string)
+	)
+
+	for _, ev := range events {
+		switch ev.Type {
+		case EventStepStart:
+			s := &Step{
+				Name:        ev.Step,
+				Start:       ev.Time,
+				Annotations: make(map[string]string),
+			}
+			steps = append(steps, s)
+			open[ev.Step] = s
+
+		case EventStepEnd:
+			s, ok := open[ev.Step]
+			if !ok {
+				continue
+			}
+			s.End = ev.Time
+			s.Status = ev.Status
+			s.Message = ev.Message
+			s.ExitCode = ev.ExitCode
+			delete(open, ev.Step)
+
+		case EventAnnotate:
+			if s, ok := open[ev.Step]; ok {
+				maps.Copy(s.Annotations, ev.Annotations)
+			} else {
+				maps.Copy(annotations, ev.Annotations)
+			}
+		}
+	}
+
+	return steps, annotations
+}
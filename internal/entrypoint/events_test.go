@@ -0,0 +1,40 @@
+package entrypoint
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSteps(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(s int) time.Time { return t0.Add(time.Duration(s) * time.Second) }
+	exitCode := 2
+
+	events := []Event{
+		{Type: EventAnnotate, Time: at(0), Annotations: map[string]string{"image": "foo"}},
+		{Type: EventStepStart, Time: at(1), Step: "outer"},
+		{Type: EventStepStart, Time: at(2), Step: "inner"},
+		{Type: EventAnnotate, Time: at(3), Step: "inner", Annotations: map[string]string{"port": "8080"}},
+		{Type: EventStepEnd, Time: at(4), Step: "inner", Status: StepStatusError, Message: "boom", ExitCode: &exitCode},
+		{Type: EventAnnotate, Time: at(5), Step: "outer", Annotations: map[string]string{"retries": "1"}},
+		{Type: EventStepEnd, Time: at(6), Step: "outer", Status: StepStatusOK},
+		// ending an unknown step is ignored
+		{Type: EventStepEnd, Time: at(7), Step: "unknown", Status: StepStatusOK},
+	}
+
+	steps, annotations := Steps(events)
+
+	wantSteps := []*Step{
+		{Name: "outer", Status: StepStatusOK, Start: at(1), End: at(6), Annotations: map[string]string{"retries": "1"}},
+		{Name: "inner", Status: StepStatusError, Message: "boom", Start: at(2), End: at(4), Annotations: map[string]string{"port": "8080"}, ExitCode: &exitCode},
+	}
+	if diff := cmp.Diff(wantSteps, steps); diff != "" {
+		t.Errorf("steps mismatch (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(map[string]string{"image": "foo"}, annotations); diff != "" {
+		t.Errorf("annotations mismatch (-want +got):\n%s", diff)
+	}
+}
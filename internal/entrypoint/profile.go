@@ -0,0 +1,29 @@
+package entrypoint
+
+import "time"
+
+// ResourceSample is a single reading of the sandbox's cgroup.
+type ResourceSample struct {
+	Time time.Time `json:"time"`
+	// CPUCores is the average number of cores used since the previous sample.
+	CPUCores           float64 `json:"cpu_cores"`
+	CPUUsageSeconds    float64 `json:"cpu_usage_seconds"`
+	MemoryCurrentBytes int64   `json:"memory_current_bytes"`
+	PIDs               int64   `json:"pids"`
+	IOReadBytes        int64   `json:"io_read_bytes"`
+	IOWriteBytes       int64   `json:"io_write_bytes"`
+}
+
+// ResourceSummary aggregates the samples taken while the wrapped process ran.
+// Counters are relative to when profiling started.
+type ResourceSummary struct {
+	Duration        time.Duration `json:"duration"`
+	Samples         int           `json:"samples"`
+	CPUUsageSeconds float64       `json:"cpu_usage_seconds"`
+	CPUPeakCores    float64       `json:"cpu_peak_cores"`
+	CPUAvgCores     float64       `json:"cpu_avg_cores"`
+	MemoryPeakBytes int64         `json:"memory_peak_bytes"`
+	PIDsPeak        int64         `json:"pids_peak"`
+	IOReadBytes     int64         `json:"io_read_bytes"`
+	IOWriteBytes    int64         `json:"io_write_bytes"`
+}
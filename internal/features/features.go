@@ -3,8 +3,11 @@ package features
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/log"
+	"golang.org/x/sync/errgroup"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
@@ -16,6 +19,20 @@ type Feature struct {
 	befores     []*step
 	afters      []*step
 	assessments []*step
+
+	// parallelism is the maximum number of assessments run concurrently
+	parallelism int
+
+	mu      sync.Mutex
+	results []StepResult
+}
+
+// StepResult is the outcome of a step that ran.
+type StepResult struct {
+	Name     string
+	Level    Level
+	Duration time.Duration
+	Err      error
 }
 
 type step struct {
@@ -58,6 +75,18 @@ const (
 	After
 )
 
+func (l Level) String() string {
+	switch l {
+	case Before:
+		return "before"
+	case Assessment:
+		return "assessment"
+	case After:
+		return "after"
+	}
+	return fmt.Sprintf("Level(%d)", l)
+}
+
 type Option func(*Feature)
 
 func New(name string, opts ...Option) *Feature {
@@ -82,6 +111,15 @@ func WithDescription(desc string) Option {
 	}
 }
 
+// WithParallelAssessments runs up to n assessments concurrently. Befores
+// still run in order before any assessment, and afters in order once every
+// assessment finished.
+func WithParallelAssessments(n int) Option {
+	return func(f *Feature) {
+		f.parallelism = n
+	}
+}
+
 func (f *Feature) WithBefore(name string, fn StepFn, opts ...StepOpt) {
 	f.withStep(name, fn, Before, opts...)
 }
@@ -116,8 +154,13 @@ func (f *Feature) withStep(name string, fn StepFn, level Level, opts ...StepOpt)
 // Test executes the steps in the feature. The "before" steps are executed
 // first, followed by the "assessments", followed by the "afters". On failures,
 // the steps are short-circuited to the "afters". The "afters" are _always_
-// run.
+// run. When assessments run in parallel, a failed assessment doesn't stop the
+// others that already started.
 func (f *Feature) Test(ctx context.Context) error {
+	f.mu.Lock()
+	f.results = nil
+	f.mu.Unlock()
+
 	var collectedError error
 
 	collectError := func(err error) {
@@ -130,7 +173,7 @@ func (f *Feature) Test(ctx context.Context) error {
 
 	afters := func() {
 		for _, after := range f.afters {
-			if err := after.Fn(ctx); err != nil {
+			if err := f.run(ctx, after); err != nil {
 				collectError(fmt.Errorf("after step '%s' failed:\n%v", after.Name, err))
 				// Don't continue if we error
 				break
@@ -139,22 +182,74 @@ func (f *Feature) Test(ctx context.Context) error {
 	}
 
 	for _, before := range f.befores {
-		if err := before.Fn(ctx); err != nil {
+		if err := f.run(ctx, before); err != nil {
 			collectError(fmt.Errorf("before step '%s' failed:\n%v", before.Name, err))
 			afters()
 			return collectedError
 		}
 	}
 
-	for _, assessment := range f.assessments {
-		if err := assessment.Fn(ctx); err != nil {
-			collectError(fmt.Errorf("assessment step '%s' failed:\n%v", assessment.Name, err))
+	if f.parallelism > 1 {
+		// Report failures in the order the assessments were declared, not
+		// the order they finished in
+		errs := make([]error, len(f.assessments))
+
+		var g errgroup.Group
+		g.SetLimit(f.parallelism)
+		for i, assessment := range f.assessments {
+			g.Go(func() error {
+				errs[i] = f.run(ctx, assessment)
+				return nil
+			})
+		}
+		_ = g.Wait()
+
+		failed := false
+		for i, err := range errs {
+			if err != nil {
+				collectError(fmt.Errorf("assessment step '%s' failed:\n%v", f.assessments[i].Name, err))
+				failed = true
+			}
+		}
+		if failed {
 			afters()
 			return collectedError
 		}
+	} else {
+		for _, assessment := range f.assessments {
+			if err := f.run(ctx, assessment); err != nil {
+				collectError(fmt.Errorf("assessment step '%s' failed:\n%v", assessment.Name, err))
+				afters()
+				return collectedError
+			}
+		}
 	}
 
 	afters()
 
 	return collectedError
 }
+
+// run runs the step and records its result.
+func (f *Feature) run(ctx context.Context, s *step) error {
+	start := time.Now()
+	err := s.Fn(ctx)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.results = append(f.results, StepResult{
+		Name:     s.Name,
+		Level:    s.level,
+		Duration: time.Since(start),
+		Err:      err,
+	})
+	return err
+}
+
+// Results returns the results of the steps that ran in the last Test, in the
+// order they finished.
+func (f *Feature) Results() []StepResult {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]StepResult(nil), f.results...)
+}
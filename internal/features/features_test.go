@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
@@ -166,3 +167,71 @@ func tstepWithRetry(s *step, backoff wait.Backoff) *step {
 	StepWithRetry(backoff)(s)
 	return s
 }
+
+func TestFeatureParallelAssessments(t *testing.T) {
+	f := New("parallel", WithParallelAssessments(2))
+
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	record := func(s string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, s)
+	}
+
+	// Both assessments must be running at once for either to finish
+	var started sync.WaitGroup
+	started.Add(2)
+	assessment := func(name string, err error) StepFn {
+		return func(ctx context.Context) error {
+			started.Done()
+			started.Wait()
+			record(name)
+			return err
+		}
+	}
+
+	f.WithBefore("before", func(ctx context.Context) error {
+		record("before")
+		return nil
+	})
+	f.WithAssessment("first", assessment("first", errors.New("first failed")))
+	f.WithAssessment("second", assessment("second", nil))
+	f.WithAfter("after", func(ctx context.Context) error {
+		record("after")
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- f.Test(t.Context()) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("assessments did not run concurrently")
+	}
+
+	if want := "assessment step 'first' failed:\nfirst failed"; err == nil || err.Error() != want {
+		t.Errorf("Test() error = %v, want %q", err, want)
+	}
+
+	if order[0] != "before" || order[3] != "after" {
+		t.Errorf("order = %v, want before first and after last", order)
+	}
+
+	results := f.Results()
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4: %+v", len(results), results)
+	}
+	for _, r := range results {
+		if (r.Name == "first") != (r.Err != nil) {
+			t.Errorf("result %s error = %v, want only first to fail", r.Name, r.Err)
+		}
+		if r.Name == "first" && r.Level != Assessment {
+			t.Errorf("result %s level = %s, want assessment", r.Name, r.Level)
+		}
+	}
+}
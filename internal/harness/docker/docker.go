@@ -5,10 +5,13 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net"
+	"strconv"
 
 	client "github.com/chainguard-dev/terraform-provider-imagetest/internal/docker"
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/harness"
 	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
 	"github.com/google/go-containerregistry/pkg/name"
 	"k8s.io/apimachinery/pkg/api/resource"
 )
@@ -26,6 +29,11 @@ type docker struct {
 	Envs       []string
 	Registries map[string]*RegistryConfig
 	Volumes    []VolumeConfig
+	Ports      []PortConfig
+
+	// addresses are the host addresses of the published ports, keyed by
+	// port/protocol
+	addresses map[string]string
 
 	stack  *harness.Stack
 	runner func(context.Context, harness.Command) error
@@ -92,6 +100,19 @@ func (h *docker) Create(ctx context.Context) error {
 		}
 	}
 
+	bindings := make(nat.PortMap)
+	for _, p := range h.Ports {
+		port, err := nat.NewPort(p.Protocol, strconv.Itoa(p.ContainerPort))
+		if err != nil {
+			return fmt.Errorf("invalid port %d/%s: %w", p.ContainerPort, p.Protocol, err)
+		}
+		hostPort := "" // Let the daemon pick a random port
+		if p.HostPort != 0 {
+			hostPort = strconv.Itoa(p.HostPort)
+		}
+		bindings[port] = append(bindings[port], nat.PortBinding{HostPort: hostPort})
+	}
+
 	resp, err := cli.Start(ctx, &client.Request{
 		Name:       h.Name,
 		Ref:        h.ImageRef,
@@ -108,6 +129,7 @@ func (h *docker) Create(ctx context.Context) error {
 		ExtraHosts: []string{
 			"host.docker.internal:host-gateway",
 		},
+		PortBindings: bindings,
 	})
 	if err != nil {
 		return fmt.Errorf("starting container: %w", err)
@@ -119,6 +141,21 @@ func (h *docker) Create(ctx context.Context) error {
 		return fmt.Errorf("adding container teardown to stack: %w", err)
 	}
 
+	h.addresses = make(map[string]string, len(bindings))
+	for port := range bindings {
+		binding, cleanup, err := resp.PortBinding(port)
+		if err != nil {
+			return fmt.Errorf("getting host binding of port %s: %w", port, err)
+		}
+		if err := h.stack.Add(func(context.Context) error {
+			cleanup()
+			return nil
+		}); err != nil {
+			return fmt.Errorf("adding port %s teardown to stack: %w", port, err)
+		}
+		h.addresses[string(port)] = hostAddress(binding)
+	}
+
 	h.runner = func(ctx context.Context, cmd harness.Command) error {
 		return resp.Run(ctx, cmd)
 	}
@@ -131,6 +168,22 @@ func (h *docker) Run(ctx context.Context, cmd harness.Command) error {
 	return h.runner(ctx, cmd)
 }
 
+// Addresses returns the host addresses of the published ports, keyed by
+// port/protocol, e.g. 8080/tcp. It is empty until the harness is created.
+func (h *docker) Addresses() map[string]string {
+	return h.addresses
+}
+
+// hostAddress returns the address to reach a binding from the host. Ports
+// published on all interfaces are reached through the loopback address.
+func hostAddress(b nat.PortBinding) string {
+	host := b.HostIP
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, b.HostPort)
+}
+
 func (h *docker) DebugLogCommand() string {
 	// TODO implement something here
 	return ""
@@ -16,6 +16,14 @@ type VolumeConfig struct {
 	Target string
 }
 
+// PortConfig is a container port published to the host. A zero HostPort
+// lets the docker daemon pick a free port.
+type PortConfig struct {
+	ContainerPort int
+	Protocol      string
+	HostPort      int
+}
+
 type RegistryConfig struct {
 	Auth *RegistryAuthConfig
 	Tls  *RegistryTlsConfig
@@ -142,3 +150,10 @@ func WithVolumes(volumes ...VolumeConfig) Option {
 		return nil
 	}
 }
+
+func WithPorts(ports ...PortConfig) Option {
+	return func(opt *docker) error {
+		opt.Ports = append(opt.Ports, ports...)
+		return nil
+	}
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
+
+	"github.com/kballard/go-shellquote"
 )
 
 type Harness interface {
@@ -14,6 +18,35 @@ type Harness interface {
 	Run(context.Context, Command) error
 }
 
+// Redactor is implemented by harnesses that know of secrets a command may
+// print, such as sensitive terraform outputs.
+type Redactor interface {
+	// Redact masks the secrets in s.
+	Redact(s string) string
+}
+
+// Redact masks the secrets h knows of in s, if any.
+func Redact(h Harness, s string) string {
+	if r, ok := h.(Redactor); ok {
+		return r.Redact(s)
+	}
+	return s
+}
+
+// MinMaskLen is the length below which Mask leaves values alone, since
+// masking them would mangle unrelated text without hiding anything.
+const MinMaskLen = 4
+
+// Mask replaces every occurrence of the values in s with "(sensitive)".
+func Mask(s string, values ...string) string {
+	for _, v := range values {
+		if len(v) >= MinMaskLen {
+			s = strings.ReplaceAll(s, v, "(sensitive)")
+		}
+	}
+	return s
+}
+
 type Command struct {
 	Args       string
 	WorkingDir string
@@ -22,6 +55,16 @@ type Command struct {
 	Stderr     io.Writer
 }
 
+// ExportEnv returns the shell prelude exporting the variables, in order of
+// their names.
+func ExportEnv(vars map[string]string) string {
+	var b strings.Builder
+	for _, k := range slices.Sorted(maps.Keys(vars)) {
+		fmt.Fprintf(&b, "export %s=%s\n", k, shellquote.Join(vars[k]))
+	}
+	return b.String()
+}
+
 func DefaultEntrypoint() []string {
 	return []string{"/bin/sh", "-c"}
 }
@@ -4,14 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/docker"
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/harness"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/sandbox/k8s"
 	"github.com/docker/cli/cli/config/configfile"
 	dtypes "github.com/docker/cli/cli/config/types"
 	"github.com/docker/docker/api/types/container"
@@ -33,6 +36,9 @@ var _ harness.Harness = &k3s{}
 type k3s struct {
 	Service *serviceConfig
 	Sandbox *docker.Request
+	// SandboxPod runs the sandbox as a pod in the cluster configured by these
+	// options instead of a container next to it, when not nil.
+	SandboxPod []k8s.Option
 
 	Hooks Hooks
 
@@ -110,6 +116,13 @@ func (h *k3s) Create(ctx context.Context) error {
 		return fmt.Errorf("starting k3s: %w", err)
 	}
 
+	if h.SandboxPod != nil {
+		if err := h.startSandboxPod(ctx); err != nil {
+			return fmt.Errorf("creating sandbox pod: %w", err)
+		}
+		return nil
+	}
+
 	if err := h.startSandbox(ctx, cli, kresp); err != nil {
 		return fmt.Errorf("creating sandbox: %w", err)
 	}
@@ -328,6 +341,40 @@ func (h *k3s) startSandbox(ctx context.Context, cli *docker.Client, resp *docker
 	return nil
 }
 
+// startSandboxPod runs the sandbox as a pod in the cluster, which reaches the
+// api server with its service account rather than the sandbox kubeconfig.
+func (h *k3s) startSandboxPod(ctx context.Context) error {
+	env := make(map[string]string)
+	for _, e := range h.Sandbox.Env {
+		k, v, _ := strings.Cut(e, "=")
+		if k == "KUBECONFIG" || k == "ENV" {
+			continue
+		}
+		env[k] = v
+	}
+
+	sbx, err := k8s.NewFromConfig(h.kcfg, append([]k8s.Option{
+		k8s.WithImageRef(h.Sandbox.Ref),
+		k8s.WithEnv(env),
+	}, h.SandboxPod...)...)
+	if err != nil {
+		return fmt.Errorf("creating sandbox: %w", err)
+	}
+
+	runner, err := sbx.Start(ctx)
+	if err != nil {
+		return errors.Join(fmt.Errorf("starting sandbox: %w", err), sbx.Destroy(ctx))
+	}
+
+	if err := h.stack.Add(sbx.Destroy); err != nil {
+		return fmt.Errorf("adding sandbox teardown to stack: %w", err)
+	}
+
+	h.runner = runner.Run
+
+	return nil
+}
+
 func (h *k3s) config(host string) (*docker.Content, error) {
 	tpl := fmt.Sprintf(`
 tls-san: "%[1]s"
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/docker"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/sandbox/k8s"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
@@ -272,3 +273,16 @@ func WithSandboxName(name string) Option {
 		return nil
 	}
 }
+
+// WithSandboxPod runs the sandbox as a pod in the k3s cluster, configured by
+// opts, rather than as a container next to it. Sandbox mounts and networks
+// don't apply to the pod.
+func WithSandboxPod(opts ...k8s.Option) Option {
+	return func(opt *k3s) error {
+		if opt.SandboxPod == nil {
+			opt.SandboxPod = make([]k8s.Option, 0)
+		}
+		opt.SandboxPod = append(opt.SandboxPod, opts...)
+		return nil
+	}
+}
@@ -0,0 +1,314 @@
+package pterraform
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/log"
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/hc-install/product"
+	"github.com/hashicorp/hc-install/releases"
+	"github.com/hashicorp/terraform-exec/tfexec"
+)
+
+const (
+	FlavorTerraform = "terraform"
+	FlavorTofu      = "tofu"
+)
+
+// tofuReleasesURL is where OpenTofu releases are downloaded from.
+var tofuReleasesURL = "https://github.com/opentofu/opentofu/releases/download"
+
+// Binary selects the terraform executable the harness runs.
+type Binary struct {
+	// Flavor is either terraform or tofu, defaulting to terraform
+	Flavor string
+	// Path is the executable to run. When empty, the executable is found in
+	// CacheDir or on $PATH.
+	Path string
+	// Version is a version constraint the executable must satisfy, e.g.
+	// ">= 1.8, < 2.0". Exact versions missing from the cache and $PATH are
+	// installed in CacheDir. Installed terraform is verified against
+	// HashiCorp's signing key, installed tofu only against its release's
+	// checksums.
+	Version string
+	// CacheDir holds the installed executables, as
+	// {CacheDir}/{Flavor}/{Version}/{Flavor}
+	CacheDir string
+}
+
+func (b Binary) flavor() string {
+	if b.Flavor == "" {
+		return FlavorTerraform
+	}
+	return b.Flavor
+}
+
+// resolve returns the path of the executable, installing it when needed. It
+// doesn't check the version of an explicit Path, which is verified once it
+// runs.
+func (b Binary) resolve(ctx context.Context) (string, error) {
+	flavor := b.flavor()
+	if flavor != FlavorTerraform && flavor != FlavorTofu {
+		return "", fmt.Errorf("unknown flavor %q, must be one of %s or %s", flavor, FlavorTerraform, FlavorTofu)
+	}
+
+	if b.Path != "" {
+		return b.Path, nil
+	}
+
+	if b.Version == "" {
+		path, err := exec.LookPath(flavor)
+		if err != nil {
+			return "", fmt.Errorf("failed to find a %s executable on $PATH: %w", flavor, err)
+		}
+		return path, nil
+	}
+
+	constraints, err := version.NewConstraint(b.Version)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s version constraint %q: %w", flavor, b.Version, err)
+	}
+
+	if path, ok := b.cached(constraints); ok {
+		return path, nil
+	}
+
+	if path, err := exec.LookPath(flavor); err == nil {
+		v, err := binaryVersion(ctx, path)
+		switch {
+		case err != nil:
+			log.Warn(ctx, "ignoring executable on $PATH", "path", path, "error", err)
+		case !constraints.Check(v):
+			log.Info(ctx, "ignoring executable on $PATH", "path", path, "version", v.String(), "constraint", b.Version)
+		default:
+			return path, nil
+		}
+	}
+
+	exact, ok := exactVersion(b.Version)
+	if !ok {
+		return "", fmt.Errorf("no %s executable satisfying %q found in %s or on $PATH, pin an exact version to install it", flavor, b.Version, b.CacheDir)
+	}
+	return b.install(ctx, exact)
+}
+
+// verify fails when the version of the executable doesn't satisfy the
+// constraint.
+func (b Binary) verify(ctx context.Context, tf *tfexec.Terraform) error {
+	v, _, err := tf.Version(ctx, true)
+	if err != nil {
+		return fmt.Errorf("getting the version of %s: %w", tf.ExecPath(), err)
+	}
+	log.Info(ctx, "using terraform executable", "flavor", b.flavor(), "path", tf.ExecPath(), "version", v.String())
+
+	if b.Version == "" {
+		return nil
+	}
+	constraints, err := version.NewConstraint(b.Version)
+	if err != nil {
+		return fmt.Errorf("invalid %s version constraint %q: %w", b.flavor(), b.Version, err)
+	}
+	if !constraints.Check(v) {
+		return fmt.Errorf("%s at %s is version %s, which doesn't satisfy %q", b.flavor(), tf.ExecPath(), v, b.Version)
+	}
+	return nil
+}
+
+// cached returns the newest executable in the cache satisfying constraints.
+func (b Binary) cached(constraints version.Constraints) (string, bool) {
+	if b.CacheDir == "" {
+		return "", false
+	}
+
+	entries, err := os.ReadDir(filepath.Join(b.CacheDir, b.flavor()))
+	if err != nil {
+		return "", false
+	}
+
+	var (
+		newest *version.Version
+		path   string
+	)
+	for _, e := range entries {
+		v, err := version.NewVersion(e.Name())
+		if err != nil || !constraints.Check(v) || (newest != nil && !v.GreaterThan(newest)) {
+			continue
+		}
+		p := filepath.Join(b.CacheDir, b.flavor(), e.Name(), b.flavor())
+		if _, err := os.Stat(p); err != nil {
+			continue
+		}
+		newest, path = v, p
+	}
+	return path, newest != nil
+}
+
+// install downloads the executable into the cache. Installs land in a
+// temporary directory first so concurrent runs never see a partial install.
+func (b Binary) install(ctx context.Context, v *version.Version) (string, error) {
+	if b.CacheDir == "" {
+		return "", fmt.Errorf("a cache directory is required to install %s %s", b.flavor(), v)
+	}
+
+	base := filepath.Join(b.CacheDir, b.flavor())
+	if err := os.MkdirAll(base, 0o755); err != nil {
+		return "", fmt.Errorf("creating cache directory: %w", err)
+	}
+	tmp, err := os.MkdirTemp(base, ".install-")
+	if err != nil {
+		return "", fmt.Errorf("creating install directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	log.Info(ctx, "installing terraform executable", "flavor", b.flavor(), "version", v.String(), "cache_dir", b.CacheDir)
+
+	switch b.flavor() {
+	case FlavorTerraform:
+		installer := &releases.ExactVersion{
+			Product:    product.Terraform,
+			Version:    v,
+			InstallDir: tmp,
+		}
+		if _, err := installer.Install(ctx); err != nil {
+			return "", fmt.Errorf("installing terraform %s: %w", v, err)
+		}
+	case FlavorTofu:
+		if err := installTofu(ctx, v, tmp); err != nil {
+			return "", fmt.Errorf("installing tofu %s: %w", v, err)
+		}
+	}
+
+	dir := filepath.Join(base, v.String())
+	if err := os.Rename(tmp, dir); err != nil {
+		// Another run installed the same version first
+		if _, serr := os.Stat(filepath.Join(dir, b.flavor())); serr != nil {
+			return "", fmt.Errorf("moving install into the cache: %w", err)
+		}
+	}
+	return filepath.Join(dir, b.flavor()), nil
+}
+
+// installTofu downloads the OpenTofu release for this platform into dir,
+// verifying it against the release's checksums. The checksums are downloaded
+// from the same release without verifying their signature, so this only
+// catches corrupted downloads, not a tampered release. Unlike terraform, whose
+// installs hc-install verifies against HashiCorp's signing key, tofu installs
+// are only integrity-checked; use Path with a vetted executable when that
+// matters.
+func installTofu(ctx context.Context, v *version.Version, dir string) error {
+	archive := fmt.Sprintf("tofu_%s_%s_%s.zip", v, runtime.GOOS, runtime.GOARCH)
+
+	log.Warn(ctx, "tofu installs are only checked against the release's unsigned checksums", "version", v.String())
+
+	sums, err := download(ctx, fmt.Sprintf("%s/v%s/tofu_%s_SHA256SUMS", tofuReleasesURL, v, v))
+	if err != nil {
+		return fmt.Errorf("downloading checksums: %w", err)
+	}
+	want, err := checksum(sums, archive)
+	if err != nil {
+		return err
+	}
+
+	data, err := download(ctx, fmt.Sprintf("%s/v%s/%s", tofuReleasesURL, v, archive))
+	if err != nil {
+		return fmt.Errorf("downloading %s: %w", archive, err)
+	}
+	if got := sha256.Sum256(data); hex.EncodeToString(got[:]) != want {
+		return fmt.Errorf("checksum mismatch for %s: got %x, want %s", archive, got, want)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", archive, err)
+	}
+	for _, f := range zr.File {
+		if f.Name != FlavorTofu {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		w, err := os.OpenFile(filepath.Join(dir, FlavorTofu), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o755)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+
+		if _, err := io.Copy(w, r); err != nil {
+			return err
+		}
+		return w.Close()
+	}
+	return fmt.Errorf("%s has no %s executable", archive, FlavorTofu)
+}
+
+func download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// checksum returns the checksum of file in a SHA256SUMS file.
+func checksum(sums []byte, file string) (string, error) {
+	s := bufio.NewScanner(bytes.NewReader(sums))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 2 && fields[1] == file {
+			return fields[0], nil
+		}
+	}
+	if err := s.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no checksum for %s", file)
+}
+
+// exactVersion returns the version when the constraint pins a single version,
+// e.g. "1.8.2" or "= 1.8.2".
+func exactVersion(constraint string) (*version.Version, bool) {
+	v, err := version.NewVersion(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(constraint), "=")))
+	if err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// binaryVersion returns the version of the executable at path.
+func binaryVersion(ctx context.Context, path string) (*version.Version, error) {
+	tf, err := tfexec.NewTerraform(os.TempDir(), path)
+	if err != nil {
+		return nil, err
+	}
+	v, _, err := tf.Version(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("getting the version of %s: %w", path, err)
+	}
+	return v, nil
+}
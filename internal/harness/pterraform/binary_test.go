@@ -0,0 +1,125 @@
+package pterraform
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+)
+
+func TestExactVersion(t *testing.T) {
+	for constraint, want := range map[string]string{
+		"1.8.2":      "1.8.2",
+		"= 1.8.2":    "1.8.2",
+		">= 1.8.2":   "",
+		"~> 1.8":     "",
+		"1.8, < 1.9": "",
+	} {
+		got := ""
+		if v, ok := exactVersion(constraint); ok {
+			got = v.String()
+		}
+		if got != want {
+			t.Errorf("exactVersion(%q) = %q, want %q", constraint, got, want)
+		}
+	}
+}
+
+func TestBinaryCached(t *testing.T) {
+	dir := t.TempDir()
+	for _, v := range []string{"1.7.0", "1.8.2", "2.0.0"} {
+		writeExecutable(t, filepath.Join(dir, FlavorTofu, v, FlavorTofu))
+	}
+	// A version without an executable is an incomplete install
+	if err := os.MkdirAll(filepath.Join(dir, FlavorTofu, "1.9.0"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	b := Binary{Flavor: FlavorTofu, CacheDir: dir}
+
+	path, ok := b.cached(version.MustConstraints(version.NewConstraint(">= 1.7, < 2.0")))
+	if want := filepath.Join(dir, FlavorTofu, "1.8.2", FlavorTofu); !ok || path != want {
+		t.Errorf("cached() = %q, %t, want %q", path, ok, want)
+	}
+
+	if path, ok := b.cached(version.MustConstraints(version.NewConstraint("< 1.0"))); ok {
+		t.Errorf("cached() = %q, want no match", path)
+	}
+}
+
+func TestBinaryInstallTofu(t *testing.T) {
+	var zbuf bytes.Buffer
+	zw := zip.NewWriter(&zbuf)
+	f, err := zw.Create(FlavorTofu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("#!/bin/sh\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := fmt.Sprintf("tofu_1.8.2_%s_%s.zip", runtime.GOOS, runtime.GOARCH)
+	sums := fmt.Sprintf("%x  %s\n", sha256.Sum256(zbuf.Bytes()), archive)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.8.2/tofu_1.8.2_SHA256SUMS", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(sums))
+	})
+	mux.HandleFunc("/v1.8.2/"+archive, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(zbuf.Bytes())
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	orig := tofuReleasesURL
+	tofuReleasesURL = srv.URL
+	defer func() { tofuReleasesURL = orig }()
+
+	// Keep any tofu on the host out of the way
+	t.Setenv("PATH", "")
+
+	dir := t.TempDir()
+	b := Binary{Flavor: FlavorTofu, Version: "1.8.2", CacheDir: dir}
+
+	path, err := b.resolve(t.Context())
+	if err != nil {
+		t.Fatalf("resolve() = %v", err)
+	}
+	if want := filepath.Join(dir, FlavorTofu, "1.8.2", FlavorTofu); path != want {
+		t.Errorf("resolve() = %q, want %q", path, want)
+	}
+	if _, ok := b.cached(version.MustConstraints(version.NewConstraint("1.8.2"))); !ok {
+		t.Error("cached() found no install after resolve()")
+	}
+
+	if _, err := (Binary{Flavor: FlavorTofu, Version: "1.8.3", CacheDir: dir}).resolve(t.Context()); err == nil {
+		t.Error("resolve() of a version without a release succeeded, want an error")
+	}
+	if _, err := (Binary{Flavor: FlavorTofu, Version: ">= 1.9", CacheDir: dir}).resolve(t.Context()); err == nil {
+		t.Error("resolve() of an unpinned missing version succeeded, want an error")
+	}
+	if _, err := (Binary{Flavor: "pulumi"}).resolve(t.Context()); err == nil {
+		t.Error("resolve() of an unknown flavor succeeded, want an error")
+	}
+}
+
+func writeExecutable(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 type Connection struct {
 	Kubernetes *KubernetesConnection `json:"kubernetes"`
 	Docker     *DockerConnection     `json:"docker"`
+	SSH        *SSHConnection        `json:"ssh"`
 	// Retry is the retry configuration for the connection
 	Retry *ConnectionRetry `json:"retry"`
 
@@ -5,6 +5,7 @@ import (
 
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/sandbox"
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/sandbox/k8s"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
@@ -13,6 +14,16 @@ type KubernetesConnection struct {
 	Kubeconfig     string `json:"kubeconfig"`
 	KubeconfigPath string `json:"kubeconfig_path"`
 	SandboxImage   string `json:"sandbox_image"`
+
+	// ServiceAccount is an existing service account to run the sandbox as,
+	// otherwise one bound to cluster-admin is created
+	ServiceAccount string                  `json:"service_account"`
+	Resources      sandbox.ResourceRequest `json:"resources"`
+	NodeSelector   map[string]string       `json:"node_selector"`
+	Tolerations    []corev1.Toleration     `json:"tolerations"`
+	Volumes        []k8s.Volume            `json:"volumes"`
+	EnvFromSecrets []string                `json:"env_from_secrets"`
+	Labels         map[string]string       `json:"labels"`
 }
 
 func (k *KubernetesConnection) runner() (sandbox.Sandbox, error) {
@@ -22,6 +33,13 @@ func (k *KubernetesConnection) runner() (sandbox.Sandbox, error) {
 	}
 	return k8s.NewFromConfig(cfg,
 		k8s.WithRawImageRef(k.SandboxImage),
+		k8s.WithServiceAccount(k.ServiceAccount),
+		k8s.WithResources(k.Resources),
+		k8s.WithNodeSelector(k.NodeSelector),
+		k8s.WithTolerations(k.Tolerations...),
+		k8s.WithVolumes(k.Volumes...),
+		k8s.WithEnvFromSecrets(k.EnvFromSecrets...),
+		k8s.WithLabels(k.Labels),
 	)
 }
 
@@ -4,14 +4,18 @@
 package pterraform
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -19,6 +23,7 @@ import (
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/log"
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/sandbox"
 	"github.com/hashicorp/terraform-exec/tfexec"
+	tfjson "github.com/hashicorp/terraform-json"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
@@ -33,10 +38,27 @@ type pterraform struct {
 	// copy of the source FS
 	work string
 
-	tf    *tfexec.Terraform
-	stack *harness.Stack
+	binary Binary
+	tf     *tfexec.Terraform
+	stack  *harness.Stack
+
+	// stateDir persists the terraform state across runs when set, and
+	// keepInfra skips destroying the infrastructure on teardown
+	stateDir  string
+	keepInfra bool
+	// drift lists the changes applied to the persisted infrastructure
+	drift []string
 
 	runner sandbox.Runner
+
+	// outputs are the terraform outputs other than the connection, keyed by
+	// output name
+	outputs map[string]output
+}
+
+type output struct {
+	value     string
+	sensitive bool
 }
 
 func New(ctx context.Context, source fs.FS, opts ...Option) (*pterraform, error) {
@@ -64,9 +86,14 @@ func New(ctx context.Context, source fs.FS, opts ...Option) (*pterraform, error)
 		}
 	}
 
-	tf, err := tfexec.NewTerraform(p.work, "terraform")
+	execPath, err := p.binary.resolve(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find a terraform executable on $PATH: %w", err)
+		return nil, err
+	}
+
+	tf, err := tfexec.NewTerraform(p.work, execPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a %s executor: %w", p.binary.flavor(), err)
 	}
 	p.tf = tf
 	p.tf.SetStdout(io.Discard)
@@ -99,6 +126,10 @@ func New(ctx context.Context, source fs.FS, opts ...Option) (*pterraform, error)
 		return nil, fmt.Errorf("setting environment variables: %w", err)
 	}
 
+	if err := p.binary.verify(ctx, p.tf); err != nil {
+		return nil, err
+	}
+
 	return p, nil
 }
 
@@ -223,16 +254,17 @@ func (p *pterraform) Create(ctx context.Context) error {
 		return fmt.Errorf("adding terraform destroy to stack: %w", err)
 	}
 
-	if err := p.tf.Init(ctx, initopts...); err != nil {
-		return fmt.Errorf("failed to initialize terraform: %w", err)
+	if p.stateDir != "" {
+		if err := p.writeBackend(); err != nil {
+			return err
+		}
 	}
 
-	applyopts := []tfexec.ApplyOption{}
-
-	for _, opt := range p.evars() {
-		applyopts = append(applyopts, opt)
+	if err := p.tf.Init(ctx, initopts...); err != nil {
+		return fmt.Errorf("failed to initialize terraform: %w", err)
 	}
 
+	varFile := ""
 	if p.vars != nil {
 		// Write the vars as a vars.tf.json file
 		vdata, err := json.Marshal(p.vars)
@@ -242,21 +274,37 @@ func (p *pterraform) Create(ctx context.Context) error {
 		if err := os.WriteFile(filepath.Join(p.work, "vars.tfvars.json"), vdata, 0o644); err != nil {
 			return err
 		}
-		applyopts = append(applyopts, tfexec.VarFile("vars.tfvars.json"))
+		varFile = "vars.tfvars.json"
 	}
 
-	if err := p.tf.Apply(ctx, applyopts...); err != nil {
-		return fmt.Errorf("failed to apply terraform: %w", err)
+	if p.stateDir != "" {
+		if err := p.planAndApply(ctx, varFile); err != nil {
+			return err
+		}
+	} else {
+		applyopts := []tfexec.ApplyOption{}
+		for _, opt := range p.evars() {
+			applyopts = append(applyopts, opt)
+		}
+		if varFile != "" {
+			applyopts = append(applyopts, tfexec.VarFile(varFile))
+		}
+
+		if err := p.tf.Apply(ctx, applyopts...); err != nil {
+			return fmt.Errorf("failed to apply terraform: %w", err)
+		}
 	}
 
-	if err := p.stack.Add(func(ctx context.Context) error {
-		destroyopts := []tfexec.DestroyOption{}
-		for _, opt := range p.evars() {
-			destroyopts = append(destroyopts, opt)
+	if !p.keepInfra {
+		if err := p.stack.Add(func(ctx context.Context) error {
+			destroyopts := []tfexec.DestroyOption{}
+			for _, opt := range p.evars() {
+				destroyopts = append(destroyopts, opt)
+			}
+			return p.tf.Destroy(ctx, destroyopts...)
+		}); err != nil {
+			return fmt.Errorf("adding terraform destroy to stack: %w", err)
 		}
-		return p.tf.Destroy(ctx, destroyopts...)
-	}); err != nil {
-		return fmt.Errorf("adding terraform destroy to stack: %w", err)
 	}
 
 	out, err := p.tf.Output(ctx)
@@ -269,6 +317,20 @@ func (p *pterraform) Create(ctx context.Context) error {
 		return fmt.Errorf("no connection output")
 	}
 
+	p.outputs, err = parseOutputs(out)
+	if err != nil {
+		return err
+	}
+
+	logged := make(map[string]string, len(p.outputs))
+	for k, o := range p.outputs {
+		logged[k] = o.value
+		if o.sensitive {
+			logged[k] = "(sensitive)"
+		}
+	}
+	log.Info(ctx, "terraform outputs", "outputs", logged)
+
 	var conn *Connection
 	if err := json.Unmarshal(connectionRaw.Value, &conn); err != nil {
 		return fmt.Errorf("decoding connection details: %w", err)
@@ -333,6 +395,43 @@ func (p *pterraform) Create(ctx context.Context) error {
 			return fmt.Errorf("waiting for kubernetes connection to be ready: %w", err)
 		}
 
+	} else if conn.SSH != nil {
+		if conn.SSH.PrivateKeyPath != "" && !filepath.IsAbs(conn.SSH.PrivateKeyPath) {
+			conn.SSH.PrivateKeyPath = filepath.Join(p.work, conn.SSH.PrivateKeyPath)
+		}
+
+		if conn.SSH.User == "" {
+			return fmt.Errorf("ssh connection requires a user")
+		}
+
+		signer, err := conn.SSH.signer()
+		if err != nil {
+			return err
+		}
+		hostKeys, err := conn.SSH.hostKeys()
+		if err != nil {
+			return err
+		}
+
+		var c *sshConnector
+		if err := wait.ExponentialBackoffWithContext(ctx, conn.backoff, func(ctx context.Context) (bool, error) {
+			c, err = newSSHRunner(conn.SSH, signer, hostKeys)
+			if err != nil {
+				log.Warn(ctx, "failed to create ssh runner", "error", err)
+				return false, nil
+			}
+			return true, nil
+		}); err != nil {
+			return fmt.Errorf("waiting for ssh connection to be ready: %w", err)
+		}
+		p.runner = c
+
+		if err := p.stack.Add(func(context.Context) error {
+			return c.Close()
+		}); err != nil {
+			return fmt.Errorf("adding ssh connection teardown to stack: %w", err)
+		}
+
 	} else {
 		return fmt.Errorf("unknown connection type")
 	}
@@ -340,9 +439,190 @@ func (p *pterraform) Create(ctx context.Context) error {
 	return nil
 }
 
-// Run implements harness.Harness.
+// Run implements harness.Harness. The terraform outputs are exported as
+// environment variables of the command.
 func (p *pterraform) Run(ctx context.Context, cmd harness.Command) error {
-	return p.runner.Run(ctx, cmd)
+	args := cmd.Args
+	cmd.Args = p.env() + args
+
+	err := p.runner.Run(ctx, cmd)
+
+	var rerr *harness.RunError
+	if errors.As(err, &rerr) {
+		// report the command as given, without the exported outputs
+		rerr.Cmd = args
+		rerr.CombinedOutput = p.Redact(rerr.CombinedOutput)
+	}
+	return err
+}
+
+// backendFile configures the local backend keeping the persisted state.
+const backendFile = "imagetest_backend.tf.json"
+
+// writeBackend points the local backend at the state directory. The module
+// must not configure a backend of its own.
+func (p *pterraform) writeBackend() error {
+	if err := os.MkdirAll(p.stateDir, 0o755); err != nil {
+		return fmt.Errorf("creating state directory: %w", err)
+	}
+
+	statePath, err := filepath.Abs(filepath.Join(p.stateDir, "terraform.tfstate"))
+	if err != nil {
+		return fmt.Errorf("resolving state path: %w", err)
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"terraform": map[string]any{
+			"backend": map[string]any{
+				"local": map[string]any{"path": statePath},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(p.work, backendFile), data, 0o644)
+}
+
+// planAndApply plans against the persisted state, and only applies when the
+// plan has changes. Changes to infrastructure created by an earlier run are
+// reported as drift.
+func (p *pterraform) planAndApply(ctx context.Context, varFile string) error {
+	const planFile = "imagetest.tfplan"
+	planopts := []tfexec.PlanOption{tfexec.Out(planFile)}
+	for _, opt := range p.evars() {
+		planopts = append(planopts, opt)
+	}
+	if varFile != "" {
+		planopts = append(planopts, tfexec.VarFile(varFile))
+	}
+
+	changed, err := p.tf.Plan(ctx, planopts...)
+	if err != nil {
+		return fmt.Errorf("failed to plan terraform: %w", err)
+	}
+	if !changed {
+		log.Info(ctx, "terraform state is up to date, skipping apply", "state_dir", p.stateDir)
+		return nil
+	}
+
+	plan, err := p.tf.ShowPlanFile(ctx, planFile)
+	if err != nil {
+		return fmt.Errorf("failed to show terraform plan: %w", err)
+	}
+	if hasResources(plan.PriorState) {
+		p.drift = planChanges(plan)
+		log.Warn(ctx, "terraform state drifted, applying changes", "state_dir", p.stateDir, "changes", p.drift)
+	}
+
+	// Variables are baked into the saved plan
+	if err := p.tf.Apply(ctx, tfexec.DirOrPlan(planFile)); err != nil {
+		return fmt.Errorf("failed to apply terraform: %w", err)
+	}
+	return nil
+}
+
+// planChanges lists the resources the plan changes, with their actions.
+func planChanges(plan *tfjson.Plan) []string {
+	var changes []string
+	for _, rc := range plan.ResourceChanges {
+		if rc.Change == nil || rc.Change.Actions.NoOp() || rc.Change.Actions.Read() {
+			continue
+		}
+		actions := make([]string, 0, len(rc.Change.Actions))
+		for _, a := range rc.Change.Actions {
+			actions = append(actions, string(a))
+		}
+		changes = append(changes, fmt.Sprintf("%s (%s)", rc.Address, strings.Join(actions, ", ")))
+	}
+	return changes
+}
+
+// hasResources reports whether the state holds any infrastructure.
+func hasResources(state *tfjson.State) bool {
+	if state == nil || state.Values == nil || state.Values.RootModule == nil {
+		return false
+	}
+	return len(state.Values.RootModule.Resources) > 0 || len(state.Values.RootModule.ChildModules) > 0
+}
+
+// Drift returns the changes applied to infrastructure persisted by an earlier
+// run, if any.
+func (p *pterraform) Drift() []string {
+	return p.drift
+}
+
+// Outputs returns the terraform outputs other than the connection, keyed by
+// output name. Values that aren't strings are JSON encoded.
+func (p *pterraform) Outputs() map[string]string {
+	outputs := make(map[string]string, len(p.outputs))
+	for k, o := range p.outputs {
+		outputs[k] = o.value
+	}
+	return outputs
+}
+
+// invalidEnvChars matches the characters of output names the shell doesn't
+// accept in variable names.
+var invalidEnvChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// envName returns the name of the variable an output is exported as.
+// Characters that aren't valid in variable names, such as '-', are replaced
+// by '_'.
+func envName(output string) string {
+	return invalidEnvChars.ReplaceAllString(output, "_")
+}
+
+// env returns the shell prelude exporting the outputs.
+func (p *pterraform) env() string {
+	vars := make(map[string]string, len(p.outputs))
+	for k, o := range p.outputs {
+		vars[envName(k)] = o.value
+	}
+	return harness.ExportEnv(vars)
+}
+
+// Redact implements harness.Redactor, masking the values of sensitive
+// outputs in s. Values too short to be secrets, such as booleans, are left
+// alone.
+func (p *pterraform) Redact(s string) string {
+	var values []string
+	for _, o := range p.outputs {
+		if o.sensitive {
+			values = append(values, o.value)
+		}
+	}
+	return harness.Mask(s, values...)
+}
+
+// parseOutputs converts the terraform outputs other than the connection to
+// strings. Outputs whose names would be exported as the same variable are
+// rejected.
+func parseOutputs(out map[string]tfexec.OutputMeta) (map[string]output, error) {
+	outputs := make(map[string]output, len(out))
+	names := make(map[string]string, len(out))
+	for _, k := range slices.Sorted(maps.Keys(out)) {
+		meta := out[k]
+		if k == "connection" {
+			continue
+		}
+
+		if other, ok := names[envName(k)]; ok {
+			return nil, fmt.Errorf("outputs %q and %q are both exported as %s, rename one of them", other, k, envName(k))
+		}
+		names[envName(k)] = k
+
+		var value string
+		if err := json.Unmarshal(meta.Value, &value); err != nil {
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, meta.Value); err != nil {
+				return nil, fmt.Errorf("decoding output %q: %w", k, err)
+			}
+			value = buf.String()
+		}
+		outputs[k] = output{value: value, sensitive: meta.Sensitive}
+	}
+	return outputs, nil
 }
 
 func (p *pterraform) Destroy(ctx context.Context) error {
@@ -390,3 +670,22 @@ func WithVars(vars json.RawMessage) Option {
 		return nil
 	}
 }
+
+// WithBinary selects the terraform executable to run.
+func WithBinary(binary Binary) Option {
+	return func(p *pterraform) error {
+		p.binary = binary
+		return nil
+	}
+}
+
+// WithPersistentState keeps the terraform state in dir across runs, so
+// unchanged infrastructure isn't applied again. When destroy is false, the
+// infrastructure is kept on teardown for the next run to reuse.
+func WithPersistentState(dir string, destroy bool) Option {
+	return func(p *pterraform) error {
+		p.stateDir = dir
+		p.keepInfra = !destroy
+		return nil
+	}
+}
@@ -1,11 +1,19 @@
 package pterraform
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
+
+	issh "github.com/chainguard-dev/terraform-provider-imagetest/internal/ssh"
+	"github.com/hashicorp/terraform-exec/tfexec"
+	tfjson "github.com/hashicorp/terraform-json"
+	"golang.org/x/crypto/ssh"
 )
 
 func TestPterraform(t *testing.T) {
@@ -160,3 +168,163 @@ func sourceFs(t *testing.T, content string) fs.FS {
 
 	return os.DirFS(dir)
 }
+
+func TestOutputs(t *testing.T) {
+	outputs, err := parseOutputs(map[string]tfexec.OutputMeta{
+		"connection": {Value: json.RawMessage(`{"docker":{"cid":"foo"}}`)},
+		"endpoint":   {Value: json.RawMessage(`"https://example.com"`)},
+		"node-ips":   {Value: json.RawMessage(`[ "10.0.0.1", "10.0.0.2" ]`)},
+		"password":   {Value: json.RawMessage(`"it's secret"`), Sensitive: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &pterraform{outputs: outputs}
+
+	want := map[string]string{
+		"endpoint": "https://example.com",
+		"node-ips": `["10.0.0.1","10.0.0.2"]`,
+		"password": "it's secret",
+	}
+	if got := p.Outputs(); !maps.Equal(got, want) {
+		t.Errorf("Outputs() = %v, want %v", got, want)
+	}
+
+	wantEnv := "export endpoint=https://example.com\n" +
+		`export node_ips=\[\"10.0.0.1\",\"10.0.0.2\"]` + "\n" +
+		"export password='it'\\''s secret'\n"
+	if got := p.env(); got != wantEnv {
+		t.Errorf("env() = %q, want %q", got, wantEnv)
+	}
+
+	if got, want := p.Redact("the password is it's secret"), "the password is (sensitive)"; got != want {
+		t.Errorf("Redact() = %q, want %q", got, want)
+	}
+
+	p.outputs["enabled"] = output{value: "1", sensitive: true}
+	if got, want := p.Redact("1 password is it's secret"), "1 password is (sensitive)"; got != want {
+		t.Errorf("Redact() with a short value = %q, want %q", got, want)
+	}
+
+	if _, err := parseOutputs(map[string]tfexec.OutputMeta{
+		"node-ips": {Value: json.RawMessage(`"10.0.0.1"`)},
+		"node_ips": {Value: json.RawMessage(`"10.0.0.2"`)},
+	}); err == nil {
+		t.Error("parseOutputs() with colliding names succeeded, want an error")
+	}
+}
+
+func TestSSHConnection(t *testing.T) {
+	keys, err := issh.NewED25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := keys.Private.MarshalOpenSSH("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := keys.Public.MarshalOpenSSH()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(path, priv, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, conn := range []SSHConnection{
+		{PrivateKey: string(priv)},
+		{PrivateKeyPath: path},
+	} {
+		signer, err := conn.signer()
+		if err != nil {
+			t.Fatalf("signer() = %v", err)
+		}
+		if got, want := string(ssh.MarshalAuthorizedKey(signer.PublicKey())), string(pub); got != want {
+			t.Errorf("signer() public key = %q, want %q", got, want)
+		}
+	}
+
+	if _, err := (SSHConnection{}).signer(); err == nil {
+		t.Error("signer() without a key succeeded, want an error")
+	}
+	if _, err := (SSHConnection{PrivateKey: string(priv), PrivateKeyPath: path}).signer(); err == nil {
+		t.Error("signer() with both keys succeeded, want an error")
+	}
+
+	hostKeys, err := SSHConnection{HostKey: string(pub)}.hostKeys()
+	if err != nil {
+		t.Fatalf("hostKeys() = %v", err)
+	}
+	if len(hostKeys) != 1 {
+		t.Errorf("hostKeys() returned %d keys, want 1", len(hostKeys))
+	}
+	if _, err := (SSHConnection{HostKey: "not a key"}).hostKeys(); err == nil {
+		t.Error("hostKeys() with an invalid key succeeded, want an error")
+	}
+	if _, err := (SSHConnection{}).hostKeys(); err == nil {
+		t.Error("hostKeys() without a host key succeeded, want an error")
+	}
+	if hostKeys, err := (SSHConnection{InsecureIgnoreHostKey: true}).hostKeys(); err != nil || len(hostKeys) != 0 {
+		t.Errorf("hostKeys() with insecure_ignore_host_key = %v, %v, want no keys", hostKeys, err)
+	}
+}
+
+func TestKubernetesConnection(t *testing.T) {
+	raw := `{
+		"kubernetes": {
+			"kubeconfig_path": "kubeconfig",
+			"service_account": "tester",
+			"resources": {"requests": {"cpu": "500m"}, "limits": {"memory": "1Gi"}},
+			"node_selector": {"kubernetes.io/arch": "arm64"},
+			"tolerations": [{"key": "gpu", "operator": "Exists", "effect": "NoSchedule"}],
+			"volumes": [{"name": "config", "mount_path": "/config", "config_map": "settings"}],
+			"env_from_secrets": ["tokens"]
+		}
+	}`
+
+	var conn Connection
+	if err := json.Unmarshal([]byte(raw), &conn); err != nil {
+		t.Fatal(err)
+	}
+
+	k := conn.Kubernetes
+	if k.ServiceAccount != "tester" {
+		t.Errorf("service account = %q, want tester", k.ServiceAccount)
+	}
+	if q := k.Resources.Requests["cpu"]; q.String() != "500m" {
+		t.Errorf("cpu request = %s, want 500m", q.String())
+	}
+	if q := k.Resources.Limits["memory"]; q.String() != "1Gi" {
+		t.Errorf("memory limit = %s, want 1Gi", q.String())
+	}
+	if k.NodeSelector["kubernetes.io/arch"] != "arm64" {
+		t.Errorf("node selector = %v, want kubernetes.io/arch=arm64", k.NodeSelector)
+	}
+	if len(k.Tolerations) != 1 || k.Tolerations[0].Key != "gpu" || k.Tolerations[0].Effect != "NoSchedule" {
+		t.Errorf("tolerations = %+v, want the gpu toleration", k.Tolerations)
+	}
+	if len(k.Volumes) != 1 || k.Volumes[0].ConfigMap != "settings" || k.Volumes[0].MountPath != "/config" {
+		t.Errorf("volumes = %+v, want the settings ConfigMap", k.Volumes)
+	}
+	if !slices.Equal(k.EnvFromSecrets, []string{"tokens"}) {
+		t.Errorf("env from secrets = %v, want [tokens]", k.EnvFromSecrets)
+	}
+}
+
+func TestPlanChanges(t *testing.T) {
+	plan := &tfjson.Plan{
+		ResourceChanges: []*tfjson.ResourceChange{
+			{Address: "terraform_data.same", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionNoop}}},
+			{Address: "data.http.lookup", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionRead}}},
+			{Address: "terraform_data.new", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionCreate}}},
+			{Address: "terraform_data.vm", Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate}}},
+		},
+	}
+
+	want := []string{"terraform_data.new (create)", "terraform_data.vm (delete, create)"}
+	if got := planChanges(plan); !slices.Equal(got, want) {
+		t.Errorf("planChanges() = %v, want %v", got, want)
+	}
+}
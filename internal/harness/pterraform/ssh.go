@@ -0,0 +1,134 @@
+package pterraform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/harness"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/sandbox"
+	issh "github.com/chainguard-dev/terraform-provider-imagetest/internal/ssh"
+	"github.com/kballard/go-shellquote"
+	"golang.org/x/crypto/ssh"
+)
+
+var _ sandbox.Runner = &sshConnector{}
+
+type SSHConnection struct {
+	Host string `json:"host"`
+	Port uint16 `json:"port"`
+	User string `json:"user"`
+	// PrivateKey is the PEM encoded private key used to authenticate,
+	// PrivateKeyPath is read when it is empty
+	PrivateKey     string `json:"private_key"`
+	PrivateKeyPath string `json:"private_key_path"`
+	// HostKey is the host's public key in the authorized_keys format. It is
+	// required unless InsecureIgnoreHostKey is set.
+	HostKey string `json:"host_key"`
+	// InsecureIgnoreHostKey accepts any host key when HostKey is empty
+	InsecureIgnoreHostKey bool `json:"insecure_ignore_host_key"`
+	// Shell runs the commands, defaulting to sh
+	Shell string `json:"shell"`
+}
+
+// sshConnector is a connector that runs commands on a host over SSH.
+type sshConnector struct {
+	client *ssh.Client
+	shell  issh.Shell
+}
+
+func (c SSHConnection) signer() (ssh.Signer, error) {
+	if c.PrivateKey != "" && c.PrivateKeyPath != "" {
+		return nil, fmt.Errorf("only one of private_key or private_key_path can be set")
+	}
+
+	key := []byte(c.PrivateKey)
+	if c.PrivateKeyPath != "" {
+		var err error
+		key, err = os.ReadFile(c.PrivateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading private key: %w", err)
+		}
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("one of private_key or private_key_path is required")
+	}
+
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key: %w", err)
+	}
+	return signer, nil
+}
+
+func (c SSHConnection) hostKeys() ([]ssh.PublicKey, error) {
+	if c.HostKey == "" {
+		if !c.InsecureIgnoreHostKey {
+			return nil, fmt.Errorf("ssh connection requires a host_key, or insecure_ignore_host_key to accept any host key")
+		}
+		return nil, nil
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(c.HostKey))
+	if err != nil {
+		return nil, fmt.Errorf("parsing host key: %w", err)
+	}
+	return []ssh.PublicKey{key}, nil
+}
+
+func newSSHRunner(cfg *SSHConnection, signer ssh.Signer, hostKeys []ssh.PublicKey) (*sshConnector, error) {
+	client, err := issh.Connect(cfg.Host, cfg.Port, cfg.User, signer, hostKeys...)
+	if err != nil {
+		return nil, err
+	}
+
+	shell := cfg.Shell
+	if shell == "" {
+		shell = issh.ShellSh
+	}
+
+	return &sshConnector{
+		client: client,
+		shell:  shell,
+	}, nil
+}
+
+// Run implements Connector.
+func (c *sshConnector) Run(ctx context.Context, cmd harness.Command) error {
+	var stdall bytes.Buffer
+	stdout, stderr := io.Writer(&stdall), io.Writer(&stdall)
+	if cmd.Stdout != nil {
+		stdout = io.MultiWriter(&stdall, cmd.Stdout)
+	}
+	if cmd.Stderr != nil {
+		stderr = io.MultiWriter(&stdall, cmd.Stderr)
+	}
+
+	cmds := []string{}
+	if cmd.WorkingDir != "" {
+		cmds = append(cmds, fmt.Sprintf("cd %s || exit 1", shellquote.Join(cmd.WorkingDir)))
+	}
+	cmds = append(cmds, cmd.Args)
+
+	// the session is closed when ctx is done, so the command doesn't outlive it
+	err := issh.ExecInContext(ctx, c.client, c.shell, stdout, stderr, cmds...)
+	if ctx.Err() != nil {
+		return fmt.Errorf("context cancelled while waiting for command to finish: %w", ctx.Err())
+	}
+
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return &harness.RunError{
+			ExitCode:       exitErr.ExitStatus(),
+			CombinedOutput: stdall.String(),
+			Cmd:            cmd.Args,
+		}
+	}
+	return err
+}
+
+func (c *sshConnector) Close() error {
+	return c.client.Close()
+}
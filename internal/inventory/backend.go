@@ -0,0 +1,27 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrConflict is returned by a Backend when a document changed since it was
+// read.
+var ErrConflict = errors.New("inventory document was modified concurrently")
+
+// Backend stores the documents of an inventory, one per harness. Writes are
+// conditional on the revision the document was read at, so processes sharing
+// a backend never lose each other's updates.
+type Backend interface {
+	// Get returns the document at key and its revision. The error wraps
+	// fs.ErrNotExist when there is no such document.
+	Get(ctx context.Context, key string) ([]byte, string, error)
+	// Put writes the document at key when its revision is still rev. An empty
+	// rev only creates the document. It returns ErrConflict otherwise.
+	Put(ctx context.Context, key string, data []byte, rev string) error
+	// Delete removes the document at key when its revision is still rev. It
+	// returns ErrConflict otherwise.
+	Delete(ctx context.Context, key string, rev string) error
+	// List returns the keys of all the documents.
+	List(ctx context.Context) ([]string, error)
+}
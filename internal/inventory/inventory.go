@@ -3,20 +3,45 @@ package inventory
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io/fs"
 	"sync"
+	"time"
 )
 
+// maxAttempts bounds the retries of an update conflicting with another
+// process.
+const maxAttempts = 20
+
+// ErrLeased is returned when another process holds the lease on a harness.
+var ErrLeased = errors.New("harness is leased by another process")
+
 type Inventory struct {
-	base string
-	mu   sync.RWMutex
+	backend Backend
+	mu      sync.RWMutex
 }
 
 type Harness struct {
-	Id       string             `json:"id"`
+	Id string `json:"id"`
+	// Created is when the harness was added, it's zero for harnesses added
+	// before it was recorded
+	Created  time.Time          `json:"created"`
 	Features map[string]Feature `json:"features"`
+	// Lease is set while a process removes the harness
+	Lease *Lease `json:"lease,omitempty"`
+}
+
+// Lease reserves a harness for the process removing it. Until the lease
+// expires, no other process may lease the harness or add features to it.
+type Lease struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+// held reports whether the lease hasn't expired at now.
+func (l *Lease) held(now time.Time) bool {
+	return l != nil && now.Before(l.Expires)
 }
 
 type Feature struct {
@@ -24,164 +49,252 @@ type Feature struct {
 	Skipped string `json:"skipped"` // Either the reason for skipping or an empty string
 }
 
+// NewInventory returns an inventory stored in the local base directory.
 func NewInventory(base string) (*Inventory, error) {
-	if err := os.MkdirAll(base, 0o755); err != nil {
-		return nil, fmt.Errorf("failed to create inventory base directory: %w", err)
+	b, err := NewLocalBackend(base)
+	if err != nil {
+		return nil, err
 	}
+	return New(b), nil
+}
 
+// New returns an inventory stored in the backend.
+func New(backend Backend) *Inventory {
 	return &Inventory{
-		base: base,
-		mu:   sync.RWMutex{},
-	}, nil
+		backend: backend,
+		mu:      sync.RWMutex{},
+	}
 }
 
 // AddHarness creates a new harness with the given id. If the harness already exists this is a no-op.
 func (i *Inventory) AddHarness(ctx context.Context, id string) error {
-	hpath := i.harnessPath(id)
+	i.mu.Lock()
+	defer i.mu.Unlock()
 
-	if _, err := os.Stat(hpath); err == nil {
-		return nil
+	data, err := json.Marshal(Harness{
+		Id:       id,
+		Created:  time.Now().UTC(),
+		Features: make(map[string]Feature),
+	})
+	if err != nil {
+		return err
 	}
 
-	f, err := os.OpenFile(hpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
-	if err != nil {
+	err = i.backend.Put(ctx, id, data, "")
+	if err != nil && !errors.Is(err, ErrConflict) {
 		return fmt.Errorf("failed to create harness: %w", err)
 	}
-	defer f.Close()
-
-	fs := make(map[string]Feature)
-
-	return json.NewEncoder(f).Encode(fs)
+	return nil
 }
 
 // AddFeature adds a feature to an existing harness. It returns an error if the harness does not exist.
 func (i *Inventory) AddFeature(ctx context.Context, harness string, feature Feature) error {
-	hpath := i.harnessPath(harness)
-	if _, err := os.Stat(hpath); err != nil {
-		return fmt.Errorf("harness [%s] does not exist at [%s]: base %s: %v", harness, hpath, i.base, err)
-	}
-
-	fs := make(map[string]Feature)
+	return i.update(ctx, harness, func(fs map[string]Feature) error {
+		fs[feature.Id] = feature
+		return nil
+	})
+}
 
-	i.mu.Lock()
-	defer i.mu.Unlock()
+func (i *Inventory) GetFeatures(ctx context.Context, id string) (map[string]Feature, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
 
-	data, err := os.ReadFile(hpath)
+	h, _, err := i.get(ctx, id)
 	if err != nil {
-		return fmt.Errorf("failed to read harness: %w", err)
-	}
-
-	if err := json.Unmarshal(data, &fs); err != nil {
-		return fmt.Errorf("failed to unmarshal harness: %w", err)
+		return nil, err
 	}
+	return h.Features, nil
+}
 
-	fs[feature.Id] = feature
+// Harnesses returns every harness in the inventory, including the ones left
+// behind by processes that never removed them.
+func (i *Inventory) Harnesses(ctx context.Context) ([]Harness, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
 
-	fw, err := os.OpenFile(hpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	keys, err := i.backend.List(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to open harness: %w", err)
+		return nil, fmt.Errorf("failed to list harnesses: %w", err)
 	}
-	defer fw.Close()
 
-	if err := json.NewEncoder(fw).Encode(fs); err != nil {
-		return fmt.Errorf("failed to encode harness: %w", err)
+	hs := make([]Harness, 0, len(keys))
+	for _, key := range keys {
+		h, _, err := i.get(ctx, key)
+		if errors.Is(err, fs.ErrNotExist) {
+			// Removed since it was listed
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		hs = append(hs, h)
 	}
-
-	return nil
+	return hs, nil
 }
 
-func (i *Inventory) GetFeatures(ctx context.Context, id string) (map[string]Feature, error) {
-	hpath := i.harnessPath(id)
+func (i *Inventory) RemoveHarness(ctx context.Context, id string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
 
-	if _, err := os.Stat(hpath); err != nil {
-		return nil, fmt.Errorf("harness [%s] does not exist at [%s]: %v", id, hpath, err)
-	}
+	return retry(ctx, func() error {
+		h, rev, err := i.get(ctx, id)
+		if err != nil {
+			return err
+		}
 
-	i.mu.RLock()
-	defer i.mu.RUnlock()
+		if len(h.Features) > 0 {
+			return fmt.Errorf("cannot remove harness [%s]: harness contains features", id)
+		}
 
-	f, err := os.Open(hpath)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
+		if err := i.backend.Delete(ctx, id, rev); err != nil {
+			return fmt.Errorf("failed to remove harness [%s]: %w", id, err)
+		}
+		return nil
+	})
+}
 
-	fs := make(map[string]Feature)
+// LeaseHarness reserves the harness for holder until ttl elapses, and
+// returns it as it was leased. It returns ErrLeased when another holder's
+// lease hasn't expired yet.
+func (i *Inventory) LeaseHarness(ctx context.Context, id string, holder string, ttl time.Duration) (Harness, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
 
-	if err := json.NewDecoder(f).Decode(&fs); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal harness: %v", err)
-	}
+	var leased Harness
+	err := retry(ctx, func() error {
+		h, rev, err := i.get(ctx, id)
+		if err != nil {
+			return err
+		}
 
-	return fs, nil
-}
+		now := time.Now().UTC()
+		if h.Lease.held(now) && h.Lease.Holder != holder {
+			return fmt.Errorf("cannot lease harness [%s]: %w", id, ErrLeased)
+		}
+		h.Lease = &Lease{Holder: holder, Expires: now.Add(ttl)}
 
-func (i *Inventory) RemoveHarness(ctx context.Context, id string) error {
-	hpath := i.harnessPath(id)
+		data, err := json.Marshal(h)
+		if err != nil {
+			return fmt.Errorf("failed to encode harness [%s]: %w", id, err)
+		}
 
+		// The write only succeeds if nobody else leased the harness since it
+		// was read
+		if err := i.backend.Put(ctx, id, data, rev); err != nil {
+			return fmt.Errorf("failed to lease harness [%s]: %w", id, err)
+		}
+		leased = h
+		return nil
+	})
+	return leased, err
+}
+
+// PurgeHarness removes the harness along with any features it still has. The
+// harness must be leased by holder.
+func (i *Inventory) PurgeHarness(ctx context.Context, id string, holder string) error {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
-	data, err := os.ReadFile(hpath)
-	if err != nil {
-		return fmt.Errorf("failed to read harness [%s]: %w", id, err)
-	}
-
-	fs := make(map[string]Feature)
-	if err := json.Unmarshal(data, &fs); err != nil {
-		return fmt.Errorf("failed to unmarshal harness [%s]: %w", id, err)
-	}
-
-	if len(fs) > 0 {
-		return fmt.Errorf("cannot remove harness [%s]: harness contains features", id)
-	}
+	return retry(ctx, func() error {
+		h, rev, err := i.get(ctx, id)
+		if err != nil {
+			return err
+		}
 
-	err = os.Remove(hpath)
-	if err != nil {
-		return fmt.Errorf("failed to remove harness [%s]: %w", id, err)
-	}
+		if h.Lease == nil || h.Lease.Holder != holder {
+			return fmt.Errorf("cannot remove harness [%s]: %w", id, ErrLeased)
+		}
 
-	return nil
+		if err := i.backend.Delete(ctx, id, rev); err != nil {
+			return fmt.Errorf("failed to remove harness [%s]: %w", id, err)
+		}
+		return nil
+	})
 }
 
 func (i *Inventory) RemoveFeature(ctx context.Context, harness string, id string) error {
-	hpath := i.harnessPath(harness)
+	return i.update(ctx, harness, func(fs map[string]Feature) error {
+		if _, exists := fs[id]; !exists {
+			return fmt.Errorf("feature [%s] does not exist in harness [%s]", id, harness)
+		}
+		delete(fs, id)
+		return nil
+	})
+}
 
+// update applies fn to the features of an existing harness, retrying when
+// another process updated the harness concurrently.
+func (i *Inventory) update(ctx context.Context, harness string, fn func(map[string]Feature) error) error {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
-	fs := make(map[string]Feature)
+	return retry(ctx, func() error {
+		h, rev, err := i.get(ctx, harness)
+		if err != nil {
+			return err
+		}
 
-	data, err := os.ReadFile(hpath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("harness [%s] does not exist", harness)
+		if h.Lease.held(time.Now()) {
+			return fmt.Errorf("cannot update harness [%s]: %w", harness, ErrLeased)
 		}
-		return fmt.Errorf("failed to read harness [%s]: %w", harness, err)
-	}
 
-	if err := json.Unmarshal(data, &fs); err != nil {
-		return fmt.Errorf("failed to unmarshal harness [%s]: %w", harness, err)
-	}
+		if err := fn(h.Features); err != nil {
+			return err
+		}
 
-	if _, exists := fs[id]; !exists {
-		return fmt.Errorf("feature [%s] does not exist in harness [%s]", id, harness)
-	}
+		data, err := json.Marshal(h)
+		if err != nil {
+			return fmt.Errorf("failed to encode harness [%s]: %w", harness, err)
+		}
 
-	delete(fs, id)
+		if err := i.backend.Put(ctx, harness, data, rev); err != nil {
+			return fmt.Errorf("failed to write harness [%s]: %w", harness, err)
+		}
+		return nil
+	})
+}
 
-	fw, err := os.OpenFile(hpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+// get returns the harness and the revision it was read at.
+func (i *Inventory) get(ctx context.Context, id string) (Harness, string, error) {
+	data, rev, err := i.backend.Get(ctx, id)
 	if err != nil {
-		return fmt.Errorf("failed to open harness [%s] for writing: %w", harness, err)
+		if errors.Is(err, fs.ErrNotExist) {
+			return Harness{}, "", fmt.Errorf("harness [%s] does not exist: %w", id, err)
+		}
+		return Harness{}, "", fmt.Errorf("failed to read harness [%s]: %w", id, err)
 	}
-	defer fw.Close()
 
-	if err := json.NewEncoder(fw).Encode(fs); err != nil {
-		return fmt.Errorf("failed to encode harness [%s]: %w", harness, err)
+	h := Harness{}
+	if err := json.Unmarshal(data, &h); err != nil {
+		return Harness{}, "", fmt.Errorf("failed to unmarshal harness [%s]: %w", id, err)
 	}
 
-	return nil
+	if h.Id == "" {
+		// Harnesses used to be stored as just their features
+		h = Harness{Id: id, Features: make(map[string]Feature)}
+		if err := json.Unmarshal(data, &h.Features); err != nil {
+			return Harness{}, "", fmt.Errorf("failed to unmarshal harness [%s]: %w", id, err)
+		}
+	}
+	if h.Features == nil {
+		h.Features = make(map[string]Feature)
+	}
+	return h, rev, nil
 }
 
-func (i *Inventory) harnessPath(id string) string {
-	return filepath.Join(i.base, id+".json")
+// retry runs fn until it doesn't fail with ErrConflict.
+func retry(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := range maxAttempts {
+		if err = fn(); !errors.Is(err, ErrConflict) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt+1) * 10 * time.Millisecond):
+		}
+	}
+	return err
 }
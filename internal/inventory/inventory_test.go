@@ -1,8 +1,14 @@
 package inventory_test
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/inventory"
 	"github.com/google/go-cmp/cmp"
@@ -307,6 +313,158 @@ func TestInventory_Concurrency(t *testing.T) {
 	}
 }
 
+func TestInventory_SharedDirectory(t *testing.T) {
+	ctx := t.Context()
+	base := t.TempDir()
+
+	// Each inventory stands in for a separate terraform process, sharing
+	// nothing but the directory
+	invs := make([]*inventory.Inventory, 4)
+	for i := range invs {
+		inv, err := inventory.NewInventory(base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		invs[i] = inv
+	}
+
+	if err := invs[0].AddHarness(ctx, "shared"); err != nil {
+		t.Fatal(err)
+	}
+
+	numFeatures := 25
+	g, gctx := errgroup.WithContext(ctx)
+	for i, inv := range invs {
+		for j := range numFeatures {
+			g.Go(func() error {
+				return inv.AddFeature(gctx, "shared", inventory.Feature{Id: fmt.Sprintf("feature-%d-%d", i, j)})
+			})
+		}
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	features, err := invs[len(invs)-1].GetFeatures(ctx, "shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(features), len(invs)*numFeatures; got != want {
+		t.Errorf("got %d features, want %d", got, want)
+	}
+}
+
+func TestInventory_Harnesses(t *testing.T) {
+	ctx := t.Context()
+	base := t.TempDir()
+	inv, err := inventory.NewInventory(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now()
+	if err := inv.AddHarness(ctx, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := inv.AddFeature(ctx, "foo", inventory.Feature{Id: "bar", Skipped: "excluded by label"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A harness written before harnesses recorded their creation time
+	if err := os.WriteFile(filepath.Join(base, "legacy.json"), []byte(`{"baz":{"id":"baz","skipped":""}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	hs, err := inv.Harnesses(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.SortFunc(hs, func(a, b inventory.Harness) int { return strings.Compare(a.Id, b.Id) })
+
+	if len(hs) != 2 {
+		t.Fatalf("Harnesses() returned %d harnesses, want 2", len(hs))
+	}
+	if hs[0].Id != "foo" || hs[0].Created.Before(before) {
+		t.Errorf("Harnesses()[0] = %+v, want foo created after %s", hs[0], before)
+	}
+	if diff := cmp.Diff(map[string]inventory.Feature{"bar": {Id: "bar", Skipped: "excluded by label"}}, hs[0].Features); diff != "" {
+		t.Errorf("Harnesses()[0] features mismatch (-want +got):\n%s", diff)
+	}
+	if hs[1].Id != "legacy" || !hs[1].Created.IsZero() {
+		t.Errorf("Harnesses()[1] = %+v, want legacy with no creation time", hs[1])
+	}
+	if diff := cmp.Diff(map[string]inventory.Feature{"baz": {Id: "baz"}}, hs[1].Features); diff != "" {
+		t.Errorf("Harnesses()[1] features mismatch (-want +got):\n%s", diff)
+	}
+
+	// Legacy harnesses keep working
+	if err := inv.RemoveFeature(ctx, "legacy", "baz"); err != nil {
+		t.Fatal(err)
+	}
+	if err := inv.RemoveHarness(ctx, "legacy"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := inv.LeaseHarness(ctx, "foo", "cleanup", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := inv.PurgeHarness(ctx, "foo", "cleanup"); err != nil {
+		t.Fatalf("PurgeHarness() = %v", err)
+	}
+	if hs, err := inv.Harnesses(ctx); err != nil || len(hs) != 0 {
+		t.Errorf("Harnesses() = %v, %v, want none", hs, err)
+	}
+}
+
+func TestInventory_LeaseHarness(t *testing.T) {
+	ctx := t.Context()
+	inv := tinv(t)
+
+	if err := inv.AddHarness(ctx, "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := inv.PurgeHarness(ctx, "foo", "a"); !errors.Is(err, inventory.ErrLeased) {
+		t.Errorf("PurgeHarness() without a lease = %v, want ErrLeased", err)
+	}
+
+	h, err := inv.LeaseHarness(ctx, "foo", "a", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Lease == nil || h.Lease.Holder != "a" {
+		t.Errorf("LeaseHarness() = %+v, want a lease held by a", h)
+	}
+
+	// The holder may renew its lease, but nobody else may take it
+	if _, err := inv.LeaseHarness(ctx, "foo", "a", time.Minute); err != nil {
+		t.Errorf("LeaseHarness() renewal = %v", err)
+	}
+	if _, err := inv.LeaseHarness(ctx, "foo", "b", time.Minute); !errors.Is(err, inventory.ErrLeased) {
+		t.Errorf("LeaseHarness() by another holder = %v, want ErrLeased", err)
+	}
+	if err := inv.PurgeHarness(ctx, "foo", "b"); !errors.Is(err, inventory.ErrLeased) {
+		t.Errorf("PurgeHarness() by another holder = %v, want ErrLeased", err)
+	}
+	if err := inv.AddFeature(ctx, "foo", inventory.Feature{Id: "bar"}); !errors.Is(err, inventory.ErrLeased) {
+		t.Errorf("AddFeature() to a leased harness = %v, want ErrLeased", err)
+	}
+
+	// An expired lease can be taken over
+	if _, err := inv.LeaseHarness(ctx, "foo", "a", -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inv.LeaseHarness(ctx, "foo", "b", time.Minute); err != nil {
+		t.Errorf("LeaseHarness() of an expired lease = %v", err)
+	}
+	if err := inv.PurgeHarness(ctx, "foo", "a"); !errors.Is(err, inventory.ErrLeased) {
+		t.Errorf("PurgeHarness() by the previous holder = %v, want ErrLeased", err)
+	}
+	if err := inv.PurgeHarness(ctx, "foo", "b"); err != nil {
+		t.Errorf("PurgeHarness() = %v", err)
+	}
+}
+
 func tinv(t *testing.T) *inventory.Inventory {
 	inv, err := inventory.NewInventory(t.TempDir())
 	if err != nil {
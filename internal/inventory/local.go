@@ -0,0 +1,154 @@
+package inventory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockFile serializes the processes sharing a local inventory.
+const lockFile = ".inventory.lock"
+
+var _ Backend = &localBackend{}
+
+// localBackend stores documents as {key}.json files in a directory. Writes
+// hold an exclusive flock on the directory's lock file and reads a shared one,
+// so concurrent terraform processes using the same directory don't race.
+type localBackend struct {
+	dir string
+}
+
+// NewLocalBackend returns a Backend storing documents in dir.
+func NewLocalBackend(dir string) (Backend, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create inventory base directory: %w", err)
+	}
+	return &localBackend{dir: dir}, nil
+}
+
+// Get implements Backend.
+func (l *localBackend) Get(ctx context.Context, key string) ([]byte, string, error) {
+	unlock, err := l.lock(unix.LOCK_SH)
+	if err != nil {
+		return nil, "", err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(l.path(key))
+	if err != nil {
+		return nil, "", err
+	}
+	return data, revision(data), nil
+}
+
+// Put implements Backend.
+func (l *localBackend) Put(ctx context.Context, key string, data []byte, rev string) error {
+	unlock, err := l.lock(unix.LOCK_EX)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := l.check(key, rev); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a partial
+	// document behind
+	tmp, err := os.CreateTemp(l.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), l.path(key))
+}
+
+// Delete implements Backend.
+func (l *localBackend) Delete(ctx context.Context, key string, rev string) error {
+	unlock, err := l.lock(unix.LOCK_EX)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := l.check(key, rev); err != nil {
+		return err
+	}
+	return os.Remove(l.path(key))
+}
+
+// List implements Backend.
+func (l *localBackend) List(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		keys = append(keys, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	return keys, nil
+}
+
+// check fails with ErrConflict when the document's revision isn't rev.
+func (l *localBackend) check(key, rev string) error {
+	data, err := os.ReadFile(l.path(key))
+	switch {
+	case err == nil && revision(data) == rev:
+		return nil
+	case err == nil:
+		return ErrConflict
+	case os.IsNotExist(err) && rev == "":
+		return nil
+	case os.IsNotExist(err):
+		return fmt.Errorf("%w: %w", ErrConflict, fs.ErrNotExist)
+	default:
+		return err
+	}
+}
+
+func (l *localBackend) lock(how int) (func(), error) {
+	f, err := os.OpenFile(filepath.Join(l.dir, lockFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open inventory lock: %w", err)
+	}
+	if err := unix.Flock(int(f.Fd()), how); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock inventory: %w", err)
+	}
+	return func() {
+		_ = unix.Flock(int(f.Fd()), unix.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+func (l *localBackend) path(key string) string {
+	return filepath.Join(l.dir, key+".json")
+}
+
+func revision(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
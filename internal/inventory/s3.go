@@ -0,0 +1,175 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Config locates the inventory in an S3 compatible bucket.
+type S3Config struct {
+	Bucket string
+	// Prefix is prepended to the key of every document, e.g. "imagetest/"
+	Prefix string
+	// Endpoint is the base URL of the S3 API, defaulting to AWS. Buckets are
+	// always addressed path style, e.g. {Endpoint}/{Bucket}/{Key}, so MinIO and
+	// other S3 compatible stores work.
+	Endpoint string
+	Region   string
+}
+
+var _ Backend = &s3Backend{}
+
+// s3Backend stores documents as {Prefix}{key}.json objects, using the ETag of
+// an object as its revision and conditional requests (If-Match and
+// If-None-Match) to detect concurrent writes.
+type s3Backend struct {
+	cfg    S3Config
+	client *s3.Client
+}
+
+// NewS3Backend returns a Backend storing documents in an S3 bucket, with
+// credentials from the default AWS credential chain.
+func NewS3Backend(ctx context.Context, cfg S3Config) (Backend, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("an s3 inventory requires a bucket")
+	}
+
+	awscfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(cfg.Region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws config: %w", err)
+	}
+	if awscfg.Region == "" {
+		awscfg.Region = "us-east-1"
+	}
+
+	client := s3.NewFromConfig(awscfg, func(o *s3.Options) {
+		o.UsePathStyle = true
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(strings.TrimSuffix(cfg.Endpoint, "/"))
+		}
+		// Not every S3 compatible store accepts the checksums AWS defaults to
+		o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+		o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+	})
+
+	return &s3Backend{
+		cfg:    cfg,
+		client: client,
+	}, nil
+}
+
+// Get implements Backend.
+func (s *s3Backend) Get(ctx context.Context, key string) ([]byte, string, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.cfg.Bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if statusCode(err) == http.StatusNotFound {
+		return nil, "", fmt.Errorf("document %s: %w", key, fs.ErrNotExist)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get document %s: %w", key, err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, aws.ToString(out.ETag), nil
+}
+
+// Put implements Backend.
+func (s *s3Backend) Put(ctx context.Context, key string, data []byte, rev string) error {
+	in := &s3.PutObjectInput{
+		Bucket:      aws.String(s.cfg.Bucket),
+		Key:         aws.String(s.objectKey(key)),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	}
+	if rev == "" {
+		in.IfNoneMatch = aws.String("*")
+	} else {
+		in.IfMatch = aws.String(rev)
+	}
+
+	_, err := s.client.PutObject(ctx, in)
+	switch statusCode(err) {
+	case 0:
+		return nil
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return ErrConflict
+	default:
+		return fmt.Errorf("failed to put document %s: %w", key, err)
+	}
+}
+
+// Delete implements Backend. A document that is already gone counts as
+// deleted.
+func (s *s3Backend) Delete(ctx context.Context, key string, rev string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:  aws.String(s.cfg.Bucket),
+		Key:     aws.String(s.objectKey(key)),
+		IfMatch: aws.String(rev),
+	})
+	switch statusCode(err) {
+	case 0, http.StatusNotFound:
+		return nil
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return ErrConflict
+	default:
+		return fmt.Errorf("failed to delete document %s: %w", key, err)
+	}
+}
+
+// List implements Backend.
+func (s *s3Backend) List(ctx context.Context) ([]string, error) {
+	var keys []string
+	pages := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.cfg.Bucket),
+		Prefix: aws.String(s.cfg.Prefix),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list documents: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			name := strings.TrimPrefix(aws.ToString(obj.Key), s.cfg.Prefix)
+			if strings.Contains(name, "/") || !strings.HasSuffix(name, ".json") {
+				continue
+			}
+			keys = append(keys, strings.TrimSuffix(name, ".json"))
+		}
+	}
+	return keys, nil
+}
+
+func (s *s3Backend) objectKey(key string) string {
+	return s.cfg.Prefix + key + ".json"
+}
+
+// statusCode returns the HTTP status code of the response err is for. It is 0
+// when err is nil, and -1 when err isn't for a response.
+func statusCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var rerr *awshttp.ResponseError
+	if errors.As(err, &rerr) {
+		return rerr.HTTPStatusCode()
+	}
+	return -1
+}
@@ -0,0 +1,203 @@
+package inventory_test
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/inventory"
+	"golang.org/x/sync/errgroup"
+)
+
+// fakeS3 is a stand-in for MinIO, implementing the object requests the
+// inventory makes, including conditional writes.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		http.Error(w, "unsigned request", http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != "inventory" {
+		http.Error(w, "no such bucket", http.StatusNotFound)
+		return
+	}
+
+	if key == "" && r.Method == http.MethodGet {
+		type content struct{ Key string }
+		var result struct {
+			XMLName  xml.Name `xml:"ListBucketResult"`
+			Contents []content
+		}
+		for k := range f.objects {
+			if strings.HasPrefix(k, r.URL.Query().Get("prefix")) {
+				result.Contents = append(result.Contents, content{Key: k})
+			}
+		}
+		_ = xml.NewEncoder(w).Encode(result)
+		return
+	}
+
+	data, exists := f.objects[key]
+	etag := etag(data)
+
+	switch r.Method {
+	case http.MethodGet:
+		if !exists {
+			http.Error(w, "no such key", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write(data)
+	case http.MethodPut:
+		if r.Header.Get("If-None-Match") == "*" && exists {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		if m := r.Header.Get("If-Match"); m != "" && (!exists || m != etag) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		f.objects[key] = body
+	case http.MethodDelete:
+		if !exists {
+			http.Error(w, "no such key", http.StatusNotFound)
+			return
+		}
+		if m := r.Header.Get("If-Match"); m != "" && m != etag {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func TestInventory_S3(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "minioadmin")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "minioadmin")
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	ctx := t.Context()
+	b, err := inventory.NewS3Backend(ctx, inventory.S3Config{
+		Bucket:   "inventory",
+		Prefix:   "runs/",
+		Endpoint: srv.URL,
+		Region:   "us-east-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two inventories sharing the bucket, as two terraform processes would
+	a, other := inventory.New(b), inventory.New(b)
+
+	if err := a.AddHarness(ctx, "shared"); err != nil {
+		t.Fatal(err)
+	}
+	// Adding an existing harness is a no-op
+	if err := other.AddHarness(ctx, "shared"); err != nil {
+		t.Fatal(err)
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	for i, inv := range []*inventory.Inventory{a, other} {
+		for j := range 10 {
+			g.Go(func() error {
+				return inv.AddFeature(gctx, "shared", inventory.Feature{Id: fmt.Sprintf("feature-%d-%d", i, j)})
+			})
+		}
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	features, err := other.GetFeatures(ctx, "shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(features) != 20 {
+		t.Errorf("got %d features, want 20", len(features))
+	}
+
+	if _, ok := fake.objects["runs/shared.json"]; !ok {
+		t.Errorf("objects = %v, want runs/shared.json", slices.Collect(maps.Keys(fake.objects)))
+	}
+
+	keys, err := b.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(keys, []string{"shared"}) {
+		t.Errorf("List() = %v, want [shared]", keys)
+	}
+
+	if err := a.RemoveHarness(ctx, "shared"); err == nil {
+		t.Error("RemoveHarness() with features succeeded, want an error")
+	}
+	for id := range features {
+		if err := a.RemoveFeature(ctx, "shared", id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.RemoveHarness(ctx, "shared"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.GetFeatures(ctx, "shared"); err == nil {
+		t.Error("GetFeatures() of a removed harness succeeded, want an error")
+	}
+
+	// Keys are escaped in the request path
+	if err := b.Put(ctx, "a b+c", []byte("{}"), ""); err != nil {
+		t.Fatal(err)
+	}
+	data, rev, err := b.Get(ctx, "a b+c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "{}" {
+		t.Errorf("Get() = %q, want {}", data)
+	}
+	if err := b.Delete(ctx, "a b+c", rev); err != nil {
+		t.Fatal(err)
+	}
+
+	// A document that is already gone counts as deleted
+	if err := b.Delete(ctx, "a b+c", rev); err != nil {
+		t.Errorf("Delete() of a missing document = %v, want nil", err)
+	}
+}
@@ -0,0 +1,153 @@
+package o11y
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// Attribute keys and values used on metrics only.
+const (
+	AttrResult   = "result"
+	AttrScope    = "scope"
+	AttrPlatform = "platform"
+
+	ResultPassed  = "passed"
+	ResultFailed  = "failed"
+	ResultSkipped = "skipped"
+
+	// ScopeTest and ScopeSuite tell a retry of a single test from a retry
+	// of the whole suite.
+	ScopeTest  = "test"
+	ScopeSuite = "suite"
+)
+
+// meterProvider is flushed after each suite, as the provider process may
+// exit before the next periodic export. Nil when OTLP is not configured.
+var meterProvider *sdkmetric.MeterProvider
+
+// Instruments are the metrics recorded for test suites.
+type Instruments struct {
+	// Tests counts test runs by result, every attempt of a retried test is
+	// counted
+	Tests metric.Int64Counter
+	// Retries counts the attempts after the first, by scope
+	Retries          metric.Int64Counter
+	SetupDuration    metric.Float64Histogram
+	TeardownDuration metric.Float64Histogram
+	BuildDuration    metric.Float64Histogram
+	ArtifactSize     metric.Int64Histogram
+}
+
+// durationBuckets span quick docker runs to slow cloud cluster setups.
+var durationBuckets = []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200, 1800, 3600}
+
+// sizeBuckets span 1KiB to 1GiB.
+var sizeBuckets = []float64{1 << 10, 1 << 14, 1 << 18, 1 << 20, 1 << 22, 1 << 24, 1 << 26, 1 << 28, 1 << 30}
+
+var instruments = sync.OnceValue(func() *Instruments {
+	// Instruments created before Setup forward to the MeterProvider it sets
+	meter := otel.Meter("imagetest")
+
+	var i Instruments
+	var err, ierr error
+
+	i.Tests, ierr = meter.Int64Counter("imagetest.tests",
+		metric.WithDescription("The number of test runs, by result."),
+		metric.WithUnit("{test}"))
+	err = errors.Join(err, ierr)
+
+	i.Retries, ierr = meter.Int64Counter("imagetest.retries",
+		metric.WithDescription("The number of retried attempts of tests and suites."),
+		metric.WithUnit("{retry}"))
+	err = errors.Join(err, ierr)
+
+	i.SetupDuration, ierr = meter.Float64Histogram("imagetest.driver.setup.duration",
+		metric.WithDescription("The time taken to set up the driver."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...))
+	err = errors.Join(err, ierr)
+
+	i.TeardownDuration, ierr = meter.Float64Histogram("imagetest.driver.teardown.duration",
+		metric.WithDescription("The time taken to tear down the driver."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...))
+	err = errors.Join(err, ierr)
+
+	i.BuildDuration, ierr = meter.Float64Histogram("imagetest.build.duration",
+		metric.WithDescription("The time taken to build the test images of a suite."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...))
+	err = errors.Join(err, ierr)
+
+	i.ArtifactSize, ierr = meter.Int64Histogram("imagetest.artifact.size",
+		metric.WithDescription("The size of the artifact bundled by a test run."),
+		metric.WithUnit("By"),
+		metric.WithExplicitBucketBoundaries(sizeBuckets...))
+	err = errors.Join(err, ierr)
+
+	if err != nil {
+		otel.Handle(err)
+	}
+	return &i
+})
+
+// Meters returns the instruments, they are no-ops when OTLP is not
+// configured.
+func Meters() *Instruments { return instruments() }
+
+func setupMetrics(ctx context.Context, res *resource.Resource) error {
+	metricExp, err := otlpmetrichttp.New(ctx)
+	if err != nil {
+		return err
+	}
+	meterProvider = sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExp)),
+		sdkmetric.WithResource(res),
+	)
+	otel.SetMeterProvider(meterProvider)
+	return nil
+}
+
+// FlushMetrics exports the metrics recorded so far.
+func FlushMetrics(ctx context.Context) error {
+	if meterProvider == nil {
+		return nil
+	}
+	return meterProvider.ForceFlush(ctx)
+}
+
+type metricAttrsKey struct{}
+
+// WithMetricAttributes returns a copy of ctx carrying attrs, which are added
+// to every measurement recorded with MetricAttributes of the context.
+func WithMetricAttributes(ctx context.Context, attrs ...attribute.KeyValue) context.Context {
+	existing, _ := ctx.Value(metricAttrsKey{}).([]attribute.KeyValue)
+	return context.WithValue(ctx, metricAttrsKey{}, append(slices.Clone(existing), attrs...))
+}
+
+// MetricAttributes returns the attributes carried by ctx, along with attrs,
+// as a measurement option.
+func MetricAttributes(ctx context.Context, attrs ...attribute.KeyValue) metric.MeasurementOption {
+	existing, _ := ctx.Value(metricAttrsKey{}).([]attribute.KeyValue)
+	return metric.WithAttributes(append(slices.Clone(existing), attrs...)...)
+}
+
+// LabelAttributes converts a suite's labels into attributes prefixed with
+// "label.", so dashboards can group by them.
+func LabelAttributes(labels map[string]string) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(labels))
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		attrs = append(attrs, attribute.String("label."+k, labels[k]))
+	}
+	return attrs
+}
@@ -0,0 +1,49 @@
+package o11y
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestMetricAttributes(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer func() { _ = mp.Shutdown(context.Background()) }()
+
+	counter, err := mp.Meter("test").Int64Counter("tests")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithMetricAttributes(context.Background(), attribute.String(AttrDriver, "docker_in_docker"))
+	ctx = WithMetricAttributes(ctx, LabelAttributes(map[string]string{"team": "images", "arch": "arm64"})...)
+
+	// attributes added to a derived context don't leak into the parent
+	_ = WithMetricAttributes(ctx, attribute.String(AttrName, "other"))
+
+	counter.Add(ctx, 1, MetricAttributes(ctx, attribute.String(AttrResult, ResultPassed)))
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+
+	sum, ok := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64])
+	if !ok || len(sum.DataPoints) != 1 {
+		t.Fatalf("got %+v, want a single sum data point", rm.ScopeMetrics[0].Metrics[0].Data)
+	}
+
+	want := attribute.NewSet(
+		attribute.String(AttrDriver, "docker_in_docker"),
+		attribute.String("label.arch", "arm64"),
+		attribute.String("label.team", "images"),
+		attribute.String(AttrResult, ResultPassed),
+	)
+	if got := sum.DataPoints[0].Attributes; !got.Equals(&want) {
+		t.Errorf("attributes = %v, want %v", got.ToSlice(), want.ToSlice())
+	}
+}
@@ -27,12 +27,14 @@ var loggerProvider *sdklog.LoggerProvider
 // LoggerProvider returns the configured LoggerProvider, or nil.
 func LoggerProvider() *sdklog.LoggerProvider { return loggerProvider }
 
-// Setup configures the global OTel TracerProvider and LoggerProvider. This is
-// a no-op when no OTLP endpoint is configured.
+// Setup configures the global OTel TracerProvider, LoggerProvider and
+// MeterProvider. This is a no-op when no OTLP endpoint is configured.
 func Setup(ctx context.Context) error {
-	// Check both the generic and trace-specific env vars.
-	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" &&
-		os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+	// Check the generic and the signal specific env vars.
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != ""
+	traces := endpoint || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+	metrics := endpoint || os.Getenv("OTEL_EXPORTER_OTLP_METRICS_ENDPOINT") != ""
+	if !traces && !metrics {
 		return nil
 	}
 
@@ -41,6 +43,16 @@ func Setup(ctx context.Context) error {
 		return err
 	}
 
+	if metrics {
+		if err := setupMetrics(ctx, res); err != nil {
+			return err
+		}
+	}
+
+	if !traces {
+		return nil
+	}
+
 	traceExp, err := otlptracehttp.New(ctx)
 	if err != nil {
 		return err
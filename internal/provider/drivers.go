@@ -3,11 +3,13 @@ package provider
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
@@ -19,10 +21,14 @@ import (
 	mc2 "github.com/chainguard-dev/terraform-provider-imagetest/internal/drivers/ec2"
 	ekswitheksctl "github.com/chainguard-dev/terraform-provider-imagetest/internal/drivers/eks_with_eksctl"
 	k3sindocker "github.com/chainguard-dev/terraform-provider-imagetest/internal/drivers/k3s_in_docker"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/drivers/pod"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	v1 "github.com/moby/docker-image-spec/specs-go/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 )
 
 type DriverResourceModel string
@@ -60,6 +66,8 @@ type AKSDriverResourceModel struct {
 	PodIdentityAssociations     []*AKSPodIdentityAssociationResourceModel     `tfsdk:"pod_identity_associations"`
 	ClusterIdentityAssociations []*AKSClusterIdentityAssociationResourceModel `tfsdk:"cluster_identity_associations"`
 	AttachedACRs                []*AKSAttachedACR                             `tfsdk:"attached_acrs"`
+	RBAC                        *DriverRBACResourceModel                      `tfsdk:"rbac"`
+	Sandbox                     *DriverSandboxResourceModel                   `tfsdk:"sandbox"`
 }
 
 type AKSPodIdentityAssociationResourceModel struct {
@@ -85,15 +93,22 @@ type AKSAttachedACR struct {
 }
 
 type K3sInDockerDriverResourceModel struct {
-	Image         types.String                                         `tfsdk:"image"`
-	Cni           types.Bool                                           `tfsdk:"cni"`
-	NetworkPolicy types.Bool                                           `tfsdk:"network_policy"`
-	Traefik       types.Bool                                           `tfsdk:"traefik"`
-	MetricsServer types.Bool                                           `tfsdk:"metrics_server"`
-	Registries    map[string]*K3sInDockerDriverRegistriesResourceModel `tfsdk:"registries"`
-	Snapshotter   types.String                                         `tfsdk:"snapshotter"`
-	Hooks         *K3sInDockerDriverHooksModel                         `tfsdk:"hooks"`
-	Timeouts      *DriverTimeoutsResourceModel                         `tfsdk:"timeouts"`
+	Image                      types.String                                         `tfsdk:"image"`
+	Cni                        types.Bool                                           `tfsdk:"cni"`
+	NetworkPolicy              types.Bool                                           `tfsdk:"network_policy"`
+	Traefik                    types.Bool                                           `tfsdk:"traefik"`
+	MetricsServer              types.Bool                                           `tfsdk:"metrics_server"`
+	Registries                 map[string]*K3sInDockerDriverRegistriesResourceModel `tfsdk:"registries"`
+	Snapshotter                types.String                                         `tfsdk:"snapshotter"`
+	Hooks                      *K3sInDockerDriverHooksModel                         `tfsdk:"hooks"`
+	Timeouts                   *DriverTimeoutsResourceModel                         `tfsdk:"timeouts"`
+	RBAC                       *DriverRBACResourceModel                             `tfsdk:"rbac"`
+	Sandbox                    *DriverSandboxResourceModel                          `tfsdk:"sandbox"`
+	KubernetesVersion          types.String                                         `tfsdk:"kubernetes_version"`
+	ServerArgs                 []string                                             `tfsdk:"server_args"`
+	KubeletArgs                []string                                             `tfsdk:"kubelet_args"`
+	FeatureGates               map[string]bool                                      `tfsdk:"feature_gates"`
+	EffectiveKubernetesVersion types.String                                         `tfsdk:"effective_kubernetes_version"`
 }
 
 type K3sInDockerDriverRegistriesResourceModel struct {
@@ -109,9 +124,26 @@ type K3sInDockerDriverHooksModel struct {
 }
 
 type DockerInDockerDriverResourceModel struct {
-	Image    types.String                `tfsdk:"image"`
-	Mirrors  []string                    `tfsdk:"mirrors"`
-	Timeouts *DriverTimeoutsResourceModel `tfsdk:"timeouts"`
+	Image    types.String                          `tfsdk:"image"`
+	Mirrors  []string                              `tfsdk:"mirrors"`
+	Timeouts *DriverTimeoutsResourceModel          `tfsdk:"timeouts"`
+	Services []*DockerInDockerServiceResourceModel `tfsdk:"services"`
+}
+
+type DockerInDockerServiceResourceModel struct {
+	Image       types.String                                   `tfsdk:"image"`
+	Alias       types.String                                   `tfsdk:"alias"`
+	Env         map[string]string                              `tfsdk:"env"`
+	Command     []string                                       `tfsdk:"command"`
+	Healthcheck *DockerInDockerServiceHealthcheckResourceModel `tfsdk:"healthcheck"`
+}
+
+type DockerInDockerServiceHealthcheckResourceModel struct {
+	Test        []string     `tfsdk:"test"`
+	Interval    types.String `tfsdk:"interval"`
+	Timeout     types.String `tfsdk:"timeout"`
+	StartPeriod types.String `tfsdk:"start_period"`
+	Retries     types.Int64  `tfsdk:"retries"`
 }
 
 type EKSWithEksctlDriverResourceModel struct {
@@ -124,6 +156,8 @@ type EKSWithEksctlDriverResourceModel struct {
 	PodIdentityAssociations []*EKSWithEksctlPodIdentityAssociationResourceModule `tfsdk:"pod_identity_associations"`
 	AWSProfile              types.String                                         `tfsdk:"aws_profile"`
 	Tags                    map[string]string                                    `tfsdk:"tags"`
+	RBAC                    *DriverRBACResourceModel                             `tfsdk:"rbac"`
+	Sandbox                 *DriverSandboxResourceModel                          `tfsdk:"sandbox"`
 }
 
 // DriverTimeoutsResourceModel is the shared schema model for driver
@@ -151,6 +185,218 @@ func driverTimeoutsSchema() schema.SingleNestedAttribute {
 	}
 }
 
+// DriverRBACResourceModel is the shared schema model for the access granted
+// to the sandbox pod of the Kubernetes based drivers.
+type DriverRBACResourceModel struct {
+	Mode        types.String                   `tfsdk:"mode"`
+	ClusterRole types.String                   `tfsdk:"cluster_role"`
+	Rules       []*DriverRBACRuleResourceModel `tfsdk:"rules"`
+}
+
+type DriverRBACRuleResourceModel struct {
+	APIGroups     []string `tfsdk:"api_groups"`
+	Resources     []string `tfsdk:"resources"`
+	Verbs         []string `tfsdk:"verbs"`
+	ResourceNames []string `tfsdk:"resource_names"`
+}
+
+func driverRBACSchema() schema.SingleNestedAttribute {
+	return schema.SingleNestedAttribute{
+		Description: "Access granted to the service account of the test sandbox pod. Defaults to binding cluster-admin.",
+		Optional:    true,
+		Attributes: map[string]schema.Attribute{
+			"mode": schema.StringAttribute{
+				Description: "One of 'cluster-admin' (default), 'namespace' to grant only the given rules within the sandbox namespace, or 'cluster-role' to bind an existing ClusterRole.",
+				Optional:    true,
+			},
+			"cluster_role": schema.StringAttribute{
+				Description: "The existing ClusterRole to bind when mode is 'cluster-role'.",
+				Optional:    true,
+			},
+			"rules": schema.ListNestedAttribute{
+				Description: "The rules granted in the sandbox namespace when mode is 'namespace'. The caller must hold every permission granted, which is verified before the sandbox is created.",
+				Optional:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"api_groups": schema.ListAttribute{
+							Description: "The API groups of the resources, use \"\" for the core group.",
+							ElementType: types.StringType,
+							Optional:    true,
+						},
+						"resources": schema.ListAttribute{
+							Description: "The resources the rule applies to, e.g. 'pods' or 'pods/log'.",
+							ElementType: types.StringType,
+							Required:    true,
+						},
+						"verbs": schema.ListAttribute{
+							Description: "The verbs the rule allows, e.g. 'get', 'list' or 'create'.",
+							ElementType: types.StringType,
+							Required:    true,
+						},
+						"resource_names": schema.ListAttribute{
+							Description: "Optionally restrict the rule to the named resources.",
+							ElementType: types.StringType,
+							Optional:    true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// DriverSandboxResourceModel is the shared schema model for the scheduling
+// and resource controls of the sandbox pod of the Kubernetes based drivers.
+type DriverSandboxResourceModel struct {
+	Resources        *ContainerResources                        `tfsdk:"resources"`
+	NodeSelector     map[string]string                          `tfsdk:"node_selector"`
+	Tolerations      []*DriverSandboxTolerationResourceModel    `tfsdk:"tolerations"`
+	Affinity         types.String                               `tfsdk:"affinity"`
+	RuntimeClassName types.String                               `tfsdk:"runtime_class_name"`
+	SecurityContext  *DriverSandboxSecurityContextResourceModel `tfsdk:"security_context"`
+}
+
+type DriverSandboxTolerationResourceModel struct {
+	Key               types.String `tfsdk:"key"`
+	Operator          types.String `tfsdk:"operator"`
+	Value             types.String `tfsdk:"value"`
+	Effect            types.String `tfsdk:"effect"`
+	TolerationSeconds types.Int64  `tfsdk:"toleration_seconds"`
+}
+
+type DriverSandboxSecurityContextResourceModel struct {
+	Privileged               types.Bool  `tfsdk:"privileged"`
+	RunAsUser                types.Int64 `tfsdk:"run_as_user"`
+	RunAsGroup               types.Int64 `tfsdk:"run_as_group"`
+	RunAsNonRoot             types.Bool  `tfsdk:"run_as_non_root"`
+	ReadOnlyRootFilesystem   types.Bool  `tfsdk:"read_only_root_filesystem"`
+	AllowPrivilegeEscalation types.Bool  `tfsdk:"allow_privilege_escalation"`
+	CapabilitiesAdd          []string    `tfsdk:"capabilities_add"`
+	CapabilitiesDrop         []string    `tfsdk:"capabilities_drop"`
+}
+
+func driverSandboxSchema() schema.SingleNestedAttribute {
+	return schema.SingleNestedAttribute{
+		Description: "Scheduling and resource controls for the test sandbox pod.",
+		Optional:    true,
+		Attributes: map[string]schema.Attribute{
+			"resources": schema.SingleNestedAttribute{
+				Description: "Resource requests and limits for the sandbox container.",
+				Optional:    true,
+				Attributes: map[string]schema.Attribute{
+					"memory": schema.SingleNestedAttribute{
+						Optional: true,
+						Attributes: map[string]schema.Attribute{
+							"request": schema.StringAttribute{
+								Description: "Amount of memory requested for the sandbox container",
+								Optional:    true,
+							},
+							"limit": schema.StringAttribute{
+								Description: "Limit of memory the sandbox container can consume",
+								Optional:    true,
+							},
+						},
+					},
+					"cpu": schema.SingleNestedAttribute{
+						Optional: true,
+						Attributes: map[string]schema.Attribute{
+							"request": schema.StringAttribute{
+								Description: "Amount of cpu requested for the sandbox container",
+								Optional:    true,
+							},
+							"limit": schema.StringAttribute{
+								Description: "Limit of cpu the sandbox container can consume",
+								Optional:    true,
+							},
+						},
+					},
+				},
+			},
+			"node_selector": schema.MapAttribute{
+				Description: "Node labels the sandbox pod must be scheduled on, e.g. kubernetes.io/arch = arm64.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"tolerations": schema.ListNestedAttribute{
+				Description: "Tolerations for the sandbox pod, e.g. to run on tainted GPU node pools.",
+				Optional:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"key": schema.StringAttribute{
+							Description: "The taint key the toleration applies to. Empty matches all keys when operator is Exists.",
+							Optional:    true,
+						},
+						"operator": schema.StringAttribute{
+							Description: "Either Equal (default) or Exists.",
+							Optional:    true,
+						},
+						"value": schema.StringAttribute{
+							Description: "The taint value the toleration matches when operator is Equal.",
+							Optional:    true,
+						},
+						"effect": schema.StringAttribute{
+							Description: "The taint effect to match, one of NoSchedule, PreferNoSchedule or NoExecute. Empty matches all effects.",
+							Optional:    true,
+						},
+						"toleration_seconds": schema.Int64Attribute{
+							Description: "How long the pod tolerates a NoExecute taint before being evicted.",
+							Optional:    true,
+						},
+					},
+				},
+			},
+			"affinity": schema.StringAttribute{
+				Description: "A JSON encoded Kubernetes Affinity for the sandbox pod, e.g. jsonencode({ nodeAffinity = { ... } }).",
+				Optional:    true,
+			},
+			"runtime_class_name": schema.StringAttribute{
+				Description: "The RuntimeClass to run the sandbox pod with, e.g. nvidia.",
+				Optional:    true,
+			},
+			"security_context": schema.SingleNestedAttribute{
+				Description: "Overrides for the sandbox container's security context. The sandbox runs privileged as root by default, and only the fields set here are overridden.",
+				Optional:    true,
+				Attributes: map[string]schema.Attribute{
+					"privileged": schema.BoolAttribute{
+						Description: "Run the sandbox container as privileged.",
+						Optional:    true,
+					},
+					"run_as_user": schema.Int64Attribute{
+						Description: "The UID to run the sandbox container as.",
+						Optional:    true,
+					},
+					"run_as_group": schema.Int64Attribute{
+						Description: "The GID to run the sandbox container as.",
+						Optional:    true,
+					},
+					"run_as_non_root": schema.BoolAttribute{
+						Description: "Require the sandbox container to run as a non-root user.",
+						Optional:    true,
+					},
+					"read_only_root_filesystem": schema.BoolAttribute{
+						Description: "Mount the sandbox container's root filesystem as read-only.",
+						Optional:    true,
+					},
+					"allow_privilege_escalation": schema.BoolAttribute{
+						Description: "Allow processes in the sandbox container to gain more privileges than their parent.",
+						Optional:    true,
+					},
+					"capabilities_add": schema.ListAttribute{
+						Description: "Linux capabilities to add to the sandbox container.",
+						ElementType: types.StringType,
+						Optional:    true,
+					},
+					"capabilities_drop": schema.ListAttribute{
+						Description: "Linux capabilities to drop from the sandbox container.",
+						ElementType: types.StringType,
+						Optional:    true,
+					},
+				},
+			},
+		},
+	}
+}
+
 type EKSWithEksctlStorageResourceModel struct {
 	Size types.String `tfsdk:"size"`
 	Type types.String `tfsdk:"type"`
@@ -196,6 +442,187 @@ func parseTimeoutsModel(m *DriverTimeoutsResourceModel) (drivers.Timeouts, error
 	return drivers.ParseTimeouts(m.Setup.ValueString(), m.Teardown.ValueString())
 }
 
+func parseServiceModels(ms []*DockerInDockerServiceResourceModel) ([]dockerindocker.Service, error) {
+	var services []dockerindocker.Service
+	for _, m := range ms {
+		if m == nil {
+			continue
+		}
+
+		ref, err := name.ParseReference(m.Image.ValueString())
+		if err != nil {
+			return nil, fmt.Errorf("invalid service image %q: %w", m.Image.ValueString(), err)
+		}
+
+		svc := dockerindocker.Service{
+			Image:   ref,
+			Alias:   m.Alias.ValueString(),
+			Env:     m.Env,
+			Command: m.Command,
+		}
+
+		if hc := m.Healthcheck; hc != nil {
+			if len(hc.Test) == 0 {
+				return nil, fmt.Errorf("service %s: healthcheck test is required", svc.Alias)
+			}
+
+			svc.HealthCheck = &v1.HealthcheckConfig{
+				Test:    hc.Test,
+				Retries: int(hc.Retries.ValueInt64()),
+			}
+
+			for _, d := range []struct {
+				field string
+				raw   types.String
+				dst   *time.Duration
+			}{
+				{"interval", hc.Interval, &svc.HealthCheck.Interval},
+				{"timeout", hc.Timeout, &svc.HealthCheck.Timeout},
+				{"start_period", hc.StartPeriod, &svc.HealthCheck.StartPeriod},
+			} {
+				if d.raw.ValueString() == "" {
+					continue
+				}
+				v, err := time.ParseDuration(d.raw.ValueString())
+				if err != nil {
+					return nil, fmt.Errorf("service %s: invalid healthcheck %s: %w", svc.Alias, d.field, err)
+				}
+				*d.dst = v
+			}
+		}
+
+		services = append(services, svc)
+	}
+	return services, nil
+}
+
+func parseRBACModel(m *DriverRBACResourceModel) ([]pod.RunOpts, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	rbac := pod.RBAC{
+		Mode:        pod.RBACMode(m.Mode.ValueString()),
+		ClusterRole: m.ClusterRole.ValueString(),
+	}
+	for _, r := range m.Rules {
+		if r == nil {
+			continue
+		}
+		rbac.Rules = append(rbac.Rules, rbacv1.PolicyRule{
+			APIGroups:     r.APIGroups,
+			Resources:     r.Resources,
+			Verbs:         r.Verbs,
+			ResourceNames: r.ResourceNames,
+		})
+	}
+
+	if err := rbac.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid rbac: %w", err)
+	}
+
+	return []pod.RunOpts{pod.WithRBAC(rbac)}, nil
+}
+
+func parseSandboxModel(m *DriverSandboxResourceModel) ([]pod.RunOpts, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	opts := []pod.RunOpts{
+		pod.WithNodeSelector(m.NodeSelector),
+		pod.WithRuntimeClassName(m.RuntimeClassName.ValueString()),
+	}
+
+	if m.Resources != nil {
+		rreq, err := ParseResources(m.Resources)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse resources: %w", err)
+		}
+
+		rr := corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{},
+			Limits:   corev1.ResourceList{},
+		}
+		if !rreq.CpuRequest.IsZero() {
+			rr.Requests[corev1.ResourceCPU] = rreq.CpuRequest
+		}
+		if !rreq.MemoryRequest.IsZero() {
+			rr.Requests[corev1.ResourceMemory] = rreq.MemoryRequest
+		}
+		if !rreq.CpuLimit.IsZero() {
+			rr.Limits[corev1.ResourceCPU] = rreq.CpuLimit
+		}
+		if !rreq.MemoryLimit.IsZero() {
+			rr.Limits[corev1.ResourceMemory] = rreq.MemoryLimit
+		}
+		opts = append(opts, pod.WithResources(rr))
+	}
+
+	for _, t := range m.Tolerations {
+		if t == nil {
+			continue
+		}
+		toleration := corev1.Toleration{
+			Key:      t.Key.ValueString(),
+			Operator: corev1.TolerationOperator(t.Operator.ValueString()),
+			Value:    t.Value.ValueString(),
+			Effect:   corev1.TaintEffect(t.Effect.ValueString()),
+		}
+		if !t.TolerationSeconds.IsNull() {
+			toleration.TolerationSeconds = t.TolerationSeconds.ValueInt64Pointer()
+		}
+		opts = append(opts, pod.WithTolerations(toleration))
+	}
+
+	if v := m.Affinity.ValueString(); v != "" {
+		affinity := &corev1.Affinity{}
+		if err := json.Unmarshal([]byte(v), affinity); err != nil {
+			return nil, fmt.Errorf("failed to parse affinity: %w", err)
+		}
+		opts = append(opts, pod.WithAffinity(affinity))
+	}
+
+	if sc := m.SecurityContext; sc != nil {
+		ksc := &corev1.SecurityContext{
+			Privileged:               sc.Privileged.ValueBoolPointer(),
+			RunAsUser:                sc.RunAsUser.ValueInt64Pointer(),
+			RunAsGroup:               sc.RunAsGroup.ValueInt64Pointer(),
+			RunAsNonRoot:             sc.RunAsNonRoot.ValueBoolPointer(),
+			ReadOnlyRootFilesystem:   sc.ReadOnlyRootFilesystem.ValueBoolPointer(),
+			AllowPrivilegeEscalation: sc.AllowPrivilegeEscalation.ValueBoolPointer(),
+		}
+		if len(sc.CapabilitiesAdd) > 0 || len(sc.CapabilitiesDrop) > 0 {
+			ksc.Capabilities = &corev1.Capabilities{}
+			for _, c := range sc.CapabilitiesAdd {
+				ksc.Capabilities.Add = append(ksc.Capabilities.Add, corev1.Capability(c))
+			}
+			for _, c := range sc.CapabilitiesDrop {
+				ksc.Capabilities.Drop = append(ksc.Capabilities.Drop, corev1.Capability(c))
+			}
+		}
+		opts = append(opts, pod.WithSecurityContext(ksc))
+	}
+
+	return opts, nil
+}
+
+// parsePodModels builds the sandbox pod options shared by the Kubernetes
+// based drivers.
+func parsePodModels(rbac *DriverRBACResourceModel, sandbox *DriverSandboxResourceModel) ([]pod.RunOpts, error) {
+	rbacOpts, err := parseRBACModel(rbac)
+	if err != nil {
+		return nil, err
+	}
+
+	sandboxOpts, err := parseSandboxModel(sandbox)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sandbox: %w", err)
+	}
+
+	return append(rbacOpts, sandboxOpts...), nil
+}
+
 // LoadDriver creates and configures a driver instance based on the specified driver type.
 func (t TestsResource) LoadDriver(ctx context.Context, data *TestsResourceModel) (drivers.Tester, error) {
 	driversCfg := data.Drivers
@@ -293,6 +720,11 @@ func (t TestsResource) LoadDriver(ctx context.Context, data *TestsResourceModel)
 			}
 		}
 
+		podOpts, err := parsePodModels(cfg.RBAC, cfg.Sandbox)
+		if err != nil {
+			return nil, fmt.Errorf("aks: %w", err)
+		}
+
 		return aks.NewDriver(id, aks.Options{
 			ResourceGroup:               cfg.ResourceGroup.ValueString(),
 			NodeResourceGroup:           cfg.NodeResourceGroup.ValueString(),
@@ -311,6 +743,7 @@ func (t TestsResource) LoadDriver(ctx context.Context, data *TestsResourceModel)
 			PodIdentityAssociations:     podIdentityAssociations,
 			ClusterIdentityAssociations: clusterIdentityAssociations,
 			AttachedACRs:                attachedACRs,
+			PodOpts:                     podOpts,
 		})
 
 	case DriverK3sInDocker:
@@ -321,6 +754,7 @@ func (t TestsResource) LoadDriver(ctx context.Context, data *TestsResourceModel)
 
 		opts := []k3sindocker.DriverOpts{
 			k3sindocker.WithRegistry(repo.RegistryStr()),
+			k3sindocker.WithRemoteOptions(t.ropts...),
 		}
 
 		for _, extraRepo := range t.extraRepos {
@@ -337,6 +771,22 @@ func (t TestsResource) LoadDriver(ctx context.Context, data *TestsResourceModel)
 			opts = append(opts, k3sindocker.WithImageRef(cfg.Image.ValueString()))
 		}
 
+		if cfg.KubernetesVersion.ValueString() != "" {
+			opts = append(opts, k3sindocker.WithKubernetesVersion(cfg.KubernetesVersion.ValueString()))
+		}
+
+		if len(cfg.ServerArgs) > 0 {
+			opts = append(opts, k3sindocker.WithServerArgs(cfg.ServerArgs...))
+		}
+
+		if len(cfg.KubeletArgs) > 0 {
+			opts = append(opts, k3sindocker.WithKubeletArgs(cfg.KubeletArgs...))
+		}
+
+		if len(cfg.FeatureGates) > 0 {
+			opts = append(opts, k3sindocker.WithFeatureGates(cfg.FeatureGates))
+		}
+
 		if cfg.Cni.ValueBool() {
 			opts = append(opts, k3sindocker.WithCNI(true))
 		}
@@ -428,6 +878,19 @@ kubectl rollout status deployment/coredns -n kube-system --timeout=60s
 		}
 		opts = append(opts, k3sindocker.WithTimeouts(timeouts))
 
+		podOpts, err := parsePodModels(cfg.RBAC, cfg.Sandbox)
+		if err != nil {
+			return nil, fmt.Errorf("k3s_in_docker: %w", err)
+		}
+		opts = append(opts, k3sindocker.WithPodOpts(podOpts...))
+
+		if len(data.Platforms) > 0 {
+			opts = append(opts, k3sindocker.WithPlatforms(data.Platforms...))
+		}
+		if data.BinfmtImage.ValueString() != "" {
+			opts = append(opts, k3sindocker.WithBinfmtImage(data.BinfmtImage.ValueString()))
+		}
+
 		return k3sindocker.NewDriver(id, opts...)
 
 	case DriverDockerInDocker:
@@ -472,6 +935,21 @@ kubectl rollout status deployment/coredns -n kube-system --timeout=60s
 		}
 		opts = append(opts, dockerindocker.WithTimeouts(timeouts))
 
+		services, err := parseServiceModels(cfg.Services)
+		if err != nil {
+			return nil, fmt.Errorf("docker_in_docker: %w", err)
+		}
+		if len(services) > 0 {
+			opts = append(opts, dockerindocker.WithServices(services...))
+		}
+
+		if len(data.Platforms) > 0 {
+			opts = append(opts, dockerindocker.WithPlatforms(data.Platforms...))
+		}
+		if data.BinfmtImage.ValueString() != "" {
+			opts = append(opts, dockerindocker.WithBinfmtImage(data.BinfmtImage.ValueString()))
+		}
+
 		return dockerindocker.NewDriver(id, opts...)
 
 	case DriverEKSWithEksctl:
@@ -527,6 +1005,11 @@ kubectl rollout status deployment/coredns -n kube-system --timeout=60s
 			return nil, fmt.Errorf("eks_with_eksctl: %w", err)
 		}
 
+		podOpts, err := parsePodModels(cfg.RBAC, cfg.Sandbox)
+		if err != nil {
+			return nil, fmt.Errorf("eks_with_eksctl: %w", err)
+		}
+
 		return ekswitheksctl.NewDriver(id, ekswitheksctl.Options{
 			Region:                  cfg.Region.ValueString(),
 			NodeAMI:                 cfg.NodeAMI.ValueString(),
@@ -538,6 +1021,7 @@ kubectl rollout status deployment/coredns -n kube-system --timeout=60s
 			Tags:                    cfg.Tags,
 			Timeouts:                timeouts,
 			Registries:              registries,
+			PodOpts:                 podOpts,
 		})
 
 	case DriverEC2:
@@ -773,6 +1257,8 @@ func DriverResourceSchema(ctx context.Context) schema.SingleNestedAttribute {
 						},
 					},
 					"timeouts": driverTimeoutsSchema(),
+					"rbac":     driverRBACSchema(),
+					"sandbox":  driverSandboxSchema(),
 				},
 			},
 			"k3s_in_docker": schema.SingleNestedAttribute{
@@ -783,6 +1269,29 @@ func DriverResourceSchema(ctx context.Context) schema.SingleNestedAttribute {
 						Description: "The image reference to use for the k3s_in_docker driver",
 						Optional:    true,
 					},
+					"kubernetes_version": schema.StringAttribute{
+						Description: "The Kubernetes version to run, either a minor (1.30) or patch (1.30.4) release. The newest matching tag in the repository of `image` is used, keeping its -dev variant if it has one.",
+						Optional:    true,
+					},
+					"effective_kubernetes_version": schema.StringAttribute{
+						Description: "The version reported by the cluster's api server, e.g. v1.30.4+k3s1.",
+						Computed:    true,
+					},
+					"server_args": schema.ListAttribute{
+						Description: "Additional flags passed to k3s server, e.g. --kube-apiserver-arg=audit-log-path=-.",
+						ElementType: types.StringType,
+						Optional:    true,
+					},
+					"kubelet_args": schema.ListAttribute{
+						Description: "Additional flags passed to the kubelet, without the leading dashes, e.g. max-pods=250.",
+						ElementType: types.StringType,
+						Optional:    true,
+					},
+					"feature_gates": schema.MapAttribute{
+						Description: "Kubernetes feature gates to enable or disable. These are set consistently on the api server, controller manager, scheduler, kubelet and kube-proxy.",
+						ElementType: types.BoolType,
+						Optional:    true,
+					},
 					"cni": schema.BoolAttribute{
 						Description: "Enable the CNI plugin",
 						Optional:    true,
@@ -832,6 +1341,8 @@ func DriverResourceSchema(ctx context.Context) schema.SingleNestedAttribute {
 						},
 					},
 					"timeouts": driverTimeoutsSchema(),
+					"rbac":     driverRBACSchema(),
+					"sandbox":  driverSandboxSchema(),
 				},
 			},
 			"docker_in_docker": schema.SingleNestedAttribute{
@@ -847,6 +1358,59 @@ func DriverResourceSchema(ctx context.Context) schema.SingleNestedAttribute {
 						Optional:    true,
 					},
 					"timeouts": driverTimeoutsSchema(),
+					"services": schema.ListNestedAttribute{
+						Description: "Service containers (databases, registries, mock servers, etc.) started on the test's network before the test runs. Each service is waited on until healthy and is reachable from the test container by its alias.",
+						Optional:    true,
+						NestedObject: schema.NestedAttributeObject{
+							Attributes: map[string]schema.Attribute{
+								"image": schema.StringAttribute{
+									Description: "The image reference of the service.",
+									Required:    true,
+								},
+								"alias": schema.StringAttribute{
+									Description: "The hostname the test container reaches the service by.",
+									Required:    true,
+								},
+								"env": schema.MapAttribute{
+									Description: "Environment variables to set in the service container.",
+									ElementType: types.StringType,
+									Optional:    true,
+								},
+								"command": schema.ListAttribute{
+									Description: "The command to run in the service container. Defaults to the image's command.",
+									ElementType: types.StringType,
+									Optional:    true,
+								},
+								"healthcheck": schema.SingleNestedAttribute{
+									Description: "The healthcheck the service must pass before the test starts. Defaults to the image's healthcheck, if any, otherwise the service only needs to be running.",
+									Optional:    true,
+									Attributes: map[string]schema.Attribute{
+										"test": schema.ListAttribute{
+											Description: "The healthcheck command, in docker's format, e.g. [\"CMD\", \"pg_isready\"].",
+											ElementType: types.StringType,
+											Required:    true,
+										},
+										"interval": schema.StringAttribute{
+											Description: "Time between healthchecks, e.g. 1s.",
+											Optional:    true,
+										},
+										"timeout": schema.StringAttribute{
+											Description: "Maximum time a single healthcheck may take.",
+											Optional:    true,
+										},
+										"start_period": schema.StringAttribute{
+											Description: "Grace period for the service to start before failing healthchecks count against retries.",
+											Optional:    true,
+										},
+										"retries": schema.Int64Attribute{
+											Description: "Consecutive failures before the service is considered unhealthy.",
+											Optional:    true,
+										},
+									},
+								},
+							},
+						},
+					},
 				},
 			},
 			"eks_with_eksctl": schema.SingleNestedAttribute{
@@ -913,6 +1477,8 @@ func DriverResourceSchema(ctx context.Context) schema.SingleNestedAttribute {
 						ElementType: types.StringType,
 						Optional:    true,
 					},
+					"rbac":    driverRBACSchema(),
+					"sandbox": driverSandboxSchema(),
 				},
 			},
 			"ec2": driverResourceSchemaEC2,
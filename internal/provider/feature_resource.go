@@ -5,6 +5,11 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/features"
@@ -23,6 +28,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/kballard/go-shellquote"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
@@ -58,6 +64,8 @@ type FeatureResourceModel struct {
 	Timeouts      timeouts.Value     `tfsdk:"timeouts"`
 	Skipped       types.String       `tfsdk:"skipped"`
 	WarnOnFailure types.Bool         `tfsdk:"warn_on_failure"`
+	Parallelism   types.Int64        `tfsdk:"parallelism"`
+	Outputs       types.Map          `tfsdk:"outputs"`
 
 	Harness FeatureHarnessResourceModel `tfsdk:"harness"`
 }
@@ -67,6 +75,12 @@ type FeatureStepModel struct {
 	Cmd     types.String             `tfsdk:"cmd"`
 	Workdir types.String             `tfsdk:"workdir"`
 	Retry   *FeatureStepBackoffModel `tfsdk:"retry"`
+	Outputs []FeatureStepOutputModel `tfsdk:"outputs"`
+}
+
+type FeatureStepOutputModel struct {
+	Name types.String `tfsdk:"name"`
+	File types.String `tfsdk:"file"`
 }
 
 type FeatureStepBackoffModel struct {
@@ -116,6 +130,7 @@ func (r *FeatureResource) Schema(ctx context.Context, _ resource.SchemaRequest,
 								Optional:    true,
 								Attributes:  addFeatureStepBackoffSchemaAttributes(),
 							},
+							"outputs": featureStepOutputsSchemaAttribute(),
 						},
 					},
 				},
@@ -141,6 +156,7 @@ func (r *FeatureResource) Schema(ctx context.Context, _ resource.SchemaRequest,
 								Optional:    true,
 								Attributes:  addFeatureStepBackoffSchemaAttributes(),
 							},
+							"outputs": featureStepOutputsSchemaAttribute(),
 						},
 					},
 				},
@@ -166,6 +182,7 @@ func (r *FeatureResource) Schema(ctx context.Context, _ resource.SchemaRequest,
 								Optional:    true,
 								Attributes:  addFeatureStepBackoffSchemaAttributes(),
 							},
+							"outputs": featureStepOutputsSchemaAttribute(),
 						},
 					},
 				},
@@ -187,6 +204,16 @@ func (r *FeatureResource) Schema(ctx context.Context, _ resource.SchemaRequest,
 					Computed:    true,
 					Default:     booldefault.StaticBool(false),
 				},
+				"parallelism": schema.Int64Attribute{
+					Description: "The maximum number of `steps` run concurrently against the harness. `before` and `after` steps always run in order. Defaults to 1, running the steps in order. `steps` can't capture `outputs` when greater than 1, since which steps see them would depend on timing.",
+					Optional:    true,
+				},
+				"outputs": schema.MapAttribute{
+					Description: "The outputs captured by the feature's steps, keyed by name. Outputs of steps that didn't run or failed are absent.",
+					Computed:    true,
+					Sensitive:   true,
+					ElementType: basetypes.StringType{},
+				},
 			},
 		),
 	}
@@ -269,25 +296,28 @@ func (r *FeatureResource) Create(ctx context.Context, req resource.CreateRequest
 	// TODO: Move this around if/when we start storing test output in the state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 
-	resp.Diagnostics.Append(r.do(ctx, data)...)
-	if resp.Diagnostics.HasError() {
-		return
-	}
+	outputs, diags := r.do(ctx, data)
+	resp.Diagnostics.Append(diags...)
+
+	outputsValue, diags := types.MapValueFrom(ctx, types.StringType, outputs)
+	resp.Diagnostics.Append(diags...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("outputs"), outputsValue)...)
 }
 
-func (r *FeatureResource) do(ctx context.Context, data FeatureResourceModel) (ds diag.Diagnostics) {
+// do tests the feature, and returns the outputs captured by its steps.
+func (r *FeatureResource) do(ctx context.Context, data FeatureResourceModel) (outputs map[string]string, ds diag.Diagnostics) {
 	if data.Skipped.ValueString() != "" {
 		ds.AddWarning(
 			fmt.Sprintf("skipping feature %s [%s]", data.Name.ValueString(), data.Id.ValueString()),
 			data.Skipped.ValueString(),
 		)
-		return ds
+		return outputs, ds
 	}
 
 	timeout, diags := data.Timeouts.Create(ctx, defaultFeatureCreateTimeout)
 	if diags.HasError() {
 		ds.Append(diags...)
-		return ds
+		return outputs, ds
 	}
 
 	ctx, cancel := context.WithTimeout(ctx, timeout)
@@ -300,13 +330,13 @@ func (r *FeatureResource) do(ctx context.Context, data FeatureResourceModel) (ds
 			fmt.Sprintf("non-skipped feature %s [%s] failed to retrieve harness [%s]",
 				data.Name.ValueString(), data.Id.ValueString(), data.Harness.Id.ValueString()),
 		)
-		return ds
+		return outputs, ds
 	}
 
 	ctx, err := r.store.Logger(ctx, data.Harness.Inventory, "feature_id", data.Id.ValueString(), "feature_name", data.Name.ValueString(), "harness_name", data.Harness.Id.ValueString())
 	if err != nil {
 		ds.AddError("failed to create logger", err.Error())
-		return ds
+		return outputs, ds
 	}
 
 	defer func() {
@@ -316,48 +346,63 @@ func (r *FeatureResource) do(ctx context.Context, data FeatureResourceModel) (ds
 	fopts := []features.Option{
 		features.WithDescription(data.Description.ValueString()),
 	}
+	if !data.Parallelism.IsNull() {
+		if data.Parallelism.ValueInt64() < 1 {
+			ds.AddError("invalid parallelism", fmt.Sprintf("parallelism must be at least 1, got %d", data.Parallelism.ValueInt64()))
+			return outputs, ds
+		}
+		fopts = append(fopts, features.WithParallelAssessments(int(data.Parallelism.ValueInt64())))
+	}
 
 	feat := features.New(data.Name.ValueString(), fopts...)
 
+	if err := validateFeatureStepOutputs(data); err != nil {
+		ds.AddError("invalid step outputs", err.Error())
+		return outputs, ds
+	}
+	captured := &featureStepOutputs{values: make(map[string]string)}
+	defer func() { outputs = captured.values }()
+
 	for _, before := range data.Before {
-		if err := r.step(feat, harness, before, features.Before); err != nil {
+		if err := r.step(feat, harness, before, features.Before, captured); err != nil {
 			ds.AddError("failed to create before step", err.Error())
-			return ds
+			return outputs, ds
 		}
 	}
 
 	for _, after := range data.After {
-		if err := r.step(feat, harness, after, features.After); err != nil {
+		if err := r.step(feat, harness, after, features.After, captured); err != nil {
 			ds.AddError("failed to create after step", err.Error())
-			return ds
+			return outputs, ds
 		}
 	}
 
 	for _, assess := range data.Steps {
-		if err := r.step(feat, harness, assess, features.Assessment); err != nil {
+		if err := r.step(feat, harness, assess, features.Assessment, captured); err != nil {
 			ds.AddError("failed to create assessment step", err.Error())
-			return ds
+			return outputs, ds
 		}
 	}
 
 	log.Info(ctx, "testing feature against harness")
 
 	if err = feat.Test(ctx); err != nil {
+		detail := err.Error() + "\n\n" + featureStepSummary(feat.Results())
 		if data.WarnOnFailure.ValueBool() {
 			ds.AddWarning(
 				fmt.Sprintf("failed to test feature: %s", feat.Name),
-				err.Error(),
+				detail,
 			)
 		} else {
 			ds.AddError(
 				fmt.Sprintf("failed to test feature: %s", feat.Name),
-				err.Error(),
+				detail,
 			)
-			return ds
+			return outputs, ds
 		}
 	}
 
-	return ds
+	return outputs, ds
 }
 
 func (r *FeatureResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
@@ -370,13 +415,18 @@ func (r *FeatureResource) Update(ctx context.Context, req resource.UpdateRequest
 	// TODO: Move this around if/when we start storing test output in the state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 
-	resp.Diagnostics.Append(r.do(ctx, data)...)
-	if resp.Diagnostics.HasError() {
-		return
-	}
+	outputs, diags := r.do(ctx, data)
+	resp.Diagnostics.Append(diags...)
+
+	outputsValue, diags := types.MapValueFrom(ctx, types.StringType, outputs)
+	resp.Diagnostics.Append(diags...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("outputs"), outputsValue)...)
 }
 
-func (r *FeatureResource) step(feat *features.Feature, h harness.Harness, data FeatureStepModel, level features.Level) error {
+// step adds the step to the feature. The step runs with the outputs captured
+// by earlier steps in its environment, and adds its own outputs once it
+// succeeds.
+func (r *FeatureResource) step(feat *features.Feature, h harness.Harness, data FeatureStepModel, level features.Level, outputs *featureStepOutputs) error {
 	fn := features.StepFn(func(ctx context.Context) error {
 		ctx = log.With(ctx,
 			"step_name", data.Name.ValueString(),
@@ -387,24 +437,49 @@ func (r *FeatureResource) step(feat *features.Feature, h harness.Harness, data F
 		// capture a combined output buffer and a stderr buffer. the combined
 		// output is usually easier to reason that just stdout alone, and lets us
 		// return more information on failures.
-		var bufall, buferr bytes.Buffer
+		var bufall, bufout, buferr bytes.Buffer
 
 		err := h.Run(ctx, harness.Command{
-			Args:       data.Cmd.ValueString(),
+			Args:       outputs.env() + data.Cmd.ValueString(),
 			WorkingDir: data.Workdir.ValueString(),
-			Stdout:     &bufall,
+			Stdout:     io.MultiWriter(&bufout, &bufall),
 			Stderr:     io.MultiWriter(&buferr, &bufall),
 		})
 
+		// capture the outputs first, so their values are masked from the
+		// logged output along with the secrets the harness knows of
+		if err == nil {
+			for _, o := range data.Outputs {
+				value := bufout.String()
+				if file := o.File.ValueString(); file != "" {
+					var buffile bytes.Buffer
+					if err := h.Run(ctx, harness.Command{
+						Args:       "cat -- " + shellquote.Join(file),
+						WorkingDir: data.Workdir.ValueString(),
+						Stdout:     &buffile,
+					}); err != nil {
+						return fmt.Errorf("reading output %q from %s: %w", o.Name.ValueString(), file, err)
+					}
+					value = buffile.String()
+				}
+				outputs.set(o.Name.ValueString(), strings.TrimSpace(value))
+			}
+		}
+
+		redact := func(s string) string {
+			return outputs.redact(harness.Redact(h, s))
+		}
 		ctx = log.With(ctx,
-			"output", bufall.String(),
+			"output", redact(bufall.String()),
 		)
 
 		if err != nil {
 			if rerr, ok := err.(*harness.RunError); ok {
 				log.Warn(ctx, "feature step failed with non-zero exit code",
 					"exit_code", rerr.ExitCode,
-					"stderr", buferr.String())
+					"stderr", redact(buferr.String()))
+				// report the step's command, not the outputs exported before it
+				rerr.Cmd = data.Cmd.ValueString()
 				return rerr
 			}
 			return fmt.Errorf("running step: %w", err)
@@ -474,6 +549,12 @@ func (r *FeatureResource) teardown(ctx context.Context, data FeatureResourceMode
 
 To remove the resources specific to this harness, run the following:
 
+  docker rm -f $(docker ps -a -q --filter "label=dev.chainguard.imagetest.harness=%[1]s")
+  docker network rm -f $(docker network ls -q --filter "label=dev.chainguard.imagetest.harness=%[1]s")
+  docker volume rm -f $(docker volume ls -q --filter "label=dev.chainguard.imagetest.harness=%[1]s")
+
+Resources created by earlier provider versions aren't labeled with the harness, remove them by name instead:
+
   docker rm -f $(docker ps -a -q --filter "name=^%[1]s*" --filter "label=dev.chainguard.imagetest=true")
   docker network rm -f $(docker network ls -q --filter "name=^%[1]s*" --filter "label=dev.chainguard.imagetest=true")
 
@@ -494,6 +575,95 @@ If you are regularly skipping the harness teardown, its recommended you run the
 	return diag.Diagnostics{}
 }
 
+func featureStepOutputsSchemaAttribute() schema.ListNestedAttribute {
+	return schema.ListNestedAttribute{
+		Description: "Values captured when the step succeeds. Each output is exported as an environment variable of the same name to the steps that run after it, and is available in the feature's `outputs`. Values of 4 characters or more are masked in the logged output of every step.",
+		Optional:    true,
+		NestedObject: schema.NestedAttributeObject{
+			Attributes: map[string]schema.Attribute{
+				"name": schema.StringAttribute{
+					Description: "The name of the output, which must be a valid environment variable name and unique within the feature.",
+					Required:    true,
+				},
+				"file": schema.StringAttribute{
+					Description: "A file in the harness to read the value from. When unset, the value is the step's stdout. Leading and trailing whitespace is trimmed either way.",
+					Optional:    true,
+				},
+			},
+		},
+	}
+}
+
+// validateFeatureStepOutputs checks output names are usable as environment
+// variables, and aren't captured by more than one step. Concurrent steps can't
+// capture outputs, since the steps seeing them would vary from run to run.
+func validateFeatureStepOutputs(data FeatureResourceModel) error {
+	if data.Parallelism.ValueInt64() > 1 && slices.ContainsFunc(data.Steps, func(s FeatureStepModel) bool { return len(s.Outputs) > 0 }) {
+		return fmt.Errorf("steps can't capture outputs when parallelism is greater than 1")
+	}
+
+	seen := make(map[string]bool)
+	for _, steps := range [][]FeatureStepModel{data.Before, data.Steps, data.After} {
+		for _, step := range steps {
+			for _, o := range step.Outputs {
+				name := o.Name.ValueString()
+				if !envVarName.MatchString(name) {
+					return fmt.Errorf("output name %q is not a valid environment variable name", name)
+				}
+				if seen[name] {
+					return fmt.Errorf("output %q is captured by more than one step", name)
+				}
+				seen[name] = true
+			}
+		}
+	}
+	return nil
+}
+
+// envVarName matches the names the shell accepts for variables.
+var envVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// featureStepOutputs are the outputs captured so far, shared by steps that
+// may run concurrently.
+type featureStepOutputs struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (o *featureStepOutputs) env() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return harness.ExportEnv(o.values)
+}
+
+// redact masks the values of the outputs in s.
+func (o *featureStepOutputs) redact(s string) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return harness.Mask(s, slices.Collect(maps.Values(o.values))...)
+}
+
+func (o *featureStepOutputs) set(name, value string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.values[name] = value
+}
+
+// featureStepSummary lists the outcome and duration of each step that ran,
+// in the order they finished.
+func featureStepSummary(results []features.StepResult) string {
+	var b strings.Builder
+	b.WriteString("Steps:")
+	for _, r := range results {
+		status := "ok"
+		if r.Err != nil {
+			status = "error"
+		}
+		fmt.Fprintf(&b, "\n  [%s] %s '%s' (%s)", status, r.Level, r.Name, r.Duration.Round(time.Millisecond))
+	}
+	return b.String()
+}
+
 func addFeatureStepBackoffSchemaAttributes() map[string]schema.Attribute {
 	return map[string]schema.Attribute{
 		"attempts": schema.Int64Attribute{
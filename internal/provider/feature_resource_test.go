@@ -2,9 +2,13 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"regexp"
 	"testing"
+	"time"
 
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/features"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 )
 
@@ -123,6 +127,168 @@ resource "imagetest_feature" "test" {
 	})
 }
 
+func TestAccFeatureResourceOutputs(t *testing.T) {
+	t.Parallel()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testProviderWithRegistry(t, context.Background()), //nolint: usetesting
+		Steps: []resource.TestStep{
+			{
+				ExpectNonEmptyPlan: true,
+				Config: `
+data "imagetest_inventory" "this" {}
+
+resource "imagetest_harness_docker" "test" {
+  name = "test"
+  inventory = data.imagetest_inventory.this
+}
+
+resource "imagetest_feature" "test" {
+  name = "Outputs"
+  description = "Test passing step outputs to later steps"
+  harness = imagetest_harness_docker.test
+  before = [
+    {
+      name = "generate"
+      cmd = <<EOF
+        echo "it's generated"
+        echo token-123 > /tmp/token
+      EOF
+      outputs = [
+        { name = "GENERATED" },
+        { name = "TOKEN", file = "/tmp/token" },
+      ]
+    },
+  ]
+  steps = [
+    {
+      name = "assert"
+      cmd = <<EOF
+        [ "$GENERATED" = "it's generated" ]
+        [ "$TOKEN" = "token-123" ]
+      EOF
+    },
+  ]
+}
+        `,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("imagetest_feature.test", "outputs.GENERATED", "it's generated"),
+					resource.TestCheckResourceAttr("imagetest_feature.test", "outputs.TOKEN", "token-123"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccFeatureResourceParallelism(t *testing.T) {
+	t.Parallel()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testProviderWithRegistry(t, context.Background()), //nolint: usetesting
+		Steps: []resource.TestStep{
+			{
+				ExpectNonEmptyPlan: true,
+				Config: `
+data "imagetest_inventory" "this" {}
+
+resource "imagetest_harness_docker" "test" {
+  name = "test"
+  inventory = data.imagetest_inventory.this
+}
+
+resource "imagetest_feature" "test" {
+  name = "Parallelism"
+  description = "Test running steps concurrently"
+  harness = imagetest_harness_docker.test
+  parallelism = 2
+  before = [
+    {
+      name = "prepare"
+      cmd = "mkdir -p /tmp/parallel"
+    },
+  ]
+  # Each step waits for the other, so they only pass when run concurrently
+  steps = [
+    {
+      name = "first"
+      cmd = <<EOF
+        touch /tmp/parallel/first
+        timeout 30 sh -c 'until [ -f /tmp/parallel/second ]; do sleep 0.1; done'
+      EOF
+    },
+    {
+      name = "second"
+      cmd = <<EOF
+        touch /tmp/parallel/second
+        timeout 30 sh -c 'until [ -f /tmp/parallel/first ]; do sleep 0.1; done'
+      EOF
+    },
+  ]
+  after = [
+    {
+      name = "cleanup"
+      cmd = "rm -rf /tmp/parallel"
+    },
+  ]
+}
+        `,
+			},
+		},
+	})
+}
+
+func TestFeatureStepSummary(t *testing.T) {
+	got := featureStepSummary([]features.StepResult{
+		{Name: "setup", Level: features.Before, Duration: 1200 * time.Microsecond},
+		{Name: "check", Level: features.Assessment, Duration: 2 * time.Second, Err: errors.New("exit 1")},
+	})
+	want := "Steps:\n  [ok] before 'setup' (1ms)\n  [error] assessment 'check' (2s)"
+	if got != want {
+		t.Errorf("featureStepSummary() = %q, want %q", got, want)
+	}
+}
+
+func TestFeatureStepEnv(t *testing.T) {
+	got := (&featureStepOutputs{values: map[string]string{"B": "it's", "A": ""}}).env()
+	if want := "export A=''\nexport B=it\\'s\n"; got != want {
+		t.Errorf("env() = %q, want %q", got, want)
+	}
+
+	got = (&featureStepOutputs{values: map[string]string{"TOKEN": "token-123", "N": "1"}}).redact("got token-123 after 1 try")
+	if want := "got (sensitive) after 1 try"; got != want {
+		t.Errorf("redact() = %q, want %q", got, want)
+	}
+
+	if err := validateFeatureStepOutputs(FeatureResourceModel{
+		Before: []FeatureStepModel{{Outputs: []FeatureStepOutputModel{{Name: types.StringValue("NAME")}}}},
+		Steps:  []FeatureStepModel{{Outputs: []FeatureStepOutputModel{{Name: types.StringValue("NAME")}}}},
+	}); err == nil {
+		t.Error("validateFeatureStepOutputs() with a duplicate output succeeded, want an error")
+	}
+
+	if err := validateFeatureStepOutputs(FeatureResourceModel{
+		Steps: []FeatureStepModel{{Outputs: []FeatureStepOutputModel{{Name: types.StringValue("not-valid")}}}},
+	}); err == nil {
+		t.Error("validateFeatureStepOutputs() with an invalid name succeeded, want an error")
+	}
+
+	if err := validateFeatureStepOutputs(FeatureResourceModel{
+		Parallelism: types.Int64Value(2),
+		Steps:       []FeatureStepModel{{Outputs: []FeatureStepOutputModel{{Name: types.StringValue("NAME")}}}},
+	}); err == nil {
+		t.Error("validateFeatureStepOutputs() with concurrent step outputs succeeded, want an error")
+	}
+
+	if err := validateFeatureStepOutputs(FeatureResourceModel{
+		Parallelism: types.Int64Value(2),
+		Before:      []FeatureStepModel{{Outputs: []FeatureStepOutputModel{{Name: types.StringValue("NAME")}}}},
+	}); err != nil {
+		t.Errorf("validateFeatureStepOutputs() with concurrent before outputs = %v", err)
+	}
+}
+
 // TestAccFeatureResourceUpdate tests that this provider works with Update()
 // requests as well. This also hits the base_harness path, where all the
 // harness update logic is located.
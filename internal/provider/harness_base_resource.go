@@ -172,12 +172,11 @@ func (r *BaseHarnessResource) ModifyPlan(ctx context.Context, req resource.Modif
 	}
 
 	// The harness ModifyPlan runs twice, once during plan and once during apply
-	inv, err := inventory.NewInventory(invd.Seed.ValueString())
+	inv, err := r.store.Inventory(ctx, invd.Seed.ValueString())
 	if err != nil {
 		resp.Diagnostics.AddError("failed to create inventory", err.Error())
 		return
 	}
-	r.store.inv.Set(invd.Seed.ValueString(), inv)
 
 	if err := inv.AddHarness(ctx, hid); err != nil {
 		resp.Diagnostics.AddError("failed to add harness to inventory", err.Error())
@@ -262,6 +261,10 @@ func (r *BaseHarnessResource) do(ctx context.Context, req framework.CreateOrUpda
 
 	r.store.harnesses.Set(data.Id.ValueString(), harness)
 
+	// Label what the harness creates, so it can be cleaned up if it's ever
+	// left behind
+	ctx = docker.WithLabels(ctx, map[string]string{docker.HarnessLabel: data.Id.ValueString()})
+
 	if err := harness.Create(ctx); err != nil {
 		return []diag.Diagnostic{diag.NewErrorDiagnostic("failed to create harness", err.Error())}
 	}
@@ -6,6 +6,7 @@ import (
 	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/bundler"
 	client "github.com/chainguard-dev/terraform-provider-imagetest/internal/docker"
@@ -17,9 +18,12 @@ import (
 	"github.com/google/go-containerregistry/pkg/name"
 	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
@@ -55,6 +59,14 @@ type HarnessDockerResourceModel struct {
 	Networks     map[string]ContainerNetworkModel       `tfsdk:"networks"`
 	Registries   map[string]DockerRegistryResourceModel `tfsdk:"registries"`
 	Resources    *ContainerResources                    `tfsdk:"resources"`
+	Ports        []DockerPortModel                      `tfsdk:"ports"`
+	Addresses    types.Map                              `tfsdk:"addresses"`
+}
+
+type DockerPortModel struct {
+	ContainerPort types.Int64  `tfsdk:"container_port"`
+	Protocol      types.String `tfsdk:"protocol"`
+	HostPort      types.Int64  `tfsdk:"host_port"`
 }
 
 type DockerRegistryResourceModel struct {
@@ -79,6 +91,7 @@ func (r *HarnessDockerResource) Create(ctx context.Context, req resource.CreateR
 	}
 
 	resp.Diagnostics.Append(r.create(ctx, req, harness)...)
+	resp.Diagnostics.Append(r.setAddresses(ctx, &resp.State, harness)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
@@ -102,6 +115,7 @@ func (r *HarnessDockerResource) Update(ctx context.Context, req resource.UpdateR
 	}
 
 	resp.Diagnostics.Append(r.update(ctx, req, harness)...)
+	resp.Diagnostics.Append(r.setAddresses(ctx, &resp.State, harness)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
@@ -246,6 +260,12 @@ func (r *HarnessDockerResource) harness(ctx context.Context, data *HarnessDocker
 		opts = append(opts, docker.WithNetworks(network))
 	}
 
+	ports, err := dockerPorts(data.Ports)
+	if err != nil {
+		return nil, []diag.Diagnostic{diag.NewErrorDiagnostic("invalid ports", err.Error())}
+	}
+	opts = append(opts, docker.WithPorts(ports...))
+
 	harness, err := docker.New(opts...)
 	if err != nil {
 		return nil, []diag.Diagnostic{diag.NewErrorDiagnostic("invalid provider data", err.Error())}
@@ -254,6 +274,52 @@ func (r *HarnessDockerResource) harness(ctx context.Context, data *HarnessDocker
 	return harness, diags
 }
 
+// setAddresses sets the computed addresses of the published ports, which are
+// empty when the harness was skipped or failed to start.
+func (r *HarnessDockerResource) setAddresses(ctx context.Context, state *tfsdk.State, h harness.Harness) diag.Diagnostics {
+	addresses := map[string]string{}
+	if ph, ok := h.(interface{ Addresses() map[string]string }); ok && ph.Addresses() != nil {
+		addresses = ph.Addresses()
+	}
+
+	value, diags := types.MapValueFrom(ctx, types.StringType, addresses)
+	if diags.HasError() {
+		return diags
+	}
+	return append(diags, state.SetAttribute(ctx, path.Root("addresses"), value)...)
+}
+
+// dockerPorts validates the ports to publish.
+func dockerPorts(ports []DockerPortModel) ([]docker.PortConfig, error) {
+	configs := make([]docker.PortConfig, 0, len(ports))
+	seen := make(map[string]bool)
+	for _, p := range ports {
+		cfg := docker.PortConfig{
+			ContainerPort: int(p.ContainerPort.ValueInt64()),
+			Protocol:      p.Protocol.ValueString(),
+			HostPort:      int(p.HostPort.ValueInt64()),
+		}
+		if cfg.Protocol == "" {
+			cfg.Protocol = "tcp"
+		}
+
+		key := fmt.Sprintf("%d/%s", cfg.ContainerPort, cfg.Protocol)
+		switch {
+		case cfg.ContainerPort < 1 || cfg.ContainerPort > 65535:
+			return nil, fmt.Errorf("container port %d is out of range", cfg.ContainerPort)
+		case cfg.HostPort < 0 || cfg.HostPort > 65535:
+			return nil, fmt.Errorf("host port %d is out of range", cfg.HostPort)
+		case !slices.Contains([]string{"tcp", "udp", "sctp"}, cfg.Protocol):
+			return nil, fmt.Errorf("protocol %q of port %d must be one of tcp, udp or sctp", cfg.Protocol, cfg.ContainerPort)
+		case seen[key]:
+			return nil, fmt.Errorf("port %s is published more than once", key)
+		}
+		seen[key] = true
+		configs = append(configs, cfg)
+	}
+	return configs, nil
+}
+
 func (r *HarnessDockerResource) bundler(data *HarnessDockerResourceModel) (bundler.Bundler, error) {
 	if data.Image.ValueString() != "" {
 		ref, err := name.ParseReference(data.Image.ValueString())
@@ -425,6 +491,33 @@ func (r *HarnessDockerResource) Schema(ctx context.Context, _ resource.SchemaReq
 						},
 					},
 				},
+				"ports": schema.ListNestedAttribute{
+					Description: "The container ports to publish to the host, so tooling running alongside terraform can reach services in the harness.",
+					Optional:    true,
+					NestedObject: schema.NestedAttributeObject{
+						Attributes: map[string]schema.Attribute{
+							"container_port": schema.Int64Attribute{
+								Description: "The port in the container to publish.",
+								Required:    true,
+							},
+							"protocol": schema.StringAttribute{
+								Description: "The protocol of the port, one of tcp, udp or sctp.",
+								Optional:    true,
+								Computed:    true,
+								Default:     stringdefault.StaticString("tcp"),
+							},
+							"host_port": schema.Int64Attribute{
+								Description: "The port on the host to publish to. Defaults to a free port picked by the docker daemon.",
+								Optional:    true,
+							},
+						},
+					},
+				},
+				"addresses": schema.MapAttribute{
+					Description: "The host addresses of the published ports, keyed by port and protocol, e.g. `8080/tcp`.",
+					Computed:    true,
+					ElementType: types.StringType,
+				},
 				"volumes": schema.ListNestedAttribute{
 					NestedObject: schema.NestedAttributeObject{
 						Attributes: map[string]schema.Attribute{
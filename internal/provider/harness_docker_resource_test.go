@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"fmt"
 	"regexp"
 	"testing"
 
@@ -37,6 +38,33 @@ resource "imagetest_feature" "test" {
         `,
 			},
 		},
+		"with a named inventory": {
+			{
+				ExpectNonEmptyPlan: true,
+				Config: fmt.Sprintf(`
+data "imagetest_inventory" "this" {
+  seed = %q
+}
+
+resource "imagetest_harness_docker" "test" {
+  name      = "test"
+  inventory = data.imagetest_inventory.this
+}
+
+resource "imagetest_feature" "test" {
+  name        = "Simple Docker based test"
+  description = "Test that harnesses can use an inventory with a fixed seed"
+  harness     = imagetest_harness_docker.test
+  steps = [
+    {
+      name = "wolfi"
+      cmd  = "cat /etc/os-release | grep -q 'wolfi'"
+    },
+  ]
+}
+        `, t.TempDir()),
+			},
+		},
 		"with resource provider": {
 			{
 				ExpectNonEmptyPlan: true,
@@ -296,6 +324,80 @@ resource "imagetest_feature" "test" {
 				Check: resource.ComposeAggregateTestCheckFunc(),
 			},
 		},
+		"with ports": {
+			{
+				ExpectNonEmptyPlan: true,
+				Config: `
+data "imagetest_inventory" "this" {}
+
+resource "imagetest_harness_docker" "test" {
+  name = "test"
+  inventory = data.imagetest_inventory.this
+
+  ports = [
+    { container_port = 8080 },
+    { container_port = 5353, protocol = "udp" },
+  ]
+
+  provisioner "local-exec" {
+    command = <<EOF
+docker inspect ${self.id} | jq -e '.[0].HostConfig.PortBindings["8080/tcp"]'
+docker inspect ${self.id} | jq -e '.[0].HostConfig.PortBindings["5353/udp"]'
+[ "${self.addresses["8080/tcp"]}" = "127.0.0.1:$(docker port ${self.id} 8080/tcp | head -n1 | cut -d: -f2)" ]
+      EOF
+  }
+}
+
+resource "imagetest_feature" "test" {
+  name = "Simple Docker based test"
+  description = "Verify the harness starts with published ports"
+  harness = imagetest_harness_docker.test
+
+  steps = [
+    {
+      name = "echo"
+      cmd = "echo hello"
+    },
+  ]
+}
+        `,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("imagetest_harness_docker.test", "addresses.8080/tcp"),
+					resource.TestCheckResourceAttrSet("imagetest_harness_docker.test", "addresses.5353/udp"),
+				),
+			},
+		},
+		"with invalid ports": {
+			{
+				ExpectError: regexp.MustCompile(`published more than once`),
+				Config: `
+data "imagetest_inventory" "this" {}
+
+resource "imagetest_harness_docker" "test" {
+  name = "test"
+  inventory = data.imagetest_inventory.this
+
+  ports = [
+    { container_port = 8080 },
+    { container_port = 8080, host_port = 8081 },
+  ]
+}
+
+resource "imagetest_feature" "test" {
+  name = "Simple Docker based test"
+  description = "Verify ports are validated"
+  harness = imagetest_harness_docker.test
+
+  steps = [
+    {
+      name = "echo"
+      cmd = "echo hello"
+    },
+  ]
+}
+        `,
+			},
+		},
 		"with resource limits": {
 			{
 				ExpectNonEmptyPlan: true,
@@ -13,6 +13,8 @@ import (
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/harness/k3s"
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/log"
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/provider/framework"
+	isandbox "github.com/chainguard-dev/terraform-provider-imagetest/internal/sandbox"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/sandbox/k8s"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/google/go-containerregistry/pkg/name"
 	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
@@ -23,6 +25,8 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	corev1 "k8s.io/api/core/v1"
+	kresource "k8s.io/apimachinery/pkg/api/resource"
 )
 
 var _ resource.ResourceWithModifyPlan = &HarnessK3sResource{}
@@ -65,15 +69,33 @@ type RegistryResourceMirrorModel struct {
 }
 
 type HarnessK3sSandboxResourceModel struct {
-	Image        types.String                     `tfsdk:"image"`
-	Privileged   types.Bool                       `tfsdk:"privileged"`
-	Envs         map[string]string                `tfsdk:"envs"`
-	Mounts       []ContainerMountModel            `tfsdk:"mounts"`
-	Layers       []ContainerLayerModel            `tfsdk:"layers"`
-	Networks     map[string]ContainerNetworkModel `tfsdk:"networks"`
-	Packages     []string                         `tfsdk:"packages"`
-	Repositories []string                         `tfsdk:"repositories"`
-	Keyrings     []string                         `tfsdk:"keyrings"`
+	Image        types.String                      `tfsdk:"image"`
+	Privileged   types.Bool                        `tfsdk:"privileged"`
+	Envs         map[string]string                 `tfsdk:"envs"`
+	Mounts       []ContainerMountModel             `tfsdk:"mounts"`
+	Layers       []ContainerLayerModel             `tfsdk:"layers"`
+	Networks     map[string]ContainerNetworkModel  `tfsdk:"networks"`
+	Packages     []string                          `tfsdk:"packages"`
+	Repositories []string                          `tfsdk:"repositories"`
+	Keyrings     []string                          `tfsdk:"keyrings"`
+	Kubernetes   *HarnessK3sSandboxKubernetesModel `tfsdk:"kubernetes"`
+}
+
+// HarnessK3sSandboxKubernetesModel runs the sandbox as a pod in the cluster.
+type HarnessK3sSandboxKubernetesModel struct {
+	ServiceAccount types.String                            `tfsdk:"service_account"`
+	Resources      *ContainerResources                     `tfsdk:"resources"`
+	NodeSelector   map[string]string                       `tfsdk:"node_selector"`
+	Tolerations    []*DriverSandboxTolerationResourceModel `tfsdk:"tolerations"`
+	Volumes        []HarnessK3sSandboxVolumeModel          `tfsdk:"volumes"`
+	EnvFromSecrets []string                                `tfsdk:"env_from_secrets"`
+}
+
+type HarnessK3sSandboxVolumeModel struct {
+	Name      types.String `tfsdk:"name"`
+	MountPath types.String `tfsdk:"mount_path"`
+	ConfigMap types.String `tfsdk:"config_map"`
+	Secret    types.String `tfsdk:"secret"`
 }
 
 func (r *HarnessK3sResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -205,6 +227,18 @@ func (r *HarnessK3sResource) harness(ctx context.Context, data *HarnessK3sResour
 			envslist = append(envslist, fmt.Sprintf("%s=%s", k, v))
 		}
 		kopts = append(kopts, k3s.WithSandboxEnv(envslist...))
+
+		if sandbox.Kubernetes != nil {
+			if len(sandbox.Mounts) > 0 || len(sandbox.Networks) > 0 {
+				return nil, []diag.Diagnostic{diag.NewErrorDiagnostic("invalid sandbox", "mounts and networks only apply to sandbox containers, they can't be set with kubernetes")}
+			}
+
+			sopts, err := sandboxPodOpts(sandbox.Kubernetes)
+			if err != nil {
+				return nil, []diag.Diagnostic{diag.NewErrorDiagnostic("invalid sandbox kubernetes configuration", err.Error())}
+			}
+			kopts = append(kopts, k3s.WithSandboxPod(sopts...))
+		}
 	}
 
 	for rname, rdata := range registries {
@@ -289,6 +323,68 @@ func (r *HarnessK3sResource) harness(ctx context.Context, data *HarnessK3sResour
 	return harness, diags
 }
 
+// sandboxPodOpts converts the kubernetes configuration of the sandbox into the
+// options of the sandbox pod.
+func sandboxPodOpts(m *HarnessK3sSandboxKubernetesModel) ([]k8s.Option, error) {
+	opts := []k8s.Option{
+		k8s.WithServiceAccount(m.ServiceAccount.ValueString()),
+		k8s.WithNodeSelector(m.NodeSelector),
+		k8s.WithEnvFromSecrets(m.EnvFromSecrets...),
+	}
+
+	if m.Resources != nil {
+		rreq, err := ParseResources(m.Resources)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse resources: %w", err)
+		}
+
+		rr := isandbox.ResourceRequest{
+			Requests: map[string]kresource.Quantity{},
+			Limits:   map[string]kresource.Quantity{},
+		}
+		if !rreq.CpuRequest.IsZero() {
+			rr.Requests[string(corev1.ResourceCPU)] = rreq.CpuRequest
+		}
+		if !rreq.MemoryRequest.IsZero() {
+			rr.Requests[string(corev1.ResourceMemory)] = rreq.MemoryRequest
+		}
+		if !rreq.CpuLimit.IsZero() {
+			rr.Limits[string(corev1.ResourceCPU)] = rreq.CpuLimit
+		}
+		if !rreq.MemoryLimit.IsZero() {
+			rr.Limits[string(corev1.ResourceMemory)] = rreq.MemoryLimit
+		}
+		opts = append(opts, k8s.WithResources(rr))
+	}
+
+	for _, t := range m.Tolerations {
+		if t == nil {
+			continue
+		}
+		toleration := corev1.Toleration{
+			Key:      t.Key.ValueString(),
+			Operator: corev1.TolerationOperator(t.Operator.ValueString()),
+			Value:    t.Value.ValueString(),
+			Effect:   corev1.TaintEffect(t.Effect.ValueString()),
+		}
+		if !t.TolerationSeconds.IsNull() {
+			toleration.TolerationSeconds = t.TolerationSeconds.ValueInt64Pointer()
+		}
+		opts = append(opts, k8s.WithTolerations(toleration))
+	}
+
+	for _, v := range m.Volumes {
+		opts = append(opts, k8s.WithVolumes(k8s.Volume{
+			Name:      v.Name.ValueString(),
+			MountPath: v.MountPath.ValueString(),
+			ConfigMap: v.ConfigMap.ValueString(),
+			Secret:    v.Secret.ValueString(),
+		}))
+	}
+
+	return opts, nil
+}
+
 // workstationOpts holds any workstation specific k3s configuration.
 func (r *HarnessK3sResource) workstationOpts() []k3s.Option {
 	opts := make([]k3s.Option, 0)
@@ -363,6 +459,48 @@ func (r *HarnessK3sResource) Schema(ctx context.Context, _ resource.SchemaReques
 				Optional:    true,
 				ElementType: types.StringType,
 			},
+			"kubernetes": schema.SingleNestedAttribute{
+				Description: "Runs the sandbox as a pod in the k3s cluster instead of a container next to it, which reaches the cluster with its service account. Mounts and networks can't be set with it.",
+				Optional:    true,
+				Attributes: map[string]schema.Attribute{
+					"service_account": schema.StringAttribute{
+						Description: "An existing service account in the default namespace to run the sandbox pod as. When unset, a service account bound to cluster-admin is created.",
+						Optional:    true,
+					},
+					"resources":     driverSandboxSchema().Attributes["resources"],
+					"node_selector": driverSandboxSchema().Attributes["node_selector"],
+					"tolerations":   driverSandboxSchema().Attributes["tolerations"],
+					"volumes": schema.ListNestedAttribute{
+						Description: "ConfigMaps or Secrets of the default namespace to mount read-only into the sandbox.",
+						Optional:    true,
+						NestedObject: schema.NestedAttributeObject{
+							Attributes: map[string]schema.Attribute{
+								"name": schema.StringAttribute{
+									Description: "The name of the volume.",
+									Required:    true,
+								},
+								"mount_path": schema.StringAttribute{
+									Description: "The absolute path to mount the volume at.",
+									Required:    true,
+								},
+								"config_map": schema.StringAttribute{
+									Description: "The ConfigMap to mount, exclusive with secret.",
+									Optional:    true,
+								},
+								"secret": schema.StringAttribute{
+									Description: "The Secret to mount, exclusive with config_map.",
+									Optional:    true,
+								},
+							},
+						},
+					},
+					"env_from_secrets": schema.ListAttribute{
+						Description: "Secrets of the default namespace whose keys are all exposed as environment variables of the sandbox.",
+						Optional:    true,
+						ElementType: types.StringType,
+					},
+				},
+			},
 			"layers": schema.ListNestedAttribute{
 				Description: "A list of layers to add to the sandbox container.",
 				Optional:    true,
@@ -185,6 +185,61 @@ resource "imagetest_feature" "test" {
           `,
 			},
 		},
+		"sandbox pod": {
+			// Create testing
+			{
+				ExpectNonEmptyPlan: true,
+				Config: `
+data "imagetest_inventory" "this" {}
+
+resource "imagetest_harness_k3s" "test" {
+  name = "test"
+  inventory = data.imagetest_inventory.this
+  sandbox = {
+    envs = { FOO = "bar" }
+    kubernetes = {
+      resources = { cpu = { request = "100m" } }
+      node_selector = { "kubernetes.io/os" = "linux" }
+      tolerations = [{ operator = "Exists" }]
+    }
+  }
+}
+
+resource "imagetest_feature" "test" {
+  name = "Simple k3s based test"
+  description = "Test that the sandbox runs as a pod in the cluster"
+  harness = imagetest_harness_k3s.test
+  steps = [
+    {
+      name = "Runs in cluster"
+      cmd = "test -n \"$KUBERNETES_SERVICE_HOST\" && test \"$FOO\" = bar"
+    },
+    {
+      name = "Access cluster"
+      cmd = "kubectl get po -A"
+    },
+  ]
+}
+          `,
+			},
+		},
+		"sandbox pod with mounts": {
+			{
+				Config: `
+data "imagetest_inventory" "this" {}
+
+resource "imagetest_harness_k3s" "test" {
+  name = "test"
+  inventory = data.imagetest_inventory.this
+  sandbox = {
+    mounts = [{ source = path.module, destination = "/src" }]
+    kubernetes = {}
+  }
+}
+          `,
+				ExpectError: regexp.MustCompile(`can't be set with kubernetes`),
+			},
+		},
 		"with memory configuration": {
 			// Create testing
 			{
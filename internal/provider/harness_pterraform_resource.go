@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/harness"
@@ -13,8 +14,10 @@ import (
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/provider/framework"
 	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
+	tfpath "github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
@@ -35,8 +38,23 @@ type HarnessPterraformResourceModel struct {
 	Inventory InventoryDataSourceModel `tfsdk:"inventory"`
 	Timeouts  timeouts.Value           `tfsdk:"timeouts"`
 
-	Path types.String `tfsdk:"path"`
-	Vars types.String `tfsdk:"vars"`
+	Path    types.String           `tfsdk:"path"`
+	Vars    types.String           `tfsdk:"vars"`
+	Binary  *PterraformBinaryModel `tfsdk:"binary"`
+	State   *PterraformStateModel  `tfsdk:"state"`
+	Outputs types.Map              `tfsdk:"outputs"`
+}
+
+type PterraformStateModel struct {
+	Directory types.String `tfsdk:"directory"`
+	Destroy   types.Bool   `tfsdk:"destroy"`
+}
+
+type PterraformBinaryModel struct {
+	Flavor   types.String `tfsdk:"flavor"`
+	Path     types.String `tfsdk:"path"`
+	Version  types.String `tfsdk:"version"`
+	CacheDir types.String `tfsdk:"cache_dir"`
 }
 
 func (r *HarnessPterraformResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -55,6 +73,8 @@ func (r *HarnessPterraformResource) Create(ctx context.Context, req resource.Cre
 	}
 
 	resp.Diagnostics.Append(r.create(ctx, req, harness)...)
+	resp.Diagnostics.Append(r.setOutputs(ctx, &resp.State, harness)...)
+	resp.Diagnostics.Append(r.drift(data, harness)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
@@ -76,6 +96,8 @@ func (r *HarnessPterraformResource) Update(ctx context.Context, req resource.Upd
 	}
 
 	resp.Diagnostics.Append(r.update(ctx, req, harness)...)
+	resp.Diagnostics.Append(r.setOutputs(ctx, &resp.State, harness)...)
+	resp.Diagnostics.Append(r.drift(data, harness)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
@@ -116,6 +138,18 @@ This will only work as intended if all harnesses are named uniquely across ALL i
 		popts = append(popts, pterraform.WithVars(vars))
 	}
 
+	if st := data.State; st != nil {
+		// Default to destroying, like a harness without persisted state
+		destroy := st.Destroy.IsNull() || st.Destroy.ValueBool()
+		popts = append(popts, pterraform.WithPersistentState(filepath.Join(st.Directory.ValueString(), data.Id.ValueString()), destroy))
+	}
+
+	binary, err := r.binary(data)
+	if err != nil {
+		return nil, []diag.Diagnostic{diag.NewErrorDiagnostic("invalid terraform binary", err.Error())}
+	}
+	popts = append(popts, pterraform.WithBinary(binary))
+
 	harness, err := pterraform.New(
 		ctx,
 		os.DirFS(data.Path.ValueString()),
@@ -128,6 +162,62 @@ This will only work as intended if all harnesses are named uniquely across ALL i
 	return harness, diags
 }
 
+// binary returns the terraform executable of the harness, falling back to the
+// provider's default.
+func (r *HarnessPterraformResource) binary(data *HarnessPterraformResourceModel) (pterraform.Binary, error) {
+	bm := data.Binary
+	if bm == nil && r.store.providerResourceData.Harnesses != nil && r.store.providerResourceData.Harnesses.Pterraform != nil {
+		bm = r.store.providerResourceData.Harnesses.Pterraform.Binary
+	}
+
+	var binary pterraform.Binary
+	if bm != nil {
+		binary = pterraform.Binary{
+			Flavor:   bm.Flavor.ValueString(),
+			Path:     bm.Path.ValueString(),
+			Version:  bm.Version.ValueString(),
+			CacheDir: bm.CacheDir.ValueString(),
+		}
+	}
+
+	if binary.CacheDir == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return binary, fmt.Errorf("finding the cache directory, set cache_dir instead: %w", err)
+		}
+		binary.CacheDir = filepath.Join(dir, "imagetest", "pterraform")
+	}
+	return binary, nil
+}
+
+// drift warns about changes applied to infrastructure persisted by an earlier
+// run.
+func (r *HarnessPterraformResource) drift(data HarnessPterraformResourceModel, h harness.Harness) diag.Diagnostics {
+	dh, ok := h.(interface{ Drift() []string })
+	if !ok || len(dh.Drift()) == 0 {
+		return nil
+	}
+	return []diag.Diagnostic{diag.NewWarningDiagnostic(
+		fmt.Sprintf("pterraform harness %s drifted from its persisted state", data.Id.ValueString()),
+		fmt.Sprintf("The following changes were applied:\n  %s", strings.Join(dh.Drift(), "\n  ")),
+	)}
+}
+
+// setOutputs sets the computed outputs of the terraform run, which are empty
+// when the harness was skipped or failed to apply.
+func (r *HarnessPterraformResource) setOutputs(ctx context.Context, state *tfsdk.State, h harness.Harness) diag.Diagnostics {
+	outputs := map[string]string{}
+	if oh, ok := h.(interface{ Outputs() map[string]string }); ok {
+		outputs = oh.Outputs()
+	}
+
+	value, diags := types.MapValueFrom(ctx, types.StringType, outputs)
+	if diags.HasError() {
+		return diags
+	}
+	return append(diags, state.SetAttribute(ctx, tfpath.Root("outputs"), value)...)
+}
+
 func (r *HarnessPterraformResource) Schema(ctx context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		MarkdownDescription: `A harness created from a generic terraform invocation.`,
@@ -142,6 +232,48 @@ func (r *HarnessPterraformResource) Schema(ctx context.Context, _ resource.Schem
 					Description: "A json encoded string of variables to pass to the terraform invocation. This will be passed in as a .tfvars.json var file.",
 					Optional:    true,
 				},
+				"binary": schema.SingleNestedAttribute{
+					Description: "The terraform executable to run. Defaults to the provider's `harnesses.pterraform.binary`, or `terraform` on $PATH.",
+					Optional:    true,
+					Attributes: map[string]schema.Attribute{
+						"flavor": schema.StringAttribute{
+							Description: "The flavor of the executable, either `terraform` or `tofu`. Defaults to `terraform`.",
+							Optional:    true,
+						},
+						"path": schema.StringAttribute{
+							Description: "The path to the executable. Defaults to finding the executable in `cache_dir` or on $PATH.",
+							Optional:    true,
+						},
+						"version": schema.StringAttribute{
+							Description: "A version constraint the executable must satisfy, e.g. `>= 1.8, < 2.0`. An exact version missing from `cache_dir` and $PATH is installed into `cache_dir`. Installed `terraform` is verified against HashiCorp's signing key, while installed `tofu` is only checked against the release's SHA256SUMS, which isn't signature verified; use `path` with a vetted executable when that matters.",
+							Optional:    true,
+						},
+						"cache_dir": schema.StringAttribute{
+							Description: "The directory executables are installed to. Defaults to `imagetest/pterraform` in the user's cache directory.",
+							Optional:    true,
+						},
+					},
+				},
+				"state": schema.SingleNestedAttribute{
+					Description: "Persists the terraform state across runs, so expensive infrastructure is only applied when its plan has changes. Changes to infrastructure created by an earlier run are reported as warnings. The module must not configure a backend.",
+					Optional:    true,
+					Attributes: map[string]schema.Attribute{
+						"directory": schema.StringAttribute{
+							Description: "The directory the state is kept in, under a subdirectory named after the harness id.",
+							Required:    true,
+						},
+						"destroy": schema.BoolAttribute{
+							Description: "Whether to destroy the infrastructure when the harness is torn down. Set to false to keep it for the next run. Defaults to true.",
+							Optional:    true,
+						},
+					},
+				},
+				"outputs": schema.MapAttribute{
+					Description: "The outputs of the terraform invocation other than `connection`, keyed by name. Values that aren't strings are JSON encoded. The outputs are also exported as environment variables to the steps of features using this harness, with characters that aren't valid in variable names replaced by `_`; outputs exported as the same variable, e.g. `a-b` and `a_b`, are an error. Sensitive outputs of 4 characters or more are masked in logs.",
+					Computed:    true,
+					Sensitive:   true,
+					ElementType: types.StringType,
+				},
 			},
 		),
 	}
@@ -1,14 +1,20 @@
 package provider
 
 import (
+	"fmt"
+	"path/filepath"
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
 )
 
 func TestAccHarnessPterraformResource(t *testing.T) {
 	t.Parallel()
 
+	stateDir := t.TempDir()
+
 	testCases := map[string][]resource.TestStep{
 		"local docker connector": {
 			// Create testing
@@ -37,6 +43,84 @@ resource "imagetest_feature" "test" {
       name = "Make sure variables are passed through"
       cmd = "echo $FOO | grep -q 'notbar'"
     },
+    {
+      name = "Make sure outputs are exported"
+      cmd = "[ \"$greeting\" = 'hello notbar' ] && [ \"$token\" = 's3cr3t' ]"
+    },
+  ]
+}
+          `,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("imagetest_harness_pterraform.test", "outputs.greeting", "hello notbar"),
+					resource.TestCheckResourceAttr("imagetest_harness_pterraform.test", "outputs.token", "s3cr3t"),
+					resource.TestCheckNoResourceAttr("imagetest_harness_pterraform.test", "outputs.connection"),
+				),
+			},
+		},
+		"persistent state": {
+			{
+				ExpectNonEmptyPlan: true,
+				Config: fmt.Sprintf(`
+data "imagetest_inventory" "this" {}
+
+resource "imagetest_harness_pterraform" "test" {
+  name = "test"
+  inventory = data.imagetest_inventory.this
+  path = "./testdata/pterraform/docker"
+  state = {
+    directory = %q
+  }
+}
+
+resource "imagetest_feature" "test" {
+  name = "Persistent state"
+  description = "Test that the state is kept outside of the working directory"
+  harness = imagetest_harness_pterraform.test
+  steps = [
+    {
+      name = "Make sure we can hit the container"
+      cmd = "grep -q 'wolfi' /etc/os-release"
+    },
+  ]
+}
+          `, stateDir),
+				Check: func(*terraform.State) error {
+					states, err := filepath.Glob(filepath.Join(stateDir, "*", "terraform.tfstate"))
+					if err != nil {
+						return err
+					}
+					if len(states) != 1 {
+						return fmt.Errorf("found %d state files in %s, want 1", len(states), stateDir)
+					}
+					return nil
+				},
+			},
+		},
+		"unsatisfied binary version": {
+			{
+				ExpectError: regexp.MustCompile(`no terraform executable satisfying "< 0.1"`),
+				Config: `
+data "imagetest_inventory" "this" {}
+
+resource "imagetest_harness_pterraform" "test" {
+  name = "test"
+  inventory = data.imagetest_inventory.this
+  path = "./testdata/pterraform/docker"
+  binary = {
+    version = "< 0.1"
+    cache_dir = "./testdata/pterraform/empty-cache"
+  }
+}
+
+resource "imagetest_feature" "test" {
+  name = "Unsatisfied binary version"
+  description = "Test that the harness fails early when no executable satisfies the version"
+  harness = imagetest_harness_pterraform.test
+  steps = [
+    {
+      name = "Never runs"
+      cmd = "true"
+    },
   ]
 }
           `,
@@ -0,0 +1,269 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/docker"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/inventory"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/log"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/provider/framework"
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+const (
+	// defaultOrphanAge is how old a harness must be before cleanup considers
+	// it orphaned.
+	defaultOrphanAge = "1h"
+	// cleanupLeaseTTL bounds how long a harness stays reserved when its
+	// cleanup fails midway, after which another cleanup may retry it.
+	cleanupLeaseTTL = 10 * time.Minute
+)
+
+var _ resource.Resource = &InventoryCleanupResource{}
+
+func NewInventoryCleanupResource() resource.Resource {
+	return &InventoryCleanupResource{WithTypeName: "inventory_cleanup"}
+}
+
+// InventoryCleanupResource removes the harnesses left behind in an inventory,
+// e.g. by crashed applies. It only runs when applied, never during plans.
+type InventoryCleanupResource struct {
+	framework.WithTypeName
+	framework.WithNoOpRead
+	framework.WithNoOpDelete
+
+	store *ProviderStore
+}
+
+type InventoryCleanupResourceModel struct {
+	Id             types.String                   `tfsdk:"id"`
+	Inventory      InventoryDataSourceModel       `tfsdk:"inventory"`
+	OlderThan      types.String                   `tfsdk:"older_than"`
+	IncludeUndated types.Bool                     `tfsdk:"include_undated"`
+	Triggers       map[string]string              `tfsdk:"triggers"`
+	Removed        []InventoryCleanupRemovedModel `tfsdk:"removed"`
+}
+
+type InventoryCleanupRemovedModel struct {
+	Id         types.String `tfsdk:"id"`
+	Containers []string     `tfsdk:"containers"`
+	Networks   []string     `tfsdk:"networks"`
+	Volumes    []string     `tfsdk:"volumes"`
+}
+
+func (r *InventoryCleanupResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Removes the orphaned harnesses of an inventory along with the docker containers, networks and volumes labeled as created for them. A harness is orphaned when it's older than `older_than` and isn't used by this terraform run. The cleanup runs when the resource is created or updated, so only on apply, and again whenever `triggers` change. Each harness is leased in the inventory before it's removed, so concurrent cleanups sharing an inventory never remove the same harness, and features can't be added to a harness being removed.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description: "The unique identifier of the last cleanup, which holds the leases of the harnesses it removes.",
+				Computed:    true,
+			},
+			"inventory": schema.SingleNestedAttribute{
+				Description: "The inventory to clean up. This is received as a direct input from a data.imagetest_inventory data source.",
+				Required:    true,
+				Attributes: map[string]schema.Attribute{
+					"seed": schema.StringAttribute{
+						Required: true,
+					},
+				},
+			},
+			"older_than": schema.StringAttribute{
+				Description: "The minimum age of an orphaned harness, as a duration. It should exceed the longest apply of any workspace sharing the inventory. Defaults to 1h.",
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(defaultOrphanAge),
+			},
+			"include_undated": schema.BoolAttribute{
+				Description: "Whether harnesses added by earlier provider versions, which have no age, are orphaned too. Defaults to false.",
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+			},
+			"triggers": schema.MapAttribute{
+				Description: "Arbitrary values that run the cleanup again when they change, e.g. a timestamp.",
+				Optional:    true,
+				ElementType: types.StringType,
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"removed": schema.ListNestedAttribute{
+				Description: "The orphaned harnesses removed by the last cleanup, and what was removed with them.",
+				Computed:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							Description: "The id of the harness.",
+							Computed:    true,
+						},
+						"containers": schema.ListAttribute{
+							Description: "The ids of the removed containers.",
+							Computed:    true,
+							ElementType: types.StringType,
+						},
+						"networks": schema.ListAttribute{
+							Description: "The ids of the removed networks.",
+							Computed:    true,
+							ElementType: types.StringType,
+						},
+						"volumes": schema.ListAttribute{
+							Description: "The names of the removed volumes.",
+							Computed:    true,
+							ElementType: types.StringType,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *InventoryCleanupResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	store, ok := req.ProviderData.(*ProviderStore)
+	if !ok {
+		resp.Diagnostics.AddError("invalid provider data", "...")
+		return
+	}
+
+	r.store = store
+}
+
+func (r *InventoryCleanupResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data InventoryCleanupResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.do(ctx, &data)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *InventoryCleanupResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data InventoryCleanupResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.do(ctx, &data)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// do removes the orphaned harnesses. A harness that can't be removed is
+// reported as a warning and left for a later cleanup.
+func (r *InventoryCleanupResource) do(ctx context.Context, data *InventoryCleanupResourceModel) (ds diag.Diagnostics) {
+	data.Id = types.StringValue(uuid.NewString())
+	data.Removed = make([]InventoryCleanupRemovedModel, 0)
+
+	olderThan, err := time.ParseDuration(data.OlderThan.ValueString())
+	if err != nil || olderThan < 0 {
+		ds.AddError("invalid older_than", fmt.Sprintf("older_than must be a positive duration, e.g. 1h: %q", data.OlderThan.ValueString()))
+		return ds
+	}
+
+	inv, err := r.store.Inventory(ctx, data.Inventory.Seed.ValueString())
+	if err != nil {
+		ds.AddError("failed to open inventory", err.Error())
+		return ds
+	}
+
+	hs, err := inv.Harnesses(ctx)
+	if err != nil {
+		ds.AddError("failed to list harnesses", err.Error())
+		return ds
+	}
+	slices.SortFunc(hs, func(a, b inventory.Harness) int { return strings.Compare(a.Id, b.Id) })
+
+	now := time.Now()
+	var cli *docker.Client
+	for _, h := range hs {
+		if !r.orphaned(h, now, olderThan, data.IncludeUndated.ValueBool()) {
+			continue
+		}
+
+		if cli == nil {
+			cli, err = docker.New()
+			if err != nil {
+				ds.AddError("failed to create docker client", err.Error())
+				return ds
+			}
+		}
+
+		removed, err := cleanupHarness(ctx, inv, cli, h.Id, data.Id.ValueString())
+		if removed != nil {
+			data.Removed = append(data.Removed, *removed)
+		}
+		if err != nil {
+			ds.AddWarning(fmt.Sprintf("failed to clean up harness [%s]", h.Id), err.Error())
+		}
+	}
+	return ds
+}
+
+// orphaned reports whether the harness is old enough to have been left
+// behind, and isn't used by this run. Harnesses with no creation time are
+// only orphaned when includeUndated is set.
+func (r *InventoryCleanupResource) orphaned(h inventory.Harness, now time.Time, olderThan time.Duration, includeUndated bool) bool {
+	if _, ok := r.store.harnesses.Get(h.Id); ok {
+		return false
+	}
+	if h.Created.IsZero() {
+		return includeUndated
+	}
+	return now.Sub(h.Created) >= olderThan
+}
+
+// cleanupHarness leases the harness for holder, then removes what docker
+// created for it, then the harness itself. It returns nil without an error
+// when another process got to the harness first. The harness stays leased
+// when the docker objects can't all be removed, so a later cleanup can try
+// again once the lease expires.
+func cleanupHarness(ctx context.Context, inv *inventory.Inventory, cli *docker.Client, id string, holder string) (*InventoryCleanupRemovedModel, error) {
+	if _, err := inv.LeaseHarness(ctx, id, holder, cleanupLeaseTTL); err != nil {
+		if errors.Is(err, inventory.ErrLeased) || errors.Is(err, fs.ErrNotExist) {
+			log.Info(ctx, "skipping harness cleaned up by another process", "harness_id", id)
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	log.Info(ctx, "cleaning up orphaned harness", "harness_id", id)
+
+	r, err := cli.RemoveLabeled(ctx, map[string]string{docker.HarnessLabel: id})
+	if r == nil {
+		return nil, err
+	}
+
+	removed := &InventoryCleanupRemovedModel{
+		Id:         types.StringValue(id),
+		Containers: append([]string{}, r.Containers...),
+		Networks:   append([]string{}, r.Networks...),
+		Volumes:    append([]string{}, r.Volumes...),
+	}
+	if err != nil {
+		return removed, err
+	}
+
+	return removed, inv.PurgeHarness(ctx, id, holder)
+}
@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/harness"
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/inventory"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestInventoryCleanupOrphaned(t *testing.T) {
+	now := time.Now()
+	r := &InventoryCleanupResource{store: &ProviderStore{
+		harnesses: &mmap[string, harness.Harness]{store: map[string]harness.Harness{"in-use": nil}},
+	}}
+
+	for name, tc := range map[string]struct {
+		harness        inventory.Harness
+		includeUndated bool
+		want           bool
+	}{
+		"old":                {harness: inventory.Harness{Id: "old", Created: now.Add(-2 * time.Hour)}, want: true},
+		"recent":             {harness: inventory.Harness{Id: "recent", Created: now.Add(-time.Minute)}, want: false},
+		"undated":            {harness: inventory.Harness{Id: "legacy"}, want: false},
+		"undated included":   {harness: inventory.Harness{Id: "legacy"}, includeUndated: true, want: true},
+		"in use":             {harness: inventory.Harness{Id: "in-use", Created: now.Add(-2 * time.Hour)}, want: false},
+		"in use and undated": {harness: inventory.Harness{Id: "in-use"}, includeUndated: true, want: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := r.orphaned(tc.harness, now, time.Hour, tc.includeUndated); got != tc.want {
+				t.Errorf("orphaned() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAccInventoryCleanupResource(t *testing.T) {
+	t.Parallel()
+
+	seed := t.TempDir()
+	// A harness left behind by an earlier provider version
+	if err := os.WriteFile(filepath.Join(seed, "leftover.json"), []byte(`{"f":{"id":"f","skipped":""}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	inv, err := inventory.NewInventory(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := inv.AddHarness(context.Background(), "recent"); err != nil { //nolint: usetesting
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testProviderWithRegistry(t, context.Background()), //nolint: usetesting
+		Steps: []resource.TestStep{
+			{
+				// Undated harnesses are kept by default
+				Config: fmt.Sprintf(`
+resource "imagetest_inventory_cleanup" "this" {
+  inventory = { seed = %q }
+}
+        `, seed),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("imagetest_inventory_cleanup.this", "removed.#", "0"),
+				),
+			},
+			{
+				Config: fmt.Sprintf(`
+resource "imagetest_inventory_cleanup" "this" {
+  inventory       = { seed = %q }
+  include_undated = true
+}
+        `, seed),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("imagetest_inventory_cleanup.this", "removed.#", "1"),
+					resource.TestCheckResourceAttr("imagetest_inventory_cleanup.this", "removed.0.id", "leftover"),
+				),
+			},
+		},
+	})
+}
@@ -38,7 +38,9 @@ func (d *InventoryDataSource) Schema(_ context.Context, _ datasource.SchemaReque
 		MarkdownDescription: "Inventory data source. Keeps track of harness resources.",
 		Attributes: map[string]schema.Attribute{
 			"seed": schema.StringAttribute{
-				Computed: true,
+				Description: "Names the inventory. Harnesses with the same name in inventories with the same seed are shared, e.g. between terraform workspaces. With the default local storage the seed is the inventory's directory. Defaults to a new temporary directory.",
+				Optional:    true,
+				Computed:    true,
 			},
 		},
 	}
@@ -66,13 +68,15 @@ func (d *InventoryDataSource) Read(ctx context.Context, req datasource.ReadReque
 		return
 	}
 
-	f, err := os.MkdirTemp("", "imagetest-")
-	if err != nil {
-		resp.Diagnostics.AddError("failed to create temp file", err.Error())
-		return
-	}
+	if data.Seed.ValueString() == "" {
+		f, err := os.MkdirTemp("", "imagetest-")
+		if err != nil {
+			resp.Diagnostics.AddError("failed to create temp file", err.Error())
+			return
+		}
 
-	data.Seed = types.StringValue(f)
+		data.Seed = types.StringValue(f)
+	}
 
 	// Save data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
@@ -0,0 +1,168 @@
+package provider
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/inventory"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ datasource.DataSource = &InventoryStatusDataSource{}
+)
+
+func NewInventoryStatusDataSource() datasource.DataSource {
+	return &InventoryStatusDataSource{}
+}
+
+// InventoryStatusDataSource lists the harnesses of an inventory. It never
+// changes the inventory, imagetest_inventory_cleanup removes the harnesses
+// left behind by crashed applies.
+type InventoryStatusDataSource struct {
+	store *ProviderStore
+}
+
+type InventoryStatusDataSourceModel struct {
+	Inventory InventoryDataSourceModel      `tfsdk:"inventory"`
+	Harnesses []InventoryStatusHarnessModel `tfsdk:"harnesses"`
+}
+
+type InventoryStatusHarnessModel struct {
+	Id       types.String                  `tfsdk:"id"`
+	Created  types.String                  `tfsdk:"created"`
+	Age      types.String                  `tfsdk:"age"`
+	Features []InventoryStatusFeatureModel `tfsdk:"features"`
+}
+
+type InventoryStatusFeatureModel struct {
+	Id      types.String `tfsdk:"id"`
+	Skipped types.String `tfsdk:"skipped"`
+}
+
+func (d *InventoryStatusDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_inventory_status"
+}
+
+func (d *InventoryStatusDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Lists the harnesses of an inventory along with their features. Harnesses outliving their apply, e.g. when it crashed, stay in the inventory and can be removed with the `imagetest_inventory_cleanup` resource.",
+		Attributes: map[string]schema.Attribute{
+			"inventory": schema.SingleNestedAttribute{
+				Description: "The inventory to inspect.",
+				Required:    true,
+				Attributes: map[string]schema.Attribute{
+					"seed": schema.StringAttribute{
+						Required: true,
+					},
+				},
+			},
+			"harnesses": schema.ListNestedAttribute{
+				Description: "The harnesses in the inventory, ordered by id.",
+				Computed:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							Description: "The id of the harness.",
+							Computed:    true,
+						},
+						"created": schema.StringAttribute{
+							Description: "When the harness was added to the inventory, in RFC 3339 format. Unknown for harnesses added by earlier provider versions.",
+							Computed:    true,
+						},
+						"age": schema.StringAttribute{
+							Description: "How long ago the harness was added to the inventory, e.g. 1h30m0s.",
+							Computed:    true,
+						},
+						"features": schema.ListNestedAttribute{
+							Description: "The features using the harness, ordered by id.",
+							Computed:    true,
+							NestedObject: schema.NestedAttributeObject{
+								Attributes: map[string]schema.Attribute{
+									"id": schema.StringAttribute{
+										Description: "The id of the feature.",
+										Computed:    true,
+									},
+									"skipped": schema.StringAttribute{
+										Description: "The reason the feature was skipped, empty when it wasn't.",
+										Computed:    true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *InventoryStatusDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	store, ok := req.ProviderData.(*ProviderStore)
+	if !ok {
+		resp.Diagnostics.AddError("invalid provider data", "...")
+		return
+	}
+
+	d.store = store
+}
+
+func (d *InventoryStatusDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data InventoryStatusDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	inv, err := d.store.Inventory(ctx, data.Inventory.Seed.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("failed to open inventory", err.Error())
+		return
+	}
+
+	hs, err := inv.Harnesses(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("failed to list harnesses", err.Error())
+		return
+	}
+	slices.SortFunc(hs, func(a, b inventory.Harness) int { return strings.Compare(a.Id, b.Id) })
+
+	now := time.Now()
+	data.Harnesses = make([]InventoryStatusHarnessModel, 0, len(hs))
+	for _, h := range hs {
+		data.Harnesses = append(data.Harnesses, inventoryStatusHarness(h, now))
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func inventoryStatusHarness(h inventory.Harness, now time.Time) InventoryStatusHarnessModel {
+	m := InventoryStatusHarnessModel{
+		Id:       types.StringValue(h.Id),
+		Created:  types.StringNull(),
+		Age:      types.StringNull(),
+		Features: make([]InventoryStatusFeatureModel, 0, len(h.Features)),
+	}
+	if !h.Created.IsZero() {
+		m.Created = types.StringValue(h.Created.Format(time.RFC3339))
+		m.Age = types.StringValue(now.Sub(h.Created).Truncate(time.Second).String())
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(h.Features)) {
+		m.Features = append(m.Features, InventoryStatusFeatureModel{
+			Id:      types.StringValue(id),
+			Skipped: types.StringValue(h.Features[id].Skipped),
+		})
+	}
+	return m
+}
@@ -0,0 +1,85 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/inventory"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestInventoryStatusHarness(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	got := inventoryStatusHarness(inventory.Harness{
+		Id:      "h",
+		Created: now.Add(-90*time.Minute - 500*time.Millisecond),
+		Features: map[string]inventory.Feature{
+			"b": {Id: "b", Skipped: "excluded"},
+			"a": {Id: "a"},
+		},
+	}, now)
+
+	if got.Created.ValueString() != "2024-01-01T10:29:59Z" {
+		t.Errorf("created = %s", got.Created)
+	}
+	if got.Age.ValueString() != "1h30m0s" {
+		t.Errorf("age = %s, want 1h30m0s", got.Age)
+	}
+	want := []InventoryStatusFeatureModel{
+		{Id: types.StringValue("a"), Skipped: types.StringValue("")},
+		{Id: types.StringValue("b"), Skipped: types.StringValue("excluded")},
+	}
+	if fmt.Sprint(got.Features) != fmt.Sprint(want) {
+		t.Errorf("features = %v, want %v", got.Features, want)
+	}
+
+	legacy := inventoryStatusHarness(inventory.Harness{Id: "legacy"}, now)
+	if !legacy.Created.IsNull() || !legacy.Age.IsNull() {
+		t.Errorf("legacy harness = %v, want a null created and age", legacy)
+	}
+}
+
+func TestAccInventoryStatusDataSource(t *testing.T) {
+	t.Parallel()
+
+	seed := t.TempDir()
+	// A harness left behind by an earlier provider version
+	if err := os.WriteFile(filepath.Join(seed, "leftover.json"), []byte(`{"f":{"id":"f","skipped":""}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	inv, err := inventory.NewInventory(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := inv.AddHarness(context.Background(), "recent"); err != nil { //nolint: usetesting
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testProviderWithRegistry(t, context.Background()), //nolint: usetesting
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+data "imagetest_inventory_status" "this" {
+  inventory = { seed = %q }
+}
+        `, seed),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.imagetest_inventory_status.this", "harnesses.#", "2"),
+					resource.TestCheckResourceAttr("data.imagetest_inventory_status.this", "harnesses.0.id", "leftover"),
+					resource.TestCheckResourceAttr("data.imagetest_inventory_status.this", "harnesses.0.features.0.id", "f"),
+					resource.TestCheckResourceAttr("data.imagetest_inventory_status.this", "harnesses.1.id", "recent"),
+					resource.TestMatchResourceAttr("data.imagetest_inventory_status.this", "harnesses.1.age", regexp.MustCompile(`s$`)),
+				),
+			},
+		},
+	})
+}
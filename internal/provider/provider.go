@@ -37,6 +37,19 @@ type ImageTestProviderModel struct {
 	ExtraRepos    []string                       `tfsdk:"extra_repos"`
 	Sandbox       *ProviderSandboxModel          `tfsdk:"sandbox"`
 	Logs          *ProviderLogsModel             `tfsdk:"logs"`
+	Inventory     *ProviderInventoryModel        `tfsdk:"inventory"`
+}
+
+// ProviderInventoryModel selects where inventories are stored.
+type ProviderInventoryModel struct {
+	S3 *ProviderInventoryS3Model `tfsdk:"s3"`
+}
+
+type ProviderInventoryS3Model struct {
+	Bucket   types.String `tfsdk:"bucket"`
+	Prefix   types.String `tfsdk:"prefix"`
+	Endpoint types.String `tfsdk:"endpoint"`
+	Region   types.String `tfsdk:"region"`
 }
 
 // ProviderLogsModel describes the logs configuration.
@@ -45,9 +58,10 @@ type ProviderLogsModel struct {
 }
 
 type ImageTestProviderHarnessModel struct {
-	K3s     *ProviderHarnessK3sModel     `tfsdk:"k3s"`
-	Docker  *ProviderHarnessDockerModel  `tfsdk:"docker"`
-	Cluster *ProviderHarnessClusterModel `tfsdk:"cluster"`
+	K3s        *ProviderHarnessK3sModel        `tfsdk:"k3s"`
+	Docker     *ProviderHarnessDockerModel     `tfsdk:"docker"`
+	Cluster    *ProviderHarnessClusterModel    `tfsdk:"cluster"`
+	Pterraform *ProviderHarnessPterraformModel `tfsdk:"pterraform"`
 }
 
 type ProviderHarnessK3sModel struct {
@@ -59,6 +73,10 @@ type ProviderHarnessClusterModel struct {
 	Kubeconfig *string `tfsdk:"kubeconfig"`
 }
 
+type ProviderHarnessPterraformModel struct {
+	Binary *PterraformBinaryModel `tfsdk:"binary"`
+}
+
 type ProviderSandboxModel struct {
 	ExtraRepos    []string `tfsdk:"extra_repos"`
 	ExtraKeyrings []string `tfsdk:"extra_keyrings"`
@@ -154,6 +172,34 @@ func (p *ImageTestProvider) Schema(ctx context.Context, req provider.SchemaReque
 					},
 				},
 			},
+			"inventory": schema.SingleNestedAttribute{
+				Description: "Where inventories keep track of harnesses and features. Inventories are local directories by default, locked so concurrent terraform processes can share them.",
+				Optional:    true,
+				Attributes: map[string]schema.Attribute{
+					"s3": schema.SingleNestedAttribute{
+						Description: "Stores inventories in an S3 compatible bucket so they survive runner restarts and can be shared between runners, using conditional writes for concurrent updates. Credentials come from the default AWS credential chain.",
+						Optional:    true,
+						Attributes: map[string]schema.Attribute{
+							"bucket": schema.StringAttribute{
+								Description: "The bucket to store inventories in.",
+								Required:    true,
+							},
+							"prefix": schema.StringAttribute{
+								Description: "A prefix for the keys of the inventories, e.g. imagetest/.",
+								Optional:    true,
+							},
+							"endpoint": schema.StringAttribute{
+								Description: "The base URL of an S3 compatible API, e.g. a MinIO server. Defaults to AWS. The bucket is always addressed path style.",
+								Optional:    true,
+							},
+							"region": schema.StringAttribute{
+								Description: "The region of the bucket, defaulting to the AWS configuration.",
+								Optional:    true,
+							},
+						},
+					},
+				},
+			},
 			"harnesses": schema.SingleNestedAttribute{
 				Optional: true,
 				Attributes: map[string]schema.Attribute{
@@ -295,6 +341,33 @@ func (p *ImageTestProvider) Schema(ctx context.Context, req provider.SchemaReque
 							},
 						},
 					},
+					"pterraform": schema.SingleNestedAttribute{
+						Optional: true,
+						Attributes: map[string]schema.Attribute{
+							"binary": schema.SingleNestedAttribute{
+								Description: "The default terraform executable of pterraform harnesses, used when the harness doesn't set its own.",
+								Optional:    true,
+								Attributes: map[string]schema.Attribute{
+									"flavor": schema.StringAttribute{
+										Description: "The flavor of the executable, either `terraform` or `tofu`. Defaults to `terraform`.",
+										Optional:    true,
+									},
+									"path": schema.StringAttribute{
+										Description: "The path to the executable. Defaults to finding the executable in `cache_dir` or on $PATH.",
+										Optional:    true,
+									},
+									"version": schema.StringAttribute{
+										Description: "A version constraint the executable must satisfy, e.g. `>= 1.8, < 2.0`. An exact version missing from `cache_dir` and $PATH is installed into `cache_dir`. Installed `terraform` is verified against HashiCorp's signing key, while installed `tofu` is only checked against the release's SHA256SUMS, which isn't signature verified; use `path` with a vetted executable when that matters.",
+										Optional:    true,
+									},
+									"cache_dir": schema.StringAttribute{
+										Description: "The directory executables are installed to. Defaults to `imagetest/pterraform` in the user's cache directory.",
+										Optional:    true,
+									},
+								},
+							},
+						},
+					},
 				},
 			},
 		},
@@ -404,6 +477,7 @@ func (p *ImageTestProvider) Resources(_ context.Context) []func() resource.Resou
 	return []func() resource.Resource{
 		NewFeatureResource,
 		NewContainerVolumeResource,
+		NewInventoryCleanupResource,
 		// Harnesses
 		NewHarnessK3sResource,
 		NewHarnessDockerResource,
@@ -422,6 +496,7 @@ func (p *ImageTestProvider) Resources(_ context.Context) []func() resource.Resou
 func (p *ImageTestProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
 	return []func() datasource.DataSource{
 		NewInventoryDataSource,
+		NewInventoryStatusDataSource,
 	}
 }
 
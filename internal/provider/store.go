@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"path/filepath"
 	"sync"
 
 	"github.com/chainguard-dev/clog"
@@ -101,6 +102,40 @@ func (s *ProviderStore) Encode(components ...string) (string, error) {
 	return hashint.Text(36)[:5], nil
 }
 
+// Inventory returns the inventory for the seed of an inventory data source,
+// stored in the backend configured on the provider.
+func (s *ProviderStore) Inventory(ctx context.Context, seed string) (*inventory.Inventory, error) {
+	s.inv.mu.Lock()
+	defer s.inv.mu.Unlock()
+
+	if inv, ok := s.inv.store[seed]; ok {
+		return inv, nil
+	}
+
+	var (
+		backend inventory.Backend
+		err     error
+	)
+	if cfg := s.providerResourceData.Inventory; cfg != nil && cfg.S3 != nil {
+		// Each seed gets its own "directory" of the bucket
+		backend, err = inventory.NewS3Backend(ctx, inventory.S3Config{
+			Bucket:   cfg.S3.Bucket.ValueString(),
+			Prefix:   cfg.S3.Prefix.ValueString() + filepath.Base(seed) + "/",
+			Endpoint: cfg.S3.Endpoint.ValueString(),
+			Region:   cfg.S3.Region.ValueString(),
+		})
+	} else {
+		backend, err = inventory.NewLocalBackend(seed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	inv := inventory.New(backend)
+	s.inv.store[seed] = inv
+	return inv, nil
+}
+
 // Logger initializes the context logger for the given inventory.
 func (s *ProviderStore) Logger(ctx context.Context, inv InventoryDataSourceModel, withs ...any) (context.Context, error) {
 	logger := clog.FromContext(ctx).With(withs...)
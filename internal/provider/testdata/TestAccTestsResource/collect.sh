@@ -0,0 +1,6 @@
+#!/bin/sh
+set -eux
+
+# Written outside of IMAGETEST_ARTIFACTS, and gathered by the collect patterns
+mkdir -p /var/log/app
+echo "collected" >/var/log/app/app.log
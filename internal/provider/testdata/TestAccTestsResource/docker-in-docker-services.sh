@@ -0,0 +1,7 @@
+#!/bin/sh
+
+set -e
+
+# The "web" service is started and healthy before the test runs, and is
+# reachable by its alias.
+wget -q -O - http://web:8080/index.html | grep "hello from web"
@@ -0,0 +1,8 @@
+#!/bin/sh
+set -eux
+
+# Allowed by the egress_allowlist
+wget -q -O /dev/null https://cgr.dev/v2/
+
+# Refused by the proxy, which fails the test even though this is ignored
+wget -q -O /dev/null http://example.com/ || true
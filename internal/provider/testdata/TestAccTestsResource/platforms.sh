@@ -0,0 +1,11 @@
+#!/bin/sh
+set -eux
+
+# Each platform runs in its own sandbox, emulated when foreign to the host
+arch="$(uname -m)"
+case "${arch}" in
+x86_64 | aarch64) ;;
+*) exit 1 ;;
+esac
+
+docker info --format '{{.Architecture}}'
@@ -0,0 +1,13 @@
+#!/bin/sh
+set -eux
+
+# IMAGETEST_ENTRYPOINT is set by the entrypoint to its own path
+"${IMAGETEST_ENTRYPOINT}" annotate suite=steps
+
+"${IMAGETEST_ENTRYPOINT}" step start setup
+echo "setting up"
+"${IMAGETEST_ENTRYPOINT}" step end setup
+
+"${IMAGETEST_ENTRYPOINT}" step start verify
+"${IMAGETEST_ENTRYPOINT}" annotate answer=42
+"${IMAGETEST_ENTRYPOINT}" step end verify --status=error --message="expected failure"
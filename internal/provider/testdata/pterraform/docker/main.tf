@@ -19,3 +19,12 @@ output "connection" {
     }
   }
 }
+
+output "greeting" {
+  value = "hello ${var.foo}"
+}
+
+output "token" {
+  value     = "s3cr3t"
+  sensitive = true
+}
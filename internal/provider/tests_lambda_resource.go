@@ -2,13 +2,18 @@ package provider
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/chainguard-dev/clog"
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/drivers"
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/drivers/lambda"
 	"github.com/chainguard-dev/terraform-provider-imagetest/internal/provider/framework"
 	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
@@ -26,13 +31,39 @@ type TestsLambdaResource struct {
 	framework.WithTypeName
 	framework.WithNoOpDelete
 	framework.WithNoOpRead
+
+	ropts []remote.Option
 }
 
 type TestsLambdaResourceModel struct {
-	Id            types.String `tfsdk:"id"`
-	ImageRef      types.String `tfsdk:"image_ref"`
-	ExecutionRole types.String `tfsdk:"execution_role"`
-	Region        types.String `tfsdk:"region"`
+	Id            types.String                   `tfsdk:"id"`
+	ImageRef      types.String                   `tfsdk:"image_ref"`
+	ExecutionRole types.String                   `tfsdk:"execution_role"`
+	Region        types.String                   `tfsdk:"region"`
+	Local         *TestsLambdaLocalResourceModel `tfsdk:"local"`
+	Invocations   []*TestsLambdaInvocationModel  `tfsdk:"invocations"`
+}
+
+type TestsLambdaInvocationModel struct {
+	Name                types.String                           `tfsdk:"name"`
+	Payload             types.String                           `tfsdk:"payload"`
+	ClientContext       types.String                           `tfsdk:"client_context"`
+	ExpectStatus        types.Int64                            `tfsdk:"expect_status"`
+	ExpectFunctionError types.Bool                             `tfsdk:"expect_function_error"`
+	MaxDuration         types.String                           `tfsdk:"max_duration"`
+	Assertions          []*TestsLambdaInvocationAssertionModel `tfsdk:"assertions"`
+}
+
+type TestsLambdaInvocationAssertionModel struct {
+	JSONPath types.String `tfsdk:"json_path"`
+	Equals   types.String `tfsdk:"equals"`
+	Matches  types.String `tfsdk:"matches"`
+}
+
+type TestsLambdaLocalResourceModel struct {
+	EmulatorPath    types.String `tfsdk:"emulator_path"`
+	EmulatorVersion types.String `tfsdk:"emulator_version"`
+	EmulatorSHA256  types.String `tfsdk:"emulator_sha256"`
 }
 
 func (t *TestsLambdaResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
@@ -48,8 +79,8 @@ func (t *TestsLambdaResource) Schema(ctx context.Context, req resource.SchemaReq
 				Required:    true,
 			},
 			"execution_role": schema.StringAttribute{
-				Description: "The ARN of the IAM role to use for the Lambda function.",
-				Required:    true,
+				Description: "The ARN of the IAM role to use for the Lambda function. Required unless testing locally.",
+				Optional:    true,
 			},
 			"region": schema.StringAttribute{
 				Description: "The AWS region to deploy the test in. If not provided, the default region will be used.",
@@ -57,11 +88,92 @@ func (t *TestsLambdaResource) Schema(ctx context.Context, req resource.SchemaReq
 				Computed:    true,
 				Default:     stringdefault.StaticString("us-west-2"),
 			},
+			"local": schema.SingleNestedAttribute{
+				Description: "When set, the function is run in a local container under the AWS Lambda Runtime Interface Emulator instead of being deployed to AWS. No AWS credentials are required.",
+				Optional:    true,
+				Attributes: map[string]schema.Attribute{
+					"emulator_path": schema.StringAttribute{
+						Description: "Path to an aws-lambda-rie binary matching the image's architecture. If not provided, the emulator_version release is downloaded and cached.",
+						Optional:    true,
+					},
+					"emulator_version": schema.StringAttribute{
+						Description: "The aws-lambda-rie release to download when emulator_path isn't provided, e.g. " + lambda.DefaultEmulatorVersion + ". Defaults to " + lambda.DefaultEmulatorVersion + ".",
+						Optional:    true,
+					},
+					"emulator_sha256": schema.StringAttribute{
+						Description: "The sha256 checksum of the downloaded aws-lambda-rie binary, for the image's architecture. The download fails unless it matches. Required when emulator_path isn't provided.",
+						Optional:    true,
+					},
+				},
+			},
+			"invocations": schema.ListNestedAttribute{
+				Description: "Invocations to run, in order, once the function is active. Every invocation runs even if an earlier one fails, and each failure is reported separately. If not provided, the function is invoked once with no payload and must not return an error.",
+				Optional:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Description: "The name of the invocation, used when reporting results. Defaults to its position in the list.",
+							Optional:    true,
+						},
+						"payload": schema.StringAttribute{
+							Description: "The JSON event to invoke the function with.",
+							Optional:    true,
+						},
+						"client_context": schema.StringAttribute{
+							Description: "JSON passed to the function as the client context.",
+							Optional:    true,
+						},
+						"expect_status": schema.Int64Attribute{
+							Description: "The expected status code of the invocation. Defaults to 200.",
+							Optional:    true,
+						},
+						"expect_function_error": schema.BoolAttribute{
+							Description: "Whether the function is expected to return an error. Defaults to false, so any function error fails the invocation.",
+							Optional:    true,
+						},
+						"max_duration": schema.StringAttribute{
+							Description: "The maximum time the invocation may take, as a Go duration string (e.g. \"500ms\").",
+							Optional:    true,
+						},
+						"assertions": schema.ListNestedAttribute{
+							Description: "Assertions on the response payload.",
+							Optional:    true,
+							NestedObject: schema.NestedAttributeObject{
+								Attributes: map[string]schema.Attribute{
+									"json_path": schema.StringAttribute{
+										Description: "A JSONPath expression (e.g. \"$.body\") selecting the value to check. If not provided, the whole payload is checked.",
+										Optional:    true,
+									},
+									"equals": schema.StringAttribute{
+										Description: "The value must be exactly this.",
+										Optional:    true,
+									},
+									"matches": schema.StringAttribute{
+										Description: "The value must match this regular expression.",
+										Optional:    true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
 		},
 	}
 }
 
-func (t *TestsLambdaResource) Configure(context.Context, resource.ConfigureRequest, *resource.ConfigureResponse) {
+func (t *TestsLambdaResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	store, ok := req.ProviderData.(*ProviderStore)
+	if !ok {
+		resp.Diagnostics.AddError("invalid provider data", "...")
+		return
+	}
+
+	t.ropts = store.ropts
 }
 
 func (t *TestsLambdaResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -96,7 +208,24 @@ func (t *TestsLambdaResource) do(ctx context.Context, data *TestsLambdaResourceM
 
 	data.Id = types.StringValue(ref.DigestStr())
 
-	dr, err := lambda.NewDriver(data.Region.ValueString(), data.ExecutionRole.ValueString())
+	invocations, err := parseInvocationModels(data.Invocations)
+	if err != nil {
+		return []diag.Diagnostic{diag.NewErrorDiagnostic("invalid invocations", err.Error())}
+	}
+
+	var dr drivers.Tester
+	if data.Local != nil {
+		dr, err = lambda.NewLocalDriver(lambda.Emulator{
+			Path:    data.Local.EmulatorPath.ValueString(),
+			Version: data.Local.EmulatorVersion.ValueString(),
+			SHA256:  data.Local.EmulatorSHA256.ValueString(),
+		}, invocations, t.ropts...)
+	} else {
+		if data.ExecutionRole.ValueString() == "" {
+			return []diag.Diagnostic{diag.NewErrorDiagnostic("missing execution role", "execution_role is required unless testing locally")}
+		}
+		dr, err = lambda.NewDriver(data.Region.ValueString(), data.ExecutionRole.ValueString(), invocations)
+	}
 	if err != nil {
 		return []diag.Diagnostic{diag.NewErrorDiagnostic("failed to create driver", err.Error())}
 	}
@@ -113,13 +242,77 @@ func (t *TestsLambdaResource) do(ctx context.Context, data *TestsLambdaResourceM
 		return ds
 	}
 
-	if _, err := dr.Run(ctx, ref); err != nil {
-		ds = []diag.Diagnostic{diag.NewErrorDiagnostic("test failed", err.Error())}
-		return ds
+	_, err = dr.Run(ctx, ref)
+
+	// Report every invocation, passed ones as warnings so they're visible
+	if r, ok := dr.(lambda.InvocationReporter); ok {
+		for _, res := range r.InvocationResults() {
+			if res.Err != nil {
+				ds = append(ds, diag.NewErrorDiagnostic(fmt.Sprintf("invocation %q failed", res.Name), res.Err.Error()))
+				continue
+			}
+			ds = append(ds, diag.NewWarningDiagnostic(fmt.Sprintf("invocation %q passed", res.Name), fmt.Sprintf("status %d in %s", res.StatusCode, res.Duration)))
+		}
+	}
+
+	var ierrs lambda.InvocationErrors
+	if err != nil && (!errors.As(err, &ierrs) || !ds.HasError()) {
+		ds = append(ds, diag.NewErrorDiagnostic("test failed", err.Error()))
 	}
 	return ds
 }
 
+func parseInvocationModels(ms []*TestsLambdaInvocationModel) ([]lambda.Invocation, error) {
+	var invocations []lambda.Invocation
+	for i, m := range ms {
+		if m == nil {
+			continue
+		}
+
+		inv := lambda.Invocation{
+			Name:                m.Name.ValueString(),
+			ExpectStatus:        int(m.ExpectStatus.ValueInt64()),
+			ExpectFunctionError: m.ExpectFunctionError.ValueBool(),
+		}
+		if inv.Name == "" {
+			inv.Name = strconv.Itoa(i)
+		}
+		if m.Payload.ValueString() != "" {
+			inv.Payload = []byte(m.Payload.ValueString())
+		}
+		if m.ClientContext.ValueString() != "" {
+			inv.ClientContext = []byte(m.ClientContext.ValueString())
+		}
+		if m.MaxDuration.ValueString() != "" {
+			d, err := time.ParseDuration(m.MaxDuration.ValueString())
+			if err != nil {
+				return nil, fmt.Errorf("invocation %s: invalid max_duration: %w", inv.Name, err)
+			}
+			inv.MaxDuration = d
+		}
+
+		for _, a := range m.Assertions {
+			if a == nil {
+				continue
+			}
+			assertion := lambda.Assertion{
+				JSONPath: a.JSONPath.ValueString(),
+				Matches:  a.Matches.ValueString(),
+			}
+			if !a.Equals.IsNull() {
+				assertion.Equals = a.Equals.ValueStringPointer()
+			}
+			inv.Assertions = append(inv.Assertions, assertion)
+		}
+
+		if err := inv.Validate(); err != nil {
+			return nil, fmt.Errorf("invocation %s: %w", inv.Name, err)
+		}
+		invocations = append(invocations, inv)
+	}
+	return invocations, nil
+}
+
 func (t *TestsLambdaResource) maybeTeardown(ctx context.Context, d drivers.Tester, failed bool) diag.Diagnostic {
 	if v := os.Getenv("IMAGETEST_LAMBDA_SKIP_TEARDOWN"); v != "" {
 		return diag.NewWarningDiagnostic("skipping teardown", "IMAGETEST_SKIP_TEARDOWN is set, skipping teardown")
@@ -33,3 +33,40 @@ func TestAccTestsResource_Lambda(t *testing.T) {
 }`, executionRole, ref)}},
 	})
 }
+
+func TestAccTestsResource_LambdaLocal(t *testing.T) {
+	ref := os.Getenv("IMAGETEST_LAMBDA_TEST_IMAGE_REF")
+	if ref == "" {
+		t.Fatal("IMAGETEST_LAMBDA_TEST_IMAGE_REF must be set")
+	}
+	checksum := os.Getenv("IMAGETEST_LAMBDA_EMULATOR_SHA256")
+	if checksum == "" {
+		t.Fatal("IMAGETEST_LAMBDA_EMULATOR_SHA256 must be set")
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+			"imagetest": providerserver.NewProtocol6WithError(&ImageTestProvider{}),
+		},
+		Steps: []resource.TestStep{{Config: fmt.Sprintf(`resource "imagetest_tests_lambda" "foo" {
+  image_ref = %q
+  local     = { emulator_sha256 = %q }
+
+  invocations = [
+    {
+      name         = "empty-event"
+      payload      = jsonencode({})
+      max_duration = "30s"
+    },
+    {
+      name           = "client-context"
+      payload        = jsonencode({ hello = "world" })
+      client_context = jsonencode({
+        custom = { source = "imagetest" }
+      })
+    },
+  ]
+}`, ref, checksum)}},
+	})
+}
@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// sandbox is a test image to run a test in. Platform is only set when the
+// suite runs its tests per platform.
+type sandbox struct {
+	Platform string
+	Ref      name.Reference
+}
+
+type TestPlatformResultResourceModel struct {
+	Platform types.String `tfsdk:"platform"`
+	Passed   types.Bool   `tfsdk:"passed"`
+	Artifact types.Object `tfsdk:"artifact"`
+}
+
+var testPlatformResultAttTypes = map[string]attr.Type{
+	"platform": types.StringType,
+	"passed":   types.BoolType,
+	"artifact": types.ObjectType{AttrTypes: testArtifactAttTypes},
+}
+
+// parsePlatforms parses the suite's platforms, e.g. linux/arm64 or
+// linux/arm/v7.
+func parsePlatforms(platforms []string) ([]v1.Platform, error) {
+	parsed := make([]v1.Platform, 0, len(platforms))
+	for _, p := range platforms {
+		platform, err := v1.ParsePlatform(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid platform %q: %w", p, err)
+		}
+		for _, existing := range parsed {
+			if existing.Equals(*platform) {
+				return nil, fmt.Errorf("platform %q is listed more than once", p)
+			}
+		}
+		parsed = append(parsed, *platform)
+	}
+	return parsed, nil
+}
+
+// matchPlatforms returns the digest of the first manifest in the index for
+// each of the platforms.
+func matchPlatforms(mfst *v1.IndexManifest, platforms []v1.Platform) ([]v1.Hash, error) {
+	digests := make([]v1.Hash, 0, len(platforms))
+	for _, want := range platforms {
+		found := false
+		for _, m := range mfst.Manifests {
+			if m.Platform != nil && m.Platform.Satisfies(want) {
+				digests = append(digests, m.Digest)
+				found = true
+				break
+			}
+		}
+		if !found {
+			available := make([]string, 0, len(mfst.Manifests))
+			for _, m := range mfst.Manifests {
+				if m.Platform != nil {
+					available = append(available, m.Platform.String())
+				}
+			}
+			return nil, fmt.Errorf("no image for platform %s, the image index has: %s", want.String(), strings.Join(available, ", "))
+		}
+	}
+	return digests, nil
+}
+
+// sandboxes returns the sandboxes each test runs in, one per platform when
+// platforms are set, or the test image itself otherwise.
+func (t *TestsResource) sandboxes(ctx context.Context, data *TestsResourceModel, trefs []name.Reference) ([][]sandbox, diag.Diagnostics) {
+	sbs := make([][]sandbox, 0, len(trefs))
+	if len(data.Platforms) == 0 {
+		for _, tref := range trefs {
+			sbs = append(sbs, []sandbox{{Ref: tref}})
+		}
+		return sbs, nil
+	}
+
+	platforms, err := parsePlatforms(data.Platforms)
+	if err != nil {
+		return nil, []diag.Diagnostic{diag.NewErrorDiagnostic("invalid platforms", err.Error())}
+	}
+
+	ropts := append(slices.Clone(t.ropts), remote.WithContext(ctx))
+	for i, tref := range trefs {
+		testName := data.Tests[i].Name.ValueString()
+
+		desc, err := remote.Get(tref, ropts...)
+		if err != nil {
+			return nil, []diag.Diagnostic{diag.NewErrorDiagnostic("failed to get test image", fmt.Sprintf("test %q: %v", testName, err))}
+		}
+
+		var digests []v1.Hash
+		if desc.MediaType.IsIndex() {
+			idx, err := desc.ImageIndex()
+			if err != nil {
+				return nil, []diag.Diagnostic{diag.NewErrorDiagnostic("failed to get test image index", fmt.Sprintf("test %q: %v", testName, err))}
+			}
+			mfst, err := idx.IndexManifest()
+			if err != nil {
+				return nil, []diag.Diagnostic{diag.NewErrorDiagnostic("failed to get test image index manifest", fmt.Sprintf("test %q: %v", testName, err))}
+			}
+			digests, err = matchPlatforms(mfst, platforms)
+			if err != nil {
+				return nil, []diag.Diagnostic{diag.NewErrorDiagnostic("unsupported platforms", fmt.Sprintf("test %q: %v", testName, err))}
+			}
+		} else {
+			// A single image can only run on its own platform
+			img, err := desc.Image()
+			if err != nil {
+				return nil, []diag.Diagnostic{diag.NewErrorDiagnostic("failed to get test image", fmt.Sprintf("test %q: %v", testName, err))}
+			}
+			cfg, err := img.ConfigFile()
+			if err != nil {
+				return nil, []diag.Diagnostic{diag.NewErrorDiagnostic("failed to get test image config", fmt.Sprintf("test %q: %v", testName, err))}
+			}
+			digests, err = matchPlatforms(&v1.IndexManifest{
+				Manifests: []v1.Descriptor{{Digest: desc.Digest, Platform: cfg.Platform()}},
+			}, platforms)
+			if err != nil {
+				return nil, []diag.Diagnostic{diag.NewErrorDiagnostic("unsupported platforms", fmt.Sprintf("test %q: %v", testName, err))}
+			}
+		}
+
+		tsbs := make([]sandbox, 0, len(platforms))
+		for j, dig := range digests {
+			tsbs = append(tsbs, sandbox{
+				Platform: platforms[j].String(),
+				Ref:      tref.Context().Digest(dig.String()),
+			})
+		}
+		sbs = append(sbs, tsbs)
+	}
+
+	return sbs, nil
+}
+
+// testPlatformResultsValue converts the per platform results into the
+// computed platform_results attribute.
+func testPlatformResultsValue(results []TestPlatformResultResourceModel) (types.List, diag.Diagnostics) {
+	return types.ListValueFrom(context.Background(), types.ObjectType{AttrTypes: testPlatformResultAttTypes}, results)
+}
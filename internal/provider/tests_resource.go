@@ -38,6 +38,7 @@ import (
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
+	kresource "k8s.io/apimachinery/pkg/api/resource"
 )
 
 type contextKey string
@@ -80,6 +81,8 @@ type TestsResourceModel struct {
 	Skipped      types.Bool                 `tfsdk:"skipped"`
 	RepoOverride types.String               `tfsdk:"repo"`
 	Retry        *RetryResourceModel        `tfsdk:"retry"`
+	Platforms    []string                   `tfsdk:"platforms"`
+	BinfmtImage  types.String               `tfsdk:"binfmt_image"`
 }
 
 type TestsImageResource map[string]string
@@ -109,15 +112,35 @@ func (t TestsImageResource) Resolve() (map[string]TestsImagesParsed, error) {
 }
 
 type TestResourceModel struct {
-	Name      types.String               `tfsdk:"name"`
-	Image     types.String               `tfsdk:"image"`
-	Content   []TestContentResourceModel `tfsdk:"content"`
-	Envs      map[string]string          `tfsdk:"envs"`
-	Cmd       types.String               `tfsdk:"cmd"`
-	Timeout   types.String               `tfsdk:"timeout"`
-	Artifact  types.Object               `tfsdk:"artifact"`
-	OnFailure []string                   `tfsdk:"on_failure"`
-	Retry     *RetryResourceModel        `tfsdk:"retry"`
+	Name            types.String               `tfsdk:"name"`
+	Image           types.String               `tfsdk:"image"`
+	Content         []TestContentResourceModel `tfsdk:"content"`
+	Envs            map[string]string          `tfsdk:"envs"`
+	Cmd             types.String               `tfsdk:"cmd"`
+	Commands        []TestCommandResourceModel `tfsdk:"commands"`
+	Timeout         types.String               `tfsdk:"timeout"`
+	Artifact        types.Object               `tfsdk:"artifact"`
+	Steps           types.List                 `tfsdk:"steps"`
+	Annotations     types.Map                  `tfsdk:"annotations"`
+	OnFailure       []string                   `tfsdk:"on_failure"`
+	Collect         []TestCollectResourceModel `tfsdk:"collect"`
+	RecordEgress    types.Bool                 `tfsdk:"record_egress"`
+	EgressAllowlist []string                   `tfsdk:"egress_allowlist"`
+	Retry           *RetryResourceModel        `tfsdk:"retry"`
+	PlatformResults types.List                 `tfsdk:"platform_results"`
+}
+
+type TestCommandResourceModel struct {
+	Name            types.String `tfsdk:"name"`
+	Cmd             types.String `tfsdk:"cmd"`
+	Timeout         types.String `tfsdk:"timeout"`
+	ContinueOnError types.Bool   `tfsdk:"continue_on_error"`
+}
+
+type TestCollectResourceModel struct {
+	Pattern   types.String `tfsdk:"pattern"`
+	MaxSize   types.String `tfsdk:"max_size"`
+	OnFailure types.Bool   `tfsdk:"on_failure"`
 }
 
 type RetryResourceModel struct {
@@ -230,6 +253,30 @@ func (t *TestsResource) Schema(ctx context.Context, req resource.SchemaRequest,
 							Description: "When specified, will override the sandbox image's CMD (oci config).",
 							Optional:    true,
 						},
+						"commands": schema.ListNestedAttribute{
+							Description: "An ordered list of named commands to run in place of `cmd`. The entrypoint runs each with `sh -c` once the sandbox is ready, and records it as a step with its exit code. A failed command stops the remaining commands and fails the test, unless it continues on error.",
+							Optional:    true,
+							NestedObject: schema.NestedAttributeObject{
+								Attributes: map[string]schema.Attribute{
+									"name": schema.StringAttribute{
+										Description: "The name of the command, used as its step name. Names must be unique within the test.",
+										Required:    true,
+									},
+									"cmd": schema.StringAttribute{
+										Description: "The command to run.",
+										Required:    true,
+									},
+									"timeout": schema.StringAttribute{
+										Description: "The maximum amount of time the command may run. A command that exceeds it is terminated and recorded with exit code 124. This is encompassed by the timeout of the test.",
+										Optional:    true,
+									},
+									"continue_on_error": schema.BoolAttribute{
+										Description: "Run the remaining commands when this one fails, without failing the test.",
+										Optional:    true,
+									},
+								},
+							},
+						},
 						"envs": schema.MapAttribute{
 							Description: "Environment variables to set on the test container. These will overwrite the environment variables set in the image's config on conflicts.",
 							Optional:    true,
@@ -244,13 +291,42 @@ func (t *TestsResource) Schema(ctx context.Context, req resource.SchemaRequest,
 							Optional:    true,
 							ElementType: types.StringType,
 						},
+						"collect": schema.ListNestedAttribute{
+							Description: "Files in the sandbox to add to the test's artifact under `collected/`, in addition to the contents of `$IMAGETEST_ARTIFACTS`. A `collected/manifest.json` lists every matched file, and why it was skipped if it was. When the test crashes with a core dumping signal, core dumps written by the kernel's `core_pattern` are collected automatically.",
+							Optional:    true,
+							NestedObject: schema.NestedAttributeObject{
+								Attributes: map[string]schema.Attribute{
+									"pattern": schema.StringAttribute{
+										Description: "An absolute path glob of the files to collect, e.g. `/var/log/*.log`. Matched directories are collected recursively, symlinks are not followed.",
+										Required:    true,
+									},
+									"max_size": schema.StringAttribute{
+										Description: "The maximum total size of the files collected by this pattern as a quantity, e.g. `50Mi`. Files that would exceed it are skipped. Defaults to `10Mi`.",
+										Optional:    true,
+									},
+									"on_failure": schema.BoolAttribute{
+										Description: "Only collect the files when the test fails.",
+										Optional:    true,
+									},
+								},
+							},
+						},
+						"record_egress": schema.BoolAttribute{
+							Description: "Record the network destinations the test contacts. The test's HTTP_PROXY and HTTPS_PROXY point at a proxy run by the entrypoint, which writes every destination to egress.json in the test's artifact and as attributes of the test span. Only clients that honor the proxy environment variables are recorded. Loopback addresses and the Kubernetes api server are not proxied.",
+							Optional:    true,
+						},
+						"egress_allowlist": schema.ListAttribute{
+							Description: "The network destinations the test may contact, implies `record_egress`. Each entry is a host, optionally prefixed with `*.` to match any subdomain, and optionally followed by `:port`. Requests to other destinations are refused by the proxy, and the test fails listing them even if it otherwise succeeded.",
+							Optional:    true,
+							ElementType: types.StringType,
+						},
 						"retry": retrySchema("Re-runs this individual test within the same driver instance. " +
 							"Each retry launches a fresh test sandbox container, but all driver-level state persists: " +
 							"for Kubernetes-based drivers (k3s_in_docker, EKS, AKS) this means the cluster, namespace, RBAC, secrets, and any objects created by previous attempts are still present. " +
 							"For EC2, the instance filesystem and Docker daemon state carry over. " +
 							"Tests must be idempotent — use create-or-update patterns, unique names, or explicit cleanup to avoid conflicts with leftover state from failed attempts."),
 						"artifact": schema.SingleNestedAttribute{
-							Description: "The bundled artifact generated by the test.",
+							Description: "The bundled artifact generated by the test. When a test on a Kubernetes based driver fails, a snapshot of the cluster's events, objects and pod logs is included under the diagnostics/ directory.",
 							Optional:    true,
 							Computed:    true,
 							Attributes: map[string]schema.Attribute{
@@ -264,6 +340,74 @@ func (t *TestsResource) Schema(ctx context.Context, req resource.SchemaRequest,
 								},
 							},
 						},
+						"steps": schema.ListNestedAttribute{
+							Description: "The steps reported by the test, in the order they started. Tests report steps by running `$IMAGETEST_ENTRYPOINT step start <name>` and `$IMAGETEST_ENTRYPOINT step end <name> [--status=ok|error] [--message=<message>]`. Each step is also recorded as a child span of the test.",
+							Computed:    true,
+							NestedObject: schema.NestedAttributeObject{
+								Attributes: map[string]schema.Attribute{
+									"name": schema.StringAttribute{
+										Description: "The name of the step.",
+										Computed:    true,
+									},
+									"status": schema.StringAttribute{
+										Description: "The result of the step, one of: ok, error, or incomplete if the test exited before ending it.",
+										Computed:    true,
+									},
+									"message": schema.StringAttribute{
+										Description: "The message the step ended with.",
+										Computed:    true,
+									},
+									"duration": schema.StringAttribute{
+										Description: "How long the step took.",
+										Computed:    true,
+									},
+									"exit_code": schema.Int64Attribute{
+										Description: "The exit code of the command, for steps run from `commands`.",
+										Computed:    true,
+									},
+									"annotations": schema.MapAttribute{
+										Description: "Annotations made while the step was the innermost open step.",
+										Computed:    true,
+										ElementType: types.StringType,
+									},
+								},
+							},
+						},
+						"annotations": schema.MapAttribute{
+							Description: "Annotations made by the test outside of any step, with `$IMAGETEST_ENTRYPOINT annotate <key>=<value>...`.",
+							Computed:    true,
+							ElementType: types.StringType,
+						},
+						"platform_results": schema.ListNestedAttribute{
+							Description: "The result of the test on each of the suite's `platforms`, in the same order. The test's `artifact`, `steps` and `annotations` are those of the last platform run.",
+							Computed:    true,
+							NestedObject: schema.NestedAttributeObject{
+								Attributes: map[string]schema.Attribute{
+									"platform": schema.StringAttribute{
+										Description: "The platform the test ran on.",
+										Computed:    true,
+									},
+									"passed": schema.BoolAttribute{
+										Description: "Whether the test passed on the platform.",
+										Computed:    true,
+									},
+									"artifact": schema.SingleNestedAttribute{
+										Description: "The bundled artifact generated by the test on the platform.",
+										Computed:    true,
+										Attributes: map[string]schema.Attribute{
+											"uri": schema.StringAttribute{
+												Description: "The URI of the artifact. The artifact is in targz format.",
+												Computed:    true,
+											},
+											"checksum": schema.StringAttribute{
+												Description: "The checksum of the artifact.",
+												Computed:    true,
+											},
+										},
+									},
+								},
+							},
+						},
 					},
 				},
 			},
@@ -273,6 +417,15 @@ func (t *TestsResource) Schema(ctx context.Context, req resource.SchemaRequest,
 				Computed:    true,
 				Default:     stringdefault.StaticString(TestsResourceDefaultTimeout),
 			},
+			"platforms": schema.ListAttribute{
+				Description: "The platforms to run each test on, e.g. `linux/amd64` and `linux/arm64`. Each test image must be an index with an image for every platform, or a single image of the only platform. The tests run in a sandbox per platform, one platform after the other, and report each in `platform_results`. The docker_in_docker and k3s_in_docker drivers run platforms foreign to the docker host under QEMU emulation, registering its binfmt handlers on the docker host with `binfmt_image`. Other drivers run each platform as is. When unset, tests run on the driver's native platform only.",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+			"binfmt_image": schema.StringAttribute{
+				Description: "The image that registers the QEMU emulators for `platforms` foreign to the docker host, e.g. `tonistiigi/binfmt@sha256:...`. The image runs privileged, so it must be pinned by digest. Required to emulate foreign platforms with the docker_in_docker and k3s_in_docker drivers.",
+				Optional:    true,
+			},
 			"labels": schema.MapAttribute{
 				Description: "Metadata to attach to the tests resource. Used for filtering and grouping.",
 				Optional:    true,
@@ -378,6 +531,17 @@ func (t *TestsResource) do(ctx context.Context, data *TestsResourceModel) (ds di
 	// Store test_id in context to deconflict with other tests
 	ctx = context.WithValue(ctx, contextKeyResourceTestID, id)
 
+	ctx = o11y.WithMetricAttributes(ctx,
+		attribute.String(o11y.AttrName, data.Name.ValueString()),
+		attribute.String(o11y.AttrDriver, string(data.Driver)),
+	)
+	ctx = o11y.WithMetricAttributes(ctx, o11y.LabelAttributes(data.Labels)...)
+	defer func() {
+		if err := o11y.FlushMetrics(context.WithoutCancel(ctx)); err != nil {
+			clog.WarnContextf(ctx, "failed to flush metrics: %v", err)
+		}
+	}()
+
 	for _, test := range data.Tests {
 		if test.Artifact.IsNull() || test.Artifact.IsUnknown() {
 			emptyArtifact := map[string]attr.Value{
@@ -388,6 +552,41 @@ func (t *TestsResource) do(ctx context.Context, data *TestsResourceModel) (ds di
 			ds.Append(objDiags...)
 			test.Artifact = artifactObj
 		}
+		if test.Steps.IsUnknown() {
+			test.Steps = types.ListNull(types.ObjectType{AttrTypes: testStepAttTypes})
+		}
+		if test.Annotations.IsUnknown() {
+			test.Annotations = types.MapNull(types.StringType)
+		}
+		if test.PlatformResults.IsUnknown() {
+			test.PlatformResults = types.ListNull(types.ObjectType{AttrTypes: testPlatformResultAttTypes})
+		}
+		if _, err := testCollectRules(test); err != nil {
+			ds.AddError("invalid collect", fmt.Sprintf("test %q: %v", test.Name.ValueString(), err))
+		}
+		if _, err := testCommands(test); err != nil {
+			ds.AddError("invalid commands", fmt.Sprintf("test %q: %v", test.Name.ValueString(), err))
+		}
+		if err := entrypoint.EgressAllowlist(test.EgressAllowlist).Validate(); err != nil {
+			ds.AddError("invalid egress_allowlist", fmt.Sprintf("test %q: %v", test.Name.ValueString(), err))
+		}
+	}
+	if _, err := parsePlatforms(data.Platforms); err != nil {
+		ds.AddError("invalid platforms", err.Error())
+	}
+	if data.BinfmtImage.ValueString() != "" {
+		if _, err := drivers.ParseBinfmtRef(data.BinfmtImage.ValueString()); err != nil {
+			ds.AddError("invalid binfmt_image", err.Error())
+		}
+	}
+	if ds.HasError() {
+		return ds
+	}
+
+	// Computed driver attributes are only known once the driver is up, so
+	// default them for the paths that never get that far.
+	if data.Drivers != nil && data.Drivers.K3sInDocker != nil && data.Drivers.K3sInDocker.EffectiveKubernetesVersion.IsUnknown() {
+		data.Drivers.K3sInDocker.EffectiveKubernetesVersion = types.StringNull()
 	}
 
 	_skip, reason := skip.Skip(data.Labels, t.includeTests, t.excludeTests)
@@ -398,6 +597,12 @@ func (t *TestsResource) do(ctx context.Context, data *TestsResourceModel) (ds di
 	data.Skipped = types.BoolValue(_skip)
 
 	if data.Skipped.ValueBool() {
+		for _, test := range data.Tests {
+			o11y.Meters().Tests.Add(ctx, 1, o11y.MetricAttributes(ctx,
+				attribute.String(o11y.AttrTest, test.Name.ValueString()),
+				attribute.String(o11y.AttrResult, o11y.ResultSkipped),
+			))
+		}
 		return []diag.Diagnostic{
 			diag.NewWarningDiagnostic(
 				fmt.Sprintf("skipping tests [%s]", id),
@@ -445,11 +650,18 @@ func (t *TestsResource) do(ctx context.Context, data *TestsResourceModel) (ds di
 	}
 
 	// Build test images once — refs are digest-based and stable across retries.
+	buildStart := time.Now()
 	trefs, buildDiags := t.buildTestImages(ctx, data, trepo, imgsResolvedData, id)
+	o11y.Meters().BuildDuration.Record(ctx, time.Since(buildStart).Seconds(), o11y.MetricAttributes(ctx))
 	if buildDiags.HasError() {
 		return buildDiags
 	}
 
+	sbs, sbDiags := t.sandboxes(ctx, data, trefs)
+	if sbDiags.HasError() {
+		return sbDiags
+	}
+
 	tracer := otel.Tracer("imagetest")
 
 	ctx, suiteSpan := tracer.Start(ctx, "imagetest.suite",
@@ -486,7 +698,7 @@ func (t *TestsResource) do(ctx context.Context, data *TestsResourceModel) (ds di
 			))
 		}
 
-		ds = t.doAttempt(ctx, data, trefs, tracer)
+		ds = t.doAttempt(ctx, data, sbs, tracer)
 		if ds.HasError() {
 			return fmt.Errorf("%s", ds[len(ds)-1].Detail())
 		}
@@ -494,6 +706,9 @@ func (t *TestsResource) do(ctx context.Context, data *TestsResourceModel) (ds di
 	})
 
 	if result.Retried {
+		o11y.Meters().Retries.Add(ctx, int64(result.Attempts-1), o11y.MetricAttributes(ctx,
+			attribute.String(o11y.AttrScope, o11y.ScopeSuite),
+		))
 		suiteSpan.SetAttributes(
 			attribute.Int("test.attempts", result.Attempts),
 			attribute.Bool("test.retried", true),
@@ -511,7 +726,7 @@ func (t *TestsResource) do(ctx context.Context, data *TestsResourceModel) (ds di
 
 // doAttempt runs a single attempt of the full driver lifecycle: load → setup →
 // run tests → teardown. Each resource-level retry calls this with a fresh driver.
-func (t *TestsResource) doAttempt(ctx context.Context, data *TestsResourceModel, trefs []name.Reference, tracer trace.Tracer) (ds diag.Diagnostics) {
+func (t *TestsResource) doAttempt(ctx context.Context, data *TestsResourceModel, sbs [][]sandbox, tracer trace.Tracer) (ds diag.Diagnostics) {
 	dr, err := t.LoadDriver(ctx, data)
 	if err != nil {
 		return []diag.Diagnostic{diag.NewErrorDiagnostic("failed to load driver", err.Error())}
@@ -532,7 +747,10 @@ func (t *TestsResource) doAttempt(ctx context.Context, data *TestsResourceModel,
 				attribute.String(o11y.AttrDriver, string(data.Driver)),
 			),
 		)
-		if d := t.maybeTeardown(teardownCtx, dr, ds.HasError()); d != nil {
+		teardownStart := time.Now()
+		d := t.maybeTeardown(teardownCtx, dr, ds.HasError())
+		o11y.Meters().TeardownDuration.Record(ctx, time.Since(teardownStart).Seconds(), o11y.MetricAttributes(ctx))
+		if d != nil {
 			teardownSpan.RecordError(fmt.Errorf("%s", d.Detail()))
 			teardownSpan.SetStatus(codes.Error, d.Detail())
 			ds = append(ds, d)
@@ -547,17 +765,34 @@ func (t *TestsResource) doAttempt(ctx context.Context, data *TestsResourceModel,
 			attribute.String(o11y.AttrDriver, string(data.Driver)),
 		),
 	)
-	if err := dr.Setup(ctx); err != nil {
+	setupStart := time.Now()
+	err = dr.Setup(ctx)
+	o11y.Meters().SetupDuration.Record(ctx, time.Since(setupStart).Seconds(), o11y.MetricAttributes(ctx,
+		attribute.String(o11y.AttrResult, metricResult(err == nil)),
+	))
+	if err != nil {
 		setupSpan.RecordError(err)
 		setupSpan.SetStatus(codes.Error, err.Error())
 		setupSpan.End()
 		return []diag.Diagnostic{diag.NewErrorDiagnostic("failed to setup driver", err.Error())}
 	}
+
+	if kv, ok := dr.(drivers.KubernetesVersioner); ok {
+		setupSpan.SetAttributes(attribute.String("kubernetes.version", kv.KubernetesVersion()))
+		if data.Drivers != nil && data.Drivers.K3sInDocker != nil {
+			data.Drivers.K3sInDocker.EffectiveKubernetesVersion = types.StringValue(kv.KubernetesVersion())
+		}
+	}
+
 	setupSpan.SetStatus(codes.Ok, "")
 	setupSpan.End()
 
-	for i, tref := range trefs {
-		ds.Append(t.doTestWithRetry(ctx, dr, data.Tests[i], tref)...)
+	for i, tsbs := range sbs {
+		if len(data.Platforms) > 0 {
+			ds.Append(t.doTestPlatforms(ctx, dr, data.Tests[i], tsbs)...)
+		} else {
+			ds.Append(t.doTestWithRetry(ctx, dr, data.Tests[i], tsbs[0])...)
+		}
 		if ds.HasError() {
 			return ds
 		}
@@ -566,26 +801,69 @@ func (t *TestsResource) doAttempt(ctx context.Context, data *TestsResourceModel,
 	return ds
 }
 
+// doTestPlatforms runs the test in the sandbox of each platform, so every
+// platform is reported even when an earlier one fails.
+func (t *TestsResource) doTestPlatforms(ctx context.Context, d drivers.Tester, test *TestResourceModel, sbs []sandbox) diag.Diagnostics {
+	var diags diag.Diagnostics
+	results := make([]TestPlatformResultResourceModel, 0, len(sbs))
+	for _, sb := range sbs {
+		// Don't report the artifact of a previous platform
+		artifactObj, objDiags := types.ObjectValue(testArtifactAttTypes, map[string]attr.Value{
+			"uri":      types.StringNull(),
+			"checksum": types.StringNull(),
+		})
+		diags.Append(objDiags...)
+		test.Artifact = artifactObj
+
+		pdiags := t.doTestWithRetry(ctx, d, test, sb)
+		for _, pd := range pdiags {
+			if pd.Severity() == diag.SeverityError {
+				pd = diag.NewErrorDiagnostic(fmt.Sprintf("%s on %s", pd.Summary(), sb.Platform), pd.Detail())
+			}
+			diags.Append(pd)
+		}
+
+		results = append(results, TestPlatformResultResourceModel{
+			Platform: types.StringValue(sb.Platform),
+			Passed:   types.BoolValue(!pdiags.HasError()),
+			Artifact: test.Artifact,
+		})
+	}
+
+	resultsList, resultDiags := testPlatformResultsValue(results)
+	diags.Append(resultDiags...)
+	test.PlatformResults = resultsList
+
+	return diags
+}
+
 // doTestWithRetry wraps doTest with per-test retry. Each retry re-runs d.Run()
 // within the same driver — the test author asserts idempotency.
-func (t *TestsResource) doTestWithRetry(ctx context.Context, d drivers.Tester, test *TestResourceModel, ref name.Reference) diag.Diagnostics {
+func (t *TestsResource) doTestWithRetry(ctx context.Context, d drivers.Tester, test *TestResourceModel, sb sandbox) diag.Diagnostics {
 	cfg, cfgDiags := test.Retry.config()
 	if cfgDiags.HasError() {
 		return cfgDiags
 	}
 	if cfg.Attempts <= 1 {
-		return t.doTest(ctx, d, test, ref)
+		return t.doTest(ctx, d, test, sb)
 	}
 
 	var lastDiags diag.Diagnostics
 	result := retry.Do(ctx, cfg, func(ctx context.Context, attempt int) error {
-		lastDiags = t.doTest(ctx, d, test, ref)
+		lastDiags = t.doTest(ctx, d, test, sb)
 		if lastDiags.HasError() {
 			return fmt.Errorf("%s", lastDiags[len(lastDiags)-1].Detail())
 		}
 		return nil
 	})
 
+	if result.Retried {
+		o11y.Meters().Retries.Add(ctx, int64(result.Attempts-1), o11y.MetricAttributes(ctx,
+			attribute.String(o11y.AttrScope, o11y.ScopeTest),
+			attribute.String(o11y.AttrTest, test.Name.ValueString()),
+		))
+	}
+
 	if result.Retried && !lastDiags.HasError() {
 		lastDiags = append(lastDiags, diag.NewWarningDiagnostic(
 			fmt.Sprintf("test %q passed after retry (attempt %d/%d)", test.Name.ValueString(), result.Attempts, cfg.Attempts),
@@ -596,7 +874,7 @@ func (t *TestsResource) doTestWithRetry(ctx context.Context, d drivers.Tester, t
 	return lastDiags
 }
 
-func (t *TestsResource) doTest(ctx context.Context, d drivers.Tester, test *TestResourceModel, ref name.Reference) diag.Diagnostics {
+func (t *TestsResource) doTest(ctx context.Context, d drivers.Tester, test *TestResourceModel, sb sandbox) diag.Diagnostics {
 	// Get the test_id from context
 	testID, ok := ctx.Value(contextKeyResourceTestID).(string)
 	if !ok {
@@ -610,8 +888,11 @@ func (t *TestsResource) doTest(ctx context.Context, d drivers.Tester, test *Test
 
 	ctx = clog.WithValues(ctx,
 		o11y.AttrTest, testName,
-		"test_ref", ref.String(),
+		"test_ref", sb.Ref.String(),
 	)
+	if sb.Platform != "" {
+		ctx = clog.WithValues(ctx, "platform", sb.Platform)
+	}
 
 	diags := diag.Diagnostics{}
 
@@ -636,17 +917,35 @@ func (t *TestsResource) doTest(ctx context.Context, d drivers.Tester, test *Test
 	ctx, testSpan := otel.Tracer("imagetest").Start(ctx, "imagetest.test",
 		trace.WithAttributes(
 			attribute.String(o11y.AttrTest, testName),
-			attribute.String("test.image_ref", ref.String()),
+			attribute.String("test.image_ref", sb.Ref.String()),
 			attribute.String("test.timeout", timeout),
 		),
 	)
+	if sb.Platform != "" {
+		testSpan.SetAttributes(attribute.String("test.platform", sb.Platform))
+	}
 
 	artifact := map[string]attr.Value{
 		"uri":      types.StringNull(),
 		"checksum": types.StringNull(),
 	}
 
-	result, err := d.Run(ctx, ref)
+	// Only report the steps of the latest attempt
+	test.Steps = types.ListNull(types.ObjectType{AttrTypes: testStepAttTypes})
+	test.Annotations = types.MapNull(types.StringType)
+
+	result, err := d.Run(ctx, sb.Ref)
+
+	metricAttrs := []attribute.KeyValue{attribute.String(o11y.AttrTest, testName)}
+	if sb.Platform != "" {
+		metricAttrs = append(metricAttrs, attribute.String(o11y.AttrPlatform, sb.Platform))
+	}
+	o11y.Meters().Tests.Add(ctx, 1, o11y.MetricAttributes(ctx,
+		append(metricAttrs, attribute.String(o11y.AttrResult, metricResult(err == nil)))...,
+	))
+	if result != nil && result.Artifact != nil && result.Artifact.Size > 0 {
+		o11y.Meters().ArtifactSize.Record(ctx, result.Artifact.Size, o11y.MetricAttributes(ctx, metricAttrs...))
+	}
 	if result != nil && result.Artifact != nil {
 		artifact["uri"] = types.StringValue(result.Artifact.URI)
 		artifact["checksum"] = types.StringValue(result.Artifact.Checksum)
@@ -660,6 +959,46 @@ func (t *TestsResource) doTest(ctx context.Context, d drivers.Tester, test *Test
 		test.Artifact = artifactObj
 	}
 
+	var steps []*entrypoint.Step
+	if result != nil && len(result.Events) > 0 {
+		var annotations map[string]string
+		steps, annotations = entrypoint.Steps(result.Events)
+		recordStepSpans(ctx, otel.Tracer("imagetest"), steps, annotations)
+
+		stepsList, stepDiags := testStepsValue(steps)
+		diags.Append(stepDiags...)
+		test.Steps = stepsList
+
+		annotationsMap, annotationDiags := types.MapValueFrom(ctx, types.StringType, annotations)
+		diags.Append(annotationDiags...)
+		test.Annotations = annotationsMap
+	}
+
+	if result != nil && result.Profile != nil {
+		testSpan.SetAttributes(
+			attribute.Float64("test.resources.cpu.peak_cores", result.Profile.CPUPeakCores),
+			attribute.Float64("test.resources.cpu.avg_cores", result.Profile.CPUAvgCores),
+			attribute.Float64("test.resources.cpu.usage_seconds", result.Profile.CPUUsageSeconds),
+			attribute.Int64("test.resources.memory.peak_bytes", result.Profile.MemoryPeakBytes),
+			attribute.Int64("test.resources.pids.peak", result.Profile.PIDsPeak),
+		)
+	}
+
+	var denied []string
+	if result != nil && len(result.Egress) > 0 {
+		destinations := make([]string, 0, len(result.Egress))
+		for _, rec := range result.Egress {
+			destinations = append(destinations, rec.String())
+			if !rec.Allowed {
+				denied = append(denied, rec.String())
+			}
+		}
+		testSpan.SetAttributes(
+			attribute.StringSlice("test.egress.destinations", destinations),
+			attribute.StringSlice("test.egress.denied", denied),
+		)
+	}
+
 	if err != nil {
 		testSpan.RecordError(err)
 		testSpan.SetStatus(codes.Error, err.Error())
@@ -668,7 +1007,7 @@ func (t *TestsResource) doTest(ctx context.Context, d drivers.Tester, test *Test
 		if result != nil && result.Artifact != nil {
 			artifactURI = result.Artifact.URI
 		}
-		diags.Append(diag.NewErrorDiagnostic("failed to run test", truncateWithLogHint(err.Error(), testLog.Path, artifactURI)))
+		diags.Append(diag.NewErrorDiagnostic("failed to run test", truncateWithLogHint(err.Error(), testLog.Path, artifactURI)+formatSteps(steps)+formatDeniedEgress(denied)))
 		return diags
 	}
 
@@ -677,6 +1016,38 @@ func (t *TestsResource) doTest(ctx context.Context, d drivers.Tester, test *Test
 	return diags
 }
 
+// testCollectRules validates the test's collect patterns and converts them
+// for the entrypoint.
+func testCollectRules(test *TestResourceModel) ([]entrypoint.CollectRule, error) {
+	rules := make([]entrypoint.CollectRule, 0, len(test.Collect))
+	for _, c := range test.Collect {
+		rule := entrypoint.CollectRule{
+			Pattern:   c.Pattern.ValueString(),
+			OnFailure: c.OnFailure.ValueBool(),
+		}
+		if v := c.MaxSize.ValueString(); v != "" {
+			q, err := kresource.ParseQuantity(v)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse max_size for %q: %w", rule.Pattern, err)
+			}
+			rule.MaxBytes = q.Value()
+		}
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// metricResult is the result attribute of a test or driver phase.
+func metricResult(passed bool) string {
+	if passed {
+		return o11y.ResultPassed
+	}
+	return o11y.ResultFailed
+}
+
 const maxErrorMessageBytes = 256 * 1024 // 256KB
 
 func truncateWithLogHint(msg string, logPath string, artifactURI string) string {
@@ -802,6 +1173,42 @@ func (t *TestsResource) buildTestImages(ctx context.Context, data *TestsResource
 						envs[entrypoint.OnFailureEnvVar] = string(ofdata)
 					}
 
+					if test.RecordEgress.ValueBool() || test.EgressAllowlist != nil {
+						envs[entrypoint.EgressProxyEnvVar] = "true"
+					}
+
+					if test.EgressAllowlist != nil {
+						aldata, err := json.Marshal(test.EgressAllowlist)
+						if err != nil {
+							return nil, fmt.Errorf("failed to marshal egress_allowlist: %w", err)
+						}
+						envs[entrypoint.EgressAllowlistEnvVar] = string(aldata)
+					}
+
+					rules, err := testCollectRules(test)
+					if err != nil {
+						return nil, err
+					}
+					if len(rules) > 0 {
+						cdata, err := json.Marshal(rules)
+						if err != nil {
+							return nil, fmt.Errorf("failed to marshal collect: %w", err)
+						}
+						envs[entrypoint.CollectEnvVar] = string(cdata)
+					}
+
+					commands, err := testCommands(test)
+					if err != nil {
+						return nil, err
+					}
+					if len(commands) > 0 {
+						cdata, err := json.Marshal(commands)
+						if err != nil {
+							return nil, fmt.Errorf("failed to marshal commands: %w", err)
+						}
+						envs[entrypoint.CommandsEnvVar] = string(cdata)
+					}
+
 					if os.Getenv("IMAGETEST_SKIP_TEARDOWN") != "" {
 						envs[entrypoint.PauseModeEnvVar] = string(entrypoint.PauseAlways)
 					}
@@ -833,6 +1240,11 @@ func (t *TestsResource) buildTestImages(ctx context.Context, data *TestsResource
 
 					cfgf.Config.Entrypoint = entrypoint.DefaultEntrypoint
 					cfgf.Config.Cmd = []string{test.Cmd.ValueString()}
+					if len(commands) > 0 {
+						// The entrypoint runs the commands itself when it is
+						// the wrapped process
+						cfgf.Config.Cmd = []string{entrypoint.BinaryPath}
+					}
 
 					if cfgf.Config.WorkingDir == "" {
 						cfgf.Config.WorkingDir = entrypoint.DefaultWorkDir
@@ -9,10 +9,12 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
@@ -179,6 +181,42 @@ resource "imagetest_tests" "foo" {
 				Check: checkArtifact(t),
 			},
 		},
+		"k3sindocker-kubernetes-version": {
+			{
+				Config: `
+resource "imagetest_tests" "foo" {
+  name   = "k3sindocker-kubernetes-version"
+  driver = "k3s_in_docker"
+
+  drivers = {
+    k3s_in_docker = {
+      image              = "rancher/k3s:latest"
+      kubernetes_version = "1.31"
+      kubelet_args       = ["max-pods=50"]
+      feature_gates      = { "InPlacePodVerticalScaling" = true }
+    }
+  }
+
+  images = {
+    foo = "cgr.dev/chainguard/busybox:latest@sha256:c546e746013d75c1fc9bf01b7a645ce7caa1ec46c45cb618c6e28d7b57bccc85"
+  }
+
+  tests = [
+    {
+      name    = "sample"
+      image   = "cgr.dev/chainguard/kubectl:latest-dev"
+      content = [{ source = "${path.module}/testdata/TestAccTestsResource" }]
+      cmd     = "./k3s-in-docker-basic.sh"
+    }
+  ]
+
+  // Something before GHA timeouts
+  timeout = "5m"
+}
+					`,
+				Check: resource.TestMatchResourceAttr("imagetest_tests.foo", "drivers.k3s_in_docker.effective_kubernetes_version", regexp.MustCompile(`^v1\.31\.`)),
+			},
+		},
 		"k3sindocker-artifacts-on-failure": {
 			{
 				ExpectError: regexp.MustCompile(`.*can't open 'imalittleteapot'.*`),
@@ -296,6 +334,177 @@ resource "imagetest_tests" "foo" {
 				Check: checkArtifact(t),
 			},
 		},
+		"dockerindocker-collect": {
+			{
+				Config: fmt.Sprintf(`
+resource "imagetest_tests" "foo" {
+  name   = "%[1]s"
+  driver = "docker_in_docker"
+
+  images = {
+    foo = "cgr.dev/chainguard/busybox:latest@sha256:c546e746013d75c1fc9bf01b7a645ce7caa1ec46c45cb618c6e28d7b57bccc85"
+  }
+
+  tests = [
+    {
+      name    = "sample"
+      image   = "cgr.dev/chainguard/busybox:latest"
+      content = [{ source = "${path.module}/testdata/TestAccTestsResource" }]
+      cmd     = "./%[1]s"
+      collect = [
+        { pattern = "/var/log/app/*.log", max_size = "1Mi" },
+        { pattern = "/etc/*", on_failure = true },
+      ]
+    }
+  ]
+
+  // Something before GHA timeouts
+  timeout = "5m"
+}
+					`, "collect.sh"),
+				Check: checkArtifactFile(t, "collected/var/log/app/app.log", "collected\n"),
+			},
+		},
+		"dockerindocker-platforms": {
+			{
+				Config: fmt.Sprintf(`
+resource "imagetest_tests" "foo" {
+  name      = "%[1]s"
+  driver       = "docker_in_docker"
+  platforms    = ["linux/amd64", "linux/arm64"]
+  binfmt_image = "%[2]s"
+
+  images = {
+    foo = "cgr.dev/chainguard/busybox:latest@sha256:c546e746013d75c1fc9bf01b7a645ce7caa1ec46c45cb618c6e28d7b57bccc85"
+  }
+
+  tests = [
+    {
+      name    = "sample"
+      image   = "cgr.dev/chainguard/busybox:latest"
+      content = [{ source = "${path.module}/testdata/TestAccTestsResource" }]
+      cmd     = "./%[1]s"
+    }
+  ]
+
+  // Something before GHA timeouts
+  timeout = "10m"
+}
+					`, "platforms.sh", os.Getenv("IMAGETEST_BINFMT_IMAGE")),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.platform_results.#", "2"),
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.platform_results.0.platform", "linux/amd64"),
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.platform_results.0.passed", "true"),
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.platform_results.1.platform", "linux/arm64"),
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.platform_results.1.passed", "true"),
+				),
+			},
+		},
+		"dockerindocker-steps": {
+			{
+				Config: fmt.Sprintf(`
+resource "imagetest_tests" "foo" {
+  name   = "%[1]s"
+  driver = "docker_in_docker"
+
+  images = {
+    foo = "cgr.dev/chainguard/busybox:latest@sha256:c546e746013d75c1fc9bf01b7a645ce7caa1ec46c45cb618c6e28d7b57bccc85"
+  }
+
+  tests = [
+    {
+      name    = "sample"
+      image   = "cgr.dev/chainguard/busybox:latest"
+      content = [{ source = "${path.module}/testdata/TestAccTestsResource" }]
+      cmd     = "./%[1]s"
+    }
+  ]
+
+  // Something before GHA timeouts
+  timeout = "5m"
+}
+					`, "steps.sh"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.annotations.suite", "steps"),
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.steps.#", "2"),
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.steps.0.name", "setup"),
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.steps.0.status", "ok"),
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.steps.1.name", "verify"),
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.steps.1.status", "error"),
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.steps.1.message", "expected failure"),
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.steps.1.annotations.answer", "42"),
+				),
+			},
+		},
+		"dockerindocker-commands": {
+			{
+				Config: `
+resource "imagetest_tests" "foo" {
+  name   = "commands"
+  driver = "docker_in_docker"
+
+  images = {
+    foo = "cgr.dev/chainguard/busybox:latest@sha256:c546e746013d75c1fc9bf01b7a645ce7caa1ec46c45cb618c6e28d7b57bccc85"
+  }
+
+  tests = [
+    {
+      name  = "sample"
+      image = "cgr.dev/chainguard/busybox:latest"
+      commands = [
+        { name = "setup", cmd = "echo setting up" },
+        { name = "flaky", cmd = "exit 3", continue_on_error = true },
+        { name = "slow", cmd = "sleep 60", timeout = "2s", continue_on_error = true },
+        { name = "verify", cmd = "docker version" },
+      ]
+    }
+  ]
+
+  // Something before GHA timeouts
+  timeout = "5m"
+}
+					`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.steps.#", "4"),
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.steps.0.status", "ok"),
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.steps.0.exit_code", "0"),
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.steps.1.status", "error"),
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.steps.1.exit_code", "3"),
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.steps.2.status", "error"),
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.steps.2.exit_code", "124"),
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.steps.3.name", "verify"),
+					resource.TestCheckResourceAttr("imagetest_tests.foo", "tests.0.steps.3.status", "ok"),
+				),
+			},
+		},
+		"dockerindocker-egress-denied": {
+			{
+				Config: fmt.Sprintf(`
+resource "imagetest_tests" "foo" {
+  name   = "%[1]s"
+  driver = "docker_in_docker"
+
+  images = {
+    foo = "cgr.dev/chainguard/busybox:latest@sha256:c546e746013d75c1fc9bf01b7a645ce7caa1ec46c45cb618c6e28d7b57bccc85"
+  }
+
+  tests = [
+    {
+      name             = "sample"
+      image            = "cgr.dev/chainguard/busybox:latest"
+      content          = [{ source = "${path.module}/testdata/TestAccTestsResource" }]
+      cmd              = "./%[1]s"
+      egress_allowlist = ["cgr.dev:443"]
+    }
+  ]
+
+  // Something before GHA timeouts
+  timeout = "5m"
+}
+					`, "egress.sh"),
+				ExpectError: regexp.MustCompile(`example.com:80`),
+			},
+		},
 		// Per-test retry on a passing test: retry block is accepted, test still passes.
 		"dockerindocker-per-test-retry-passes": {
 			{
@@ -399,6 +608,48 @@ resource "imagetest_tests" "foo" {
     }
   ]
 
+  timeout = "5m"
+}
+        `,
+			},
+		},
+		"dockerindocker-services": {
+			{
+				Config: `
+resource "imagetest_tests" "foo" {
+  name   = "dind-services"
+  driver = "docker_in_docker"
+
+  drivers = {
+    docker_in_docker = {
+      services = [
+        {
+          image   = "cgr.dev/chainguard/busybox:latest"
+          alias   = "web"
+          command = ["sh", "-c", "mkdir -p /tmp/www && echo 'hello from web' > /tmp/www/index.html && httpd -f -p 8080 -h /tmp/www"]
+          healthcheck = {
+            test     = ["CMD", "wget", "-q", "-O", "/dev/null", "http://localhost:8080/index.html"]
+            interval = "1s"
+            retries  = 10
+          }
+        }
+      ]
+    }
+  }
+
+  images = {
+    foo = "cgr.dev/chainguard/busybox:latest@sha256:c546e746013d75c1fc9bf01b7a645ce7caa1ec46c45cb618c6e28d7b57bccc85"
+  }
+
+  tests = [
+    {
+      name    = "sample"
+      image   = "cgr.dev/chainguard/busybox:latest"
+      content = [{ source = "${path.module}/testdata/TestAccTestsResource" }]
+      cmd     = "./docker-in-docker-services.sh"
+    }
+  ]
+
   timeout = "5m"
 }
         `,
@@ -450,6 +701,12 @@ resource "imagetest_tests" "foo" {
 }
 
 func checkArtifact(t *testing.T) func(s *terraform.State) error {
+	return checkArtifactFile(t, "results/output.txt", "hello artifact content 123\n")
+}
+
+// checkArtifactFile checks the test's artifact contains the file name with
+// the expected content.
+func checkArtifactFile(t *testing.T, name, expectedContent string) func(s *terraform.State) error {
 	return func(s *terraform.State) error {
 		rname := "imagetest_tests.foo"
 		rs, ok := s.RootModule().Resources[rname]
@@ -500,7 +757,6 @@ func checkArtifact(t *testing.T) func(s *terraform.State) error {
 		tr := tar.NewReader(gz)
 
 		match := false
-		expectedContent := "hello artifact content 123\n"
 		var content []byte
 
 		for {
@@ -517,7 +773,7 @@ func checkArtifact(t *testing.T) func(s *terraform.State) error {
 				continue
 			}
 
-			if hdr.Name != "results/output.txt" {
+			if hdr.Name != name {
 				continue
 			}
 
@@ -532,11 +788,11 @@ func checkArtifact(t *testing.T) func(s *terraform.State) error {
 		}
 
 		if !match {
-			return fmt.Errorf("expected artifact to contain results/output.txt")
+			return fmt.Errorf("expected artifact to contain %s", name)
 		}
 
 		if diff := cmp.Diff(expectedContent, string(content)); diff != "" {
-			return fmt.Errorf("unexpected output.txt content (-want +got):\n%s", diff)
+			return fmt.Errorf("unexpected %s content (-want +got):\n%s", name, diff)
 		}
 
 		return nil
@@ -725,3 +981,74 @@ func TestIsLocalRegistry(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchPlatforms(t *testing.T) {
+	amd64 := v1.Hash{Algorithm: "sha256", Hex: strings.Repeat("a", 64)}
+	arm64 := v1.Hash{Algorithm: "sha256", Hex: strings.Repeat("b", 64)}
+	armv7 := v1.Hash{Algorithm: "sha256", Hex: strings.Repeat("c", 64)}
+
+	mfst := &v1.IndexManifest{
+		Manifests: []v1.Descriptor{
+			{Digest: amd64, Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}},
+			{Digest: arm64, Platform: &v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}},
+			{Digest: armv7, Platform: &v1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}},
+			// attestations don't have a runnable platform
+			{Digest: v1.Hash{Algorithm: "sha256", Hex: strings.Repeat("d", 64)}, Platform: &v1.Platform{OS: "unknown", Architecture: "unknown"}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		platforms []string
+		want      []v1.Hash
+		wantErr   string
+	}{
+		{
+			name:      "in the order requested",
+			platforms: []string{"linux/arm64", "linux/amd64"},
+			want:      []v1.Hash{arm64, amd64},
+		},
+		{
+			name:      "with a variant",
+			platforms: []string{"linux/arm/v7"},
+			want:      []v1.Hash{armv7},
+		},
+		{
+			name:      "missing platform",
+			platforms: []string{"linux/amd64", "linux/s390x"},
+			wantErr:   "no image for platform linux/s390x, the image index has: linux/amd64, linux/arm64/v8, linux/arm/v7, unknown/unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			platforms, err := parsePlatforms(tt.platforms)
+			if err != nil {
+				t.Fatalf("parsePlatforms() error = %v", err)
+			}
+
+			got, err := matchPlatforms(mfst, platforms)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("matchPlatforms() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("matchPlatforms() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("matchPlatforms() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParsePlatforms(t *testing.T) {
+	if _, err := parsePlatforms([]string{"linux/amd64", "linux/amd64"}); err == nil {
+		t.Error("parsePlatforms() with a duplicate platform succeeded, want an error")
+	}
+	if _, err := parsePlatforms([]string{"linux/arm64", "linux/arm/v7"}); err != nil {
+		t.Errorf("parsePlatforms() error = %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/entrypoint"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type TestStepResourceModel struct {
+	Name        types.String `tfsdk:"name"`
+	Status      types.String `tfsdk:"status"`
+	Message     types.String `tfsdk:"message"`
+	Duration    types.String `tfsdk:"duration"`
+	ExitCode    types.Int64  `tfsdk:"exit_code"`
+	Annotations types.Map    `tfsdk:"annotations"`
+}
+
+var testStepAttTypes = map[string]attr.Type{
+	"name":        types.StringType,
+	"status":      types.StringType,
+	"message":     types.StringType,
+	"duration":    types.StringType,
+	"exit_code":   types.Int64Type,
+	"annotations": types.MapType{ElemType: types.StringType},
+}
+
+// testStepsValue converts the steps reported by a test into the computed
+// steps attribute.
+func testStepsValue(steps []*entrypoint.Step) (types.List, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	elems := make([]attr.Value, 0, len(steps))
+	for _, s := range steps {
+		annotations, d := types.MapValueFrom(context.Background(), types.StringType, s.Annotations)
+		diags.Append(d...)
+
+		exitCode := types.Int64Null()
+		if s.ExitCode != nil {
+			exitCode = types.Int64Value(int64(*s.ExitCode))
+		}
+
+		obj, d := types.ObjectValue(testStepAttTypes, map[string]attr.Value{
+			"name":        types.StringValue(s.Name),
+			"status":      types.StringValue(string(s.Status)),
+			"message":     types.StringValue(s.Message),
+			"duration":    types.StringValue(s.End.Sub(s.Start).String()),
+			"exit_code":   exitCode,
+			"annotations": annotations,
+		})
+		diags.Append(d...)
+		elems = append(elems, obj)
+	}
+
+	list, d := types.ListValue(types.ObjectType{AttrTypes: testStepAttTypes}, elems)
+	diags.Append(d...)
+	return list, diags
+}
+
+// recordStepSpans adds each step as a child span of the test span in ctx,
+// timed by the events the test sent, and the test level annotations as
+// attributes of the test span itself.
+func recordStepSpans(ctx context.Context, tracer trace.Tracer, steps []*entrypoint.Step, annotations map[string]string) {
+	testSpan := trace.SpanFromContext(ctx)
+	for _, k := range slices.Sorted(maps.Keys(annotations)) {
+		testSpan.SetAttributes(attribute.String("test.annotation."+k, annotations[k]))
+	}
+
+	for _, s := range steps {
+		_, span := tracer.Start(ctx, "imagetest.step",
+			trace.WithTimestamp(s.Start),
+			trace.WithAttributes(
+				attribute.String("step.name", s.Name),
+				attribute.String("step.status", string(s.Status)),
+			),
+		)
+		if s.ExitCode != nil {
+			span.SetAttributes(attribute.Int("step.exit_code", *s.ExitCode))
+		}
+
+		for _, k := range slices.Sorted(maps.Keys(s.Annotations)) {
+			span.SetAttributes(attribute.String("step.annotation."+k, s.Annotations[k]))
+		}
+
+		switch s.Status {
+		case entrypoint.StepStatusOK:
+			span.SetStatus(codes.Ok, "")
+		case entrypoint.StepStatusError, entrypoint.StepStatusIncomplete:
+			span.SetStatus(codes.Error, s.Message)
+		}
+
+		span.End(trace.WithTimestamp(s.End))
+	}
+}
+
+// formatSteps summarizes the steps for inclusion in a failed test's
+// diagnostic.
+func formatSteps(steps []*entrypoint.Step) string {
+	if len(steps) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Steps:\n")
+	for _, s := range steps {
+		fmt.Fprintf(&b, "  [%s] %s (%s)", s.Status, s.Name, s.End.Sub(s.Start).Round(time.Millisecond))
+		if s.ExitCode != nil {
+			fmt.Fprintf(&b, " exit code %d", *s.ExitCode)
+		}
+		if s.Message != "" {
+			fmt.Fprintf(&b, ": %s", s.Message)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// testCommands validates the test's commands and converts them for the
+// entrypoint.
+func testCommands(test *TestResourceModel) ([]entrypoint.Command, error) {
+	if len(test.Commands) == 0 {
+		return nil, nil
+	}
+	if test.Cmd.ValueString() != "" {
+		return nil, errors.New("only one of cmd and commands may be set")
+	}
+
+	commands := make([]entrypoint.Command, 0, len(test.Commands))
+	for _, c := range test.Commands {
+		name := c.Name.ValueString()
+		if name == "" {
+			return nil, errors.New("command name must not be empty")
+		}
+		if slices.ContainsFunc(commands, func(e entrypoint.Command) bool { return e.Name == name }) {
+			return nil, fmt.Errorf("command name %q is used more than once", name)
+		}
+
+		cmd := entrypoint.Command{
+			Name:            name,
+			Cmd:             c.Cmd.ValueString(),
+			ContinueOnError: c.ContinueOnError.ValueBool(),
+		}
+		if t := c.Timeout.ValueString(); t != "" {
+			d, err := time.ParseDuration(t)
+			if err != nil {
+				return nil, fmt.Errorf("command %q: failed to parse timeout: %w", name, err)
+			}
+			cmd.Timeout = d
+		}
+		commands = append(commands, cmd)
+	}
+	return commands, nil
+}
+
+// formatDeniedEgress lists the destinations refused by the egress allowlist
+// for inclusion in a failed test's diagnostic.
+func formatDeniedEgress(denied []string) string {
+	if len(denied) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Egress denied:\n")
+	for _, d := range denied {
+		fmt.Fprintf(&b, "  %s\n", d)
+	}
+	return b.String()
+}
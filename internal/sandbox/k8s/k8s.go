@@ -12,6 +12,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/httpstream"
 	"k8s.io/apimachinery/pkg/watch"
@@ -26,6 +27,38 @@ var _ sandbox.Sandbox = &k8s{}
 
 type Request struct {
 	sandbox.Request
+
+	// ServiceAccount is an existing service account to run the pod as. When
+	// empty, a service account bound to cluster-admin is created.
+	ServiceAccount string
+	NodeSelector   map[string]string
+	Tolerations    []corev1.Toleration
+	Volumes        []Volume
+	// EnvFromSecrets are the names of secrets whose keys are all exposed as
+	// environment variables.
+	EnvFromSecrets []string
+}
+
+// Volume mounts a ConfigMap or a Secret of the pod's namespace into the
+// sandbox. Exactly one of ConfigMap or Secret must be set.
+type Volume struct {
+	Name      string `json:"name"`
+	MountPath string `json:"mount_path"`
+	ConfigMap string `json:"config_map"`
+	Secret    string `json:"secret"`
+}
+
+func (v Volume) validate() error {
+	if v.Name == "" || v.MountPath == "" {
+		return fmt.Errorf("volumes require a name and a mount_path")
+	}
+	if (v.ConfigMap == "") == (v.Secret == "") {
+		return fmt.Errorf("volume %q must set exactly one of config_map or secret", v.Name)
+	}
+	if v.Name == "kube-api-access" {
+		return fmt.Errorf("volume name %q is reserved", v.Name)
+	}
+	return nil
 }
 
 // k8s is a sandbox that runs steps in a pod in a k8s cluster.
@@ -199,63 +232,77 @@ func (k *k8s) setupPod(ctx context.Context) (*corev1.Pod, error) {
 		k.request.Name = dryns.Name
 	}
 
-	// Create the laundry list of namespace scoped RBAC related resources
-	sa, err := k.cli.CoreV1().ServiceAccounts(ns.Name).Create(ctx, &corev1.ServiceAccount{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      k.request.Name,
-			Namespace: ns.Name,
-		},
-	}, metav1.CreateOptions{})
+	saName := k.request.ServiceAccount
+	if saName == "" {
+		sa, err := k.createServiceAccount(ctx, ns.Name)
+		if err != nil {
+			return nil, err
+		}
+		saName = sa
+	}
+
+	preq := k.podRequest(ns.Name, saName)
+
+	// Now create the stupidly privileged pod that we'll use to run the steps
+	pod, err := k.cli.CoreV1().Pods(ns.Name).Create(ctx, preq, metav1.CreateOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("creating service account: %w", err)
+		return nil, fmt.Errorf("creating pod: %w", err)
 	}
 
 	if err := k.stack.Add(func(ctx context.Context) error {
-		return k.cli.CoreV1().ServiceAccounts(ns.Name).Delete(ctx, sa.Name, metav1.DeleteOptions{
+		return k.cli.CoreV1().Pods(ns.Name).Delete(ctx, pod.Name, metav1.DeleteOptions{
 			GracePeriodSeconds: &k.gracePeriod,
 		})
 	}); err != nil {
-		return nil, fmt.Errorf("adding service account teardown to stack: %w", err)
+		return nil, fmt.Errorf("adding pod teardown to stack: %w", err)
 	}
 
-	// Finally, create the role binding
-	rb, err := k.cli.RbacV1().ClusterRoleBindings().Create(ctx, &rbacv1.ClusterRoleBinding{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      k.request.Name,
-			Namespace: ns.Name,
-		},
-		Subjects: []rbacv1.Subject{
-			{
-				Kind:      rbacv1.ServiceAccountKind,
-				Name:      sa.Name,
-				Namespace: sa.Namespace,
-			},
-		},
-		RoleRef: rbacv1.RoleRef{
-			APIGroup: rbacv1.GroupName,
-			Kind:     "ClusterRole",
-			Name:     "cluster-admin",
-		},
-	}, metav1.CreateOptions{})
+	// Block until the pod is running
+	watcher, err := k.cli.CoreV1().Pods(ns.Name).Watch(ctx, metav1.ListOptions{
+		Watch:         true,
+		FieldSelector: "metadata.name=" + pod.Name,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("creating role binding: %w", err)
+		return nil, fmt.Errorf("creating pod: %w", err)
 	}
+	defer watcher.Stop()
 
-	if err := k.stack.Add(func(ctx context.Context) error {
-		return k.cli.RbacV1().ClusterRoleBindings().Delete(ctx, rb.Name, metav1.DeleteOptions{
-			GracePeriodSeconds: &k.gracePeriod,
-		})
-	}); err != nil {
-		return nil, fmt.Errorf("adding role binding teardown to stack: %w", err)
+	ch := watcher.ResultChan()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case event, ok := <-ch:
+			if !ok {
+				return nil, fmt.Errorf("channel closed")
+			}
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				pod, ok := event.Object.(*corev1.Pod)
+				if !ok {
+					return nil, fmt.Errorf("failed to cast event object to pod")
+				}
+				if pod.Status.Phase == corev1.PodRunning {
+					return pod, nil
+				}
+			case watch.Deleted:
+				return nil, fmt.Errorf("pod was deleted")
+			case watch.Error:
+				return nil, fmt.Errorf("watch error: %v", event.Object)
+			}
+		}
 	}
+}
 
+// podRequest builds the sandbox pod running as the service account.
+func (k *k8s) podRequest(namespace, serviceAccount string) *corev1.Pod {
 	preq := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      k.request.Name,
-			Namespace: ns.Name,
+			Namespace: namespace,
 		},
 		Spec: corev1.PodSpec{
-			ServiceAccountName: sa.Name,
+			ServiceAccountName: serviceAccount,
 			SecurityContext: &corev1.PodSecurityContext{
 				RunAsUser:  &k.request.User,
 				RunAsGroup: &k.request.Group,
@@ -348,64 +395,108 @@ func (k *k8s) setupPod(ctx context.Context) (*corev1.Pod, error) {
 	}
 
 	if k.request.Resources.Limits != nil {
-		// TODO:
-		preq.Spec.Containers[0].Resources.Limits = corev1.ResourceList{}
+		preq.Spec.Containers[0].Resources.Limits = resourceList(k.request.Resources.Limits)
 	}
 
 	if k.request.Resources.Requests != nil {
-		// TODO:
-		preq.Spec.Containers[0].Resources.Requests = corev1.ResourceList{}
+		preq.Spec.Containers[0].Resources.Requests = resourceList(k.request.Resources.Requests)
 	}
 
-	maps.Copy(preq.Labels, k.request.Labels)
+	if len(k.request.Labels) > 0 {
+		preq.Labels = maps.Clone(k.request.Labels)
+	}
 
-	// Now create the stupidly privileged pod that we'll use to run the steps
-	pod, err := k.cli.CoreV1().Pods(ns.Name).Create(ctx, preq, metav1.CreateOptions{})
+	preq.Spec.NodeSelector = k.request.NodeSelector
+	preq.Spec.Tolerations = k.request.Tolerations
+
+	for _, s := range k.request.EnvFromSecrets {
+		preq.Spec.Containers[0].EnvFrom = append(preq.Spec.Containers[0].EnvFrom, corev1.EnvFromSource{
+			SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: s},
+			},
+		})
+	}
+
+	for _, v := range k.request.Volumes {
+		vol := corev1.Volume{Name: v.Name}
+		if v.ConfigMap != "" {
+			vol.ConfigMap = &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: v.ConfigMap},
+			}
+		} else {
+			vol.Secret = &corev1.SecretVolumeSource{SecretName: v.Secret}
+		}
+		preq.Spec.Volumes = append(preq.Spec.Volumes, vol)
+		preq.Spec.Containers[0].VolumeMounts = append(preq.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      v.Name,
+			MountPath: v.MountPath,
+			ReadOnly:  true,
+		})
+	}
+
+	return preq
+}
+
+// createServiceAccount creates a service account bound to cluster-admin,
+// returning its name.
+func (k *k8s) createServiceAccount(ctx context.Context, namespace string) (string, error) {
+	// Create the laundry list of namespace scoped RBAC related resources
+	sa, err := k.cli.CoreV1().ServiceAccounts(namespace).Create(ctx, &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      k.request.Name,
+			Namespace: namespace,
+		},
+	}, metav1.CreateOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("creating pod: %w", err)
+		return "", fmt.Errorf("creating service account: %w", err)
 	}
 
 	if err := k.stack.Add(func(ctx context.Context) error {
-		return k.cli.CoreV1().Pods(ns.Name).Delete(ctx, pod.Name, metav1.DeleteOptions{
+		return k.cli.CoreV1().ServiceAccounts(namespace).Delete(ctx, sa.Name, metav1.DeleteOptions{
 			GracePeriodSeconds: &k.gracePeriod,
 		})
 	}); err != nil {
-		return nil, fmt.Errorf("adding pod teardown to stack: %w", err)
+		return "", fmt.Errorf("adding service account teardown to stack: %w", err)
 	}
 
-	// Block until the pod is running
-	watcher, err := k.cli.CoreV1().Pods(ns.Name).Watch(ctx, metav1.ListOptions{
-		Watch:         true,
-		FieldSelector: "metadata.name=" + pod.Name,
-	})
+	// Finally, create the role binding
+	rb, err := k.cli.RbacV1().ClusterRoleBindings().Create(ctx, &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      k.request.Name,
+			Namespace: namespace,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      sa.Name,
+				Namespace: sa.Namespace,
+			},
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     "cluster-admin",
+		},
+	}, metav1.CreateOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("creating pod: %w", err)
+		return "", fmt.Errorf("creating role binding: %w", err)
 	}
-	defer watcher.Stop()
 
-	ch := watcher.ResultChan()
-	for {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case event, ok := <-ch:
-			if !ok {
-				return nil, fmt.Errorf("channel closed")
-			}
-			switch event.Type {
-			case watch.Added, watch.Modified:
-				pod, ok := event.Object.(*corev1.Pod)
-				if !ok {
-					return nil, fmt.Errorf("failed to cast event object to pod")
-				}
-				if pod.Status.Phase == corev1.PodRunning {
-					return pod, nil
-				}
-			case watch.Deleted:
-				return nil, fmt.Errorf("pod was deleted")
-			case watch.Error:
-				return nil, fmt.Errorf("watch error: %v", event.Object)
-			}
-		}
+	if err := k.stack.Add(func(ctx context.Context) error {
+		return k.cli.RbacV1().ClusterRoleBindings().Delete(ctx, rb.Name, metav1.DeleteOptions{
+			GracePeriodSeconds: &k.gracePeriod,
+		})
+	}); err != nil {
+		return "", fmt.Errorf("adding role binding teardown to stack: %w", err)
+	}
+
+	return sa.Name, nil
+}
+
+func resourceList(resources map[string]resource.Quantity) corev1.ResourceList {
+	rl := make(corev1.ResourceList, len(resources))
+	for name, q := range resources {
+		rl[corev1.ResourceName(name)] = q
 	}
+	return rl
 }
@@ -0,0 +1,107 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/sandbox"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/rest"
+)
+
+func TestPodRequest(t *testing.T) {
+	toleration := corev1.Toleration{
+		Key:      "gpu",
+		Operator: corev1.TolerationOpExists,
+		Effect:   corev1.TaintEffectNoSchedule,
+	}
+
+	k, err := NewFromConfig(&rest.Config{Host: "https://127.0.0.1:6443"},
+		WithRawImageRef("cgr.dev/chainguard/kubectl:latest-dev"),
+		WithServiceAccount("tester"),
+		WithResources(sandbox.ResourceRequest{
+			Requests: map[string]resource.Quantity{"cpu": resource.MustParse("500m")},
+			Limits:   map[string]resource.Quantity{"memory": resource.MustParse("1Gi")},
+		}),
+		WithNodeSelector(map[string]string{"kubernetes.io/arch": "arm64"}),
+		WithTolerations(toleration),
+		WithVolumes(
+			Volume{Name: "config", MountPath: "/config", ConfigMap: "settings"},
+			Volume{Name: "creds", MountPath: "/creds", Secret: "credentials"},
+		),
+		WithEnvFromSecrets("tokens"),
+		WithLabels(map[string]string{"team": "images"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.request.Name = "imagetest-abc"
+
+	pod := k.podRequest("imagetest", "tester")
+
+	if got := pod.Spec.ServiceAccountName; got != "tester" {
+		t.Errorf("service account = %q, want tester", got)
+	}
+	if diff := cmp.Diff(map[string]string{"team": "images"}, pod.Labels); diff != "" {
+		t.Errorf("labels (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]string{"kubernetes.io/arch": "arm64"}, pod.Spec.NodeSelector); diff != "" {
+		t.Errorf("node selector (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]corev1.Toleration{toleration}, pod.Spec.Tolerations); diff != "" {
+		t.Errorf("tolerations (-want, +got):\n%s", diff)
+	}
+
+	c := pod.Spec.Containers[0]
+	if q := c.Resources.Requests[corev1.ResourceCPU]; q.String() != "500m" {
+		t.Errorf("cpu request = %s, want 500m", q.String())
+	}
+	if q := c.Resources.Limits[corev1.ResourceMemory]; q.String() != "1Gi" {
+		t.Errorf("memory limit = %s, want 1Gi", q.String())
+	}
+	if len(c.EnvFrom) != 1 || c.EnvFrom[0].SecretRef == nil || c.EnvFrom[0].SecretRef.Name != "tokens" {
+		t.Errorf("env from = %+v, want the tokens secret", c.EnvFrom)
+	}
+
+	mounts := map[string]string{}
+	for _, m := range c.VolumeMounts {
+		mounts[m.Name] = m.MountPath
+	}
+	if mounts["config"] != "/config" || mounts["creds"] != "/creds" {
+		t.Errorf("volume mounts = %v, want config and creds mounted", mounts)
+	}
+
+	volumes := map[string]corev1.VolumeSource{}
+	for _, v := range pod.Spec.Volumes {
+		volumes[v.Name] = v.VolumeSource
+	}
+	if v := volumes["config"]; v.ConfigMap == nil || v.ConfigMap.Name != "settings" {
+		t.Errorf("config volume = %+v, want the settings ConfigMap", v)
+	}
+	if v := volumes["creds"]; v.Secret == nil || v.Secret.SecretName != "credentials" {
+		t.Errorf("creds volume = %+v, want the credentials Secret", v)
+	}
+}
+
+func TestWithVolumes(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		volumes []Volume
+	}{
+		{"missing mount path", []Volume{{Name: "config", ConfigMap: "settings"}}},
+		{"no source", []Volume{{Name: "config", MountPath: "/config"}}},
+		{"both sources", []Volume{{Name: "config", MountPath: "/config", ConfigMap: "settings", Secret: "creds"}}},
+		{"reserved name", []Volume{{Name: "kube-api-access", MountPath: "/config", ConfigMap: "settings"}}},
+		{"duplicate name", []Volume{
+			{Name: "config", MountPath: "/a", ConfigMap: "a"},
+			{Name: "config", MountPath: "/b", ConfigMap: "b"},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewFromConfig(&rest.Config{Host: "https://127.0.0.1:6443"}, WithVolumes(tc.volumes...)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
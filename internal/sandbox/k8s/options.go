@@ -1,6 +1,13 @@
 package k8s
 
-import "github.com/google/go-containerregistry/pkg/name"
+import (
+	"fmt"
+	"maps"
+
+	"github.com/chainguard-dev/terraform-provider-imagetest/internal/sandbox"
+	"github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+)
 
 type Option func(*k8s) error
 
@@ -31,3 +38,80 @@ func WithGracePeriod(gracePeriod int64) Option {
 		return nil
 	}
 }
+
+// WithServiceAccount runs the pod as an existing service account instead of
+// creating one bound to cluster-admin.
+func WithServiceAccount(name string) Option {
+	return func(k *k8s) error {
+		k.request.ServiceAccount = name
+		return nil
+	}
+}
+
+func WithResources(req sandbox.ResourceRequest) Option {
+	return func(k *k8s) error {
+		k.request.Resources = req
+		return nil
+	}
+}
+
+func WithNodeSelector(selector map[string]string) Option {
+	return func(k *k8s) error {
+		if len(selector) == 0 {
+			return nil
+		}
+		if k.request.NodeSelector == nil {
+			k.request.NodeSelector = make(map[string]string)
+		}
+		maps.Copy(k.request.NodeSelector, selector)
+		return nil
+	}
+}
+
+func WithTolerations(tolerations ...corev1.Toleration) Option {
+	return func(k *k8s) error {
+		k.request.Tolerations = append(k.request.Tolerations, tolerations...)
+		return nil
+	}
+}
+
+// WithVolumes mounts ConfigMaps or Secrets into the sandbox.
+func WithVolumes(volumes ...Volume) Option {
+	return func(k *k8s) error {
+		for _, v := range volumes {
+			if err := v.validate(); err != nil {
+				return err
+			}
+			for _, existing := range k.request.Volumes {
+				if existing.Name == v.Name {
+					return fmt.Errorf("volume %q is listed more than once", v.Name)
+				}
+			}
+			k.request.Volumes = append(k.request.Volumes, v)
+		}
+		return nil
+	}
+}
+
+// WithEnvFromSecrets exposes every key of the secrets as an environment
+// variable of the sandbox.
+func WithEnvFromSecrets(secrets ...string) Option {
+	return func(k *k8s) error {
+		k.request.EnvFromSecrets = append(k.request.EnvFromSecrets, secrets...)
+		return nil
+	}
+}
+
+func WithEnv(env map[string]string) Option {
+	return func(k *k8s) error {
+		maps.Copy(k.request.Env, env)
+		return nil
+	}
+}
+
+func WithLabels(labels map[string]string) Option {
+	return func(k *k8s) error {
+		maps.Copy(k.request.Labels, labels)
+		return nil
+	}
+}
@@ -139,6 +139,12 @@ func Exec(client *ssh.Client, cmd string, stdout, stderr io.Writer) error {
 
 // ExecIn executes all provided commands within the provided 'shell'.
 func ExecIn(client *ssh.Client, shell Shell, stdout, stderr io.Writer, cmds ...string) error {
+	return ExecInContext(context.Background(), client, shell, stdout, stderr, cmds...)
+}
+
+// ExecInContext is like ExecIn, but kills the commands and closes the session
+// when 'ctx' is done.
+func ExecInContext(ctx context.Context, client *ssh.Client, shell Shell, stdout, stderr io.Writer, cmds ...string) error {
 	cmd := "/usr/bin/env " + shell
 	// Begin a new SSH session.
 	session, err := client.NewSession()
@@ -160,6 +166,13 @@ func ExecIn(client *ssh.Client, shell Shell, stdout, stderr io.Writer, cmds ...s
 	if err = session.Start(cmd); err != nil {
 		return fmt.Errorf("%w: %w", ErrCMDExec, err)
 	}
+	// Not every server honors signals, closing the session is what ends the
+	// wait below.
+	stop := context.AfterFunc(ctx, func() {
+		_ = session.Signal(ssh.SIGKILL)
+		_ = session.Close()
+	})
+	defer stop()
 	// Pass all provided commands in via stdin.
 	for _, cmd := range cmds {
 		// "Execute" the command.
@@ -183,6 +196,9 @@ func ExecIn(client *ssh.Client, shell Shell, stdout, stderr io.Writer, cmds ...s
 	}
 	// Wait for the command to send an 'exit-status' request.
 	if err = session.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%w: %w", ErrInWait, ctx.Err())
+		}
 		return fmt.Errorf("%w: %w", ErrInWait, err)
 	}
 	return nil